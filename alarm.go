@@ -0,0 +1,61 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+)
+
+var alarmPollInterval = 10 * time.Second
+
+// watchAlarm polls the CloudWatch alarm in the window, and fails when the alarm goes into ALARM state.
+func (d *App) watchAlarm(ctx context.Context, name string, window time.Duration) error {
+	d.Log("Watching CloudWatch alarm %s for %s", name, window)
+	client := cloudwatch.NewFromConfig(d.config.awsv2Config)
+	ctx, cancel := context.WithTimeout(ctx, window)
+	defer cancel()
+	ticker := time.NewTicker(alarmPollInterval)
+	defer ticker.Stop()
+
+	var lastState cwTypes.StateValue
+	for {
+		state, reason, err := describeAlarmState(ctx, client, name)
+		if err != nil && ctx.Err() == nil {
+			return err
+		}
+		if state != "" && state != lastState {
+			d.Log("Alarm %s is %s", name, state)
+			lastState = state
+		}
+		if state == cwTypes.StateValueAlarm {
+			return fmt.Errorf("alarm %s went into ALARM state after the run: %s", name, reason)
+		}
+		select {
+		case <-ctx.Done():
+			d.Log("Alarm %s did not fire in %s. final state: %s", name, window, lastState)
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func describeAlarmState(ctx context.Context, client *cloudwatch.Client, name string) (cwTypes.StateValue, string, error) {
+	out, err := client.DescribeAlarms(ctx, &cloudwatch.DescribeAlarmsInput{
+		AlarmNames: []string{name},
+		AlarmTypes: []cwTypes.AlarmType{cwTypes.AlarmTypeMetricAlarm, cwTypes.AlarmTypeCompositeAlarm},
+	})
+	if err != nil {
+		return "", "", fmt.Errorf("failed to describe alarm %s: %w", name, err)
+	}
+	for _, a := range out.MetricAlarms {
+		return a.StateValue, aws.ToString(a.StateReason), nil
+	}
+	for _, a := range out.CompositeAlarms {
+		return a.StateValue, aws.ToString(a.StateReason), nil
+	}
+	return "", "", ErrNotFound(fmt.Sprintf("alarm %s is not found", name))
+}
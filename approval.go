@@ -0,0 +1,116 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const maxApprovalMessageLength = 1024
+
+// approvalRequest is the payload sent to the approval gate.
+type approvalRequest struct {
+	Cluster        string          `json:"cluster"`
+	Service        string          `json:"service,omitempty"`
+	TaskDefinition string          `json:"task_definition"`
+	Count          int32           `json:"count"`
+	Overrides      json.RawMessage `json:"overrides"`
+}
+
+func isHTTPApprovalGate(gate string) bool {
+	return strings.HasPrefix(gate, "http://") || strings.HasPrefix(gate, "https://")
+}
+
+// waitForApproval asks the approval gate whether the run may proceed.
+// The gate is a URL which responds 2xx to approve, or a command which exits with 0 to approve.
+// The payload is POSTed to the URL or passed to the command via stdin.
+func (d *App) waitForApproval(ctx context.Context, gate string, timeout time.Duration, tdArn string, ov *types.TaskOverride, count int32) error {
+	ovJSON, err := MarshalJSONForAPI(ov)
+	if err != nil {
+		return fmt.Errorf("failed to marshal overrides: %w", err)
+	}
+	payload, err := json.Marshal(approvalRequest{
+		Cluster:        d.Cluster,
+		Service:        d.Service,
+		TaskDefinition: tdArn,
+		Count:          count,
+		Overrides:      ovJSON,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal the approval request: %w", err)
+	}
+	d.Log("Waiting for approval by %s (timeout %s)", gate, timeout)
+	d.Log("[DEBUG] approval request: %s", string(payload))
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if isHTTPApprovalGate(gate) {
+		err = requestApproval(ctx, gate, payload)
+	} else {
+		err = commandApproval(ctx, gate, payload)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("approval by %s is timed out after %s. the run is aborted", gate, timeout)
+	}
+	if err != nil {
+		return err
+	}
+	d.Log("The run is approved by %s", gate)
+	return nil
+}
+
+func requestApproval(ctx context.Context, u string, payload []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create the approval request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ecspresso/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to request approval to %s: %w", u, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxApprovalMessageLength))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &ErrApprovalDenied{
+			Gate:    u,
+			Message: fmt.Sprintf("%s %s", resp.Status, strings.TrimSpace(string(body))),
+		}
+	}
+	return nil
+}
+
+func commandApproval(ctx context.Context, command string, payload []byte) error {
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	err := cmd.Run()
+	var ee *exec.ExitError
+	if errors.As(err, &ee) {
+		msg := strings.TrimSpace(stdout.String())
+		if len(msg) > maxApprovalMessageLength {
+			msg = msg[:maxApprovalMessageLength]
+		}
+		if msg == "" {
+			msg = fmt.Sprintf("exit code %d", ee.ExitCode())
+		}
+		return &ErrApprovalDenied{Gate: command, Message: msg}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to run the approval command %s: %w", command, err)
+	}
+	return nil
+}
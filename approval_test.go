@@ -0,0 +1,54 @@
+package ecspresso_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestRequestApproval(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		if strings.Contains(string(b), `"deny"`) {
+			w.WriteHeader(http.StatusForbidden)
+			io.WriteString(w, "change freeze")
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+	ctx := context.Background()
+
+	if err := ecspresso.RequestApproval(ctx, ts.URL, []byte(`{"cluster":"allow"}`)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err := ecspresso.RequestApproval(ctx, ts.URL, []byte(`{"cluster":"deny"}`))
+	var denied *ecspresso.ErrApprovalDenied
+	if !errors.As(err, &denied) {
+		t.Fatalf("expected ErrApprovalDenied, got %v", err)
+	}
+	if !strings.Contains(denied.Message, "change freeze") {
+		t.Errorf("unexpected message %s", denied.Message)
+	}
+}
+
+func TestCommandApproval(t *testing.T) {
+	ctx := context.Background()
+	if err := ecspresso.CommandApproval(ctx, `grep -q '"count":1'`, []byte(`{"count":1}`)); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err := ecspresso.CommandApproval(ctx, `echo not approved; exit 1`, []byte(`{}`))
+	var denied *ecspresso.ErrApprovalDenied
+	if !errors.As(err, &denied) {
+		t.Fatalf("expected ErrApprovalDenied, got %v", err)
+	}
+	if denied.Message != "not approved" {
+		t.Errorf("unexpected message %s", denied.Message)
+	}
+}
@@ -0,0 +1,60 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	ddbTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// putRunAudit writes the final state of the task to the DynamoDB table keyed by task_arn.
+// Failures are logged as warnings and do not affect the result of the run.
+func (d *App) putRunAudit(ctx context.Context, table string, task *types.Task, runErr error) {
+	out, err := d.ecs.DescribeTasks(ctx, d.DescribeTasksInput(task))
+	if err != nil {
+		d.Log("[WARNING] failed to describe task for audit: %s", err)
+		return
+	}
+	if len(out.Tasks) > 0 {
+		task = &out.Tasks[0]
+	}
+	b, err := MarshalJSONForAPI(task)
+	if err != nil {
+		d.Log("[WARNING] failed to marshal task for audit: %s", err)
+		return
+	}
+	result := "succeeded"
+	if runErr != nil {
+		result = runErr.Error()
+	}
+	item := map[string]ddbTypes.AttributeValue{
+		"task_arn":            &ddbTypes.AttributeValueMemberS{Value: aws.ToString(task.TaskArn)},
+		"cluster":             &ddbTypes.AttributeValueMemberS{Value: d.Cluster},
+		"task_definition_arn": &ddbTypes.AttributeValueMemberS{Value: aws.ToString(task.TaskDefinitionArn)},
+		"last_status":         &ddbTypes.AttributeValueMemberS{Value: aws.ToString(task.LastStatus)},
+		"result":              &ddbTypes.AttributeValueMemberS{Value: result},
+		"recorded_at":         &ddbTypes.AttributeValueMemberS{Value: time.Now().Format(time.RFC3339)},
+		"ecspresso_version":   &ddbTypes.AttributeValueMemberS{Value: Version},
+		"task":                &ddbTypes.AttributeValueMemberS{Value: string(b)},
+	}
+	if d.Service != "" {
+		item["service"] = &ddbTypes.AttributeValueMemberS{Value: d.Service}
+	}
+	if r := aws.ToString(task.StoppedReason); r != "" {
+		item["stopped_reason"] = &ddbTypes.AttributeValueMemberS{Value: r}
+	}
+
+	client := dynamodb.NewFromConfig(d.config.awsv2Config)
+	if _, err := client.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(table),
+		Item:      item,
+	}); err != nil {
+		d.Log("[WARNING] %s", fmt.Errorf("failed to put audit item to %s: %w", table, err))
+		return
+	}
+	d.Log("Audit record of task %s is written to %s", arnToName(aws.ToString(task.TaskArn)), table)
+}
@@ -0,0 +1,47 @@
+package ecspresso
+
+import (
+	"math/rand"
+	"time"
+)
+
+// pollBackoff computes the delay between DescribeTasks polls of --custom-waiter.
+type pollBackoff struct {
+	exponential bool
+	base        time.Duration
+	max         time.Duration
+}
+
+// delay returns the delay after the attempt (0-origin).
+// The exponential delay doubles from base for each attempt, and is capped at max.
+func (b pollBackoff) delay(attempt int) time.Duration {
+	if !b.exponential || attempt <= 0 {
+		return b.base
+	}
+	d := b.base
+	for i := 0; i < attempt; i++ {
+		d *= 2
+		if b.max > 0 && d >= b.max {
+			return b.max
+		}
+	}
+	return d
+}
+
+// jitter returns a random delay in [d/2, d] not to poll in lockstep with the other clients.
+func (b pollBackoff) jitter(d time.Duration) time.Duration {
+	if !b.exponential || d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// maxPolls returns the number of polls within the timeout, which start at 0 and are separated by delay.
+// The last poll happens before the timeout elapses even if the timeout is not a multiple of the delay.
+func (b pollBackoff) maxPolls(timeout time.Duration) int {
+	n := 0
+	for at := time.Duration(0); at < timeout; at += b.delay(n - 1) {
+		n++
+	}
+	return n
+}
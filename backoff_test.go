@@ -0,0 +1,58 @@
+package ecspresso_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestPollBackoffDelay(t *testing.T) {
+	s := time.Second
+	constant := ecspresso.PollBackoffDelays(false, 5*s, time.Minute, 4)
+	if diff := cmp.Diff([]time.Duration{5 * s, 5 * s, 5 * s, 5 * s}, constant); diff != "" {
+		t.Errorf("unexpected constant delays %s", diff)
+	}
+	exponential := ecspresso.PollBackoffDelays(true, 5*s, time.Minute, 6)
+	if diff := cmp.Diff([]time.Duration{5 * s, 10 * s, 20 * s, 40 * s, 60 * s, 60 * s}, exponential); diff != "" {
+		t.Errorf("unexpected exponential delays %s", diff)
+	}
+	// must not overflow
+	if d := ecspresso.PollBackoffDelays(true, 5*s, time.Minute, 100)[99]; d != time.Minute {
+		t.Errorf("delay must be capped at max, got %s", d)
+	}
+}
+
+func TestPollBackoffMaxPolls(t *testing.T) {
+	s := time.Second
+	for _, c := range []struct {
+		exponential bool
+		timeout     time.Duration
+		expected    int
+	}{
+		// polls at 0, 6, 12, 18. the timeout is not a multiple of the delay
+		{false, 20 * s, 4},
+		// polls at 0, 6, 12. the deadline is reached at 18
+		{false, 18 * s, 3},
+		{false, 19 * s, 4},
+		{false, 1 * s, 1},
+		// polls at 0, 6, 18, 42, 90, 150
+		{true, 100 * s, 5},
+		{true, 151 * s, 6},
+	} {
+		got := ecspresso.PollBackoffMaxPolls(c.exponential, 6*s, time.Minute, c.timeout)
+		if got != c.expected {
+			t.Errorf("exponential=%t timeout=%s: expected %d polls, got %d", c.exponential, c.timeout, c.expected, got)
+		}
+	}
+}
+
+func TestPollBackoffJitter(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		d := ecspresso.PollBackoffJitter(10 * time.Second)
+		if d < 5*time.Second || d > 10*time.Second {
+			t.Fatalf("jitter out of range: %s", d)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// runTaskWithCascade tries RunTask with each capacity provider in order until the task is placed.
+// Only placement failures fall through to the next capacity provider.
+func (d *App) runTaskWithCascade(ctx context.Context, in *ecs.RunTaskInput, opt *RunOption) ([]types.Task, error) {
+	providers := opt.CapacityProviderCascade
+	if in.LaunchType != "" {
+		d.Log("[WARNING] launch type %s is ignored by --capacity-provider-cascade", in.LaunchType)
+	}
+	var lastErr error
+	for i, provider := range providers {
+		d.Log("Trying capacity provider %s (%d/%d)", provider, i+1, len(providers))
+		in.LaunchType = ""
+		in.CapacityProviderStrategy = []types.CapacityProviderStrategyItem{
+			{CapacityProvider: aws.String(provider), Weight: 1},
+		}
+		tasks, err := d.submitRunTask(ctx, in, opt.RunTaskRate)
+		if err == nil {
+			d.Log("Task is placed with capacity provider %s", provider)
+			return tasks, nil
+		}
+		var pf *ErrPlacementFailure
+		if !errors.As(err, &pf) {
+			return nil, err
+		}
+		d.Log("[WARNING] capacity provider %s failed to place the task: %s %s", provider, pf.Reason, pf.Detail)
+		lastErr = err
+	}
+	return nil, fmt.Errorf("all capacity providers (%s) failed to place the task. last error: %w", strings.Join(providers, ", "), lastErr)
+}
@@ -2,6 +2,7 @@ package ecspresso
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
@@ -16,6 +17,8 @@ type CLIOptions struct {
 	AssumeRoleARN  string            `help:"the ARN of the role to assume" default:"" env:"ECSPRESSO_ASSUME_ROLE_ARN"`
 	Timeout        *time.Duration    `help:"timeout. Override in a configuration file." env:"ECSPRESSO_TIMEOUT"`
 	FilterCommand  string            `help:"filter command" env:"ECSPRESSO_FILTER_COMMAND"`
+	LogFormat      string            `help:"format of the logs of ecspresso (text, json)" default:"text" enum:"text,json" env:"ECSPRESSO_LOG_FORMAT"`
+	LogRateLimit   float64           `help:"max calls per second of GetLogEvents for tailing the logs of the tasks (0 means unlimited)" default:"0" env:"ECSPRESSO_LOG_RATE_LIMIT"`
 
 	Appspec    *AppSpecOption    `cmd:"" help:"output AppSpec YAML for CodeDeploy to STDOUT"`
 	Delete     *DeleteOption     `cmd:"" help:"delete service"`
@@ -171,6 +174,10 @@ func CLI(ctx context.Context, parse CLIParseFunc) (int, error) {
 		return 1, err
 	}
 	if err := dispatchCLI(ctx, sub, usage, opts); err != nil {
+		var ee *ErrExitCode
+		if errors.As(err, &ee) {
+			return ee.Code, err
+		}
 		return 1, err
 	}
 	return 0, nil
@@ -4,6 +4,7 @@ import (
 	"os"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
 	"github.com/kayac/ecspresso/v2"
@@ -421,42 +422,188 @@ var cliTests = []struct {
 		args: []string{"run"},
 		sub:  "run",
 		subOption: &ecspresso.RunOption{
-			DryRun:                 false,
-			TaskDefinition:         "",
-			Wait:                   true,
-			Count:                  int32(1),
-			WatchContainer:         "",
-			PropagateTags:          "",
-			TaskOverrideStr:        "",
-			TaskOverrideFile:       "",
-			SkipTaskDefinition:     false,
-			LatestTaskDefinition:   false,
-			Tags:                   "",
-			WaitUntil:              "stopped",
-			Revision:               ptr(int64(0)),
-			ClientToken:            nil,
-			EBSDeleteOnTermination: ptr(true),
+			DryRun:                  false,
+			TaskDefinition:          "",
+			Wait:                    true,
+			Count:                   int32(1),
+			WatchAll:                false,
+			WatchContainer:          "",
+			PropagateTags:           "",
+			TaskOverrideStr:         "",
+			RunID:                   nil,
+			Format:                  "text",
+			ProgressInterval:        30 * time.Second,
+			TaskOverrideFile:        "",
+			OverridesProfile:        nil,
+			SkipTaskDefinition:      false,
+			LatestTaskDefinition:    false,
+			Tags:                    "",
+			WaitUntil:               "stopped",
+			Revision:                ptr(int64(0)),
+			ArnFile:                 nil,
+			ClientToken:             nil,
+			EBSDeleteOnTermination:  ptr(true),
+			DebugSidecar:            nil,
+			CustomWaiter:            false,
+			PollInterval:            5 * time.Second,
+			PollBackoff:             "constant",
+			PollMaxDelay:            time.Minute,
+			TaskEvents:              false,
+			StrictOverrides:         false,
+			PruneKeep:               0,
+			TargetGroupArn:          nil,
+			TargetGroupTimeout:      5 * time.Minute,
+			ForceAwslogs:            false,
+			ValidateResources:       false,
+			StartedByTemplate:       "",
+			UseDefinitionTimeout:    false,
+			RequireExplicitRevision: false,
+			BySemver:                nil,
+			SemverTag:               "version",
+			LastGood:                false,
+			RetryRun:                0,
+			CapacityProviderCascade: nil,
+			CloneTask:               nil,
+			FromSchedule:            nil,
+			CheckEndpoints:          false,
+			CheckCluster:            false,
+			LogFilterPattern:        nil,
+			LogSinks:                nil,
+			LogPollInterval:         5 * time.Second,
+			LogStreamWait:           3 * time.Second,
+			LogPollConcurrency:      0,
+			OnSuccessScale:          nil,
+			Profile:                 nil,
+			Image:                   nil,
+			KeepTransient:           false,
+			EntryPoint:              nil,
+			EntryPointContainer:     "",
+			Ulimit:                  nil,
+			FromStart:               false,
+			AuditTable:              nil,
+			EnvFile:                 nil,
+			EnvFileContainer:        "",
+			TagSeverity:             false,
+			GoldenLog:               nil,
+			GoldenLogNormalize:      nil,
+			DockerLabel:             nil,
+			MaxClusterTasks:         0,
+			OnCompleteLambda:        nil,
+			OnCompleteLambdaAsync:   false,
+			RequireLogging:          false,
+			RequireLoggingAll:       false,
+			PropagateExitCode:       false,
+			TagExitCode:             false,
+			OverridesSchema:         nil,
+			FIPS:                    false,
+			Regions:                 nil,
+			ApprovalGate:            nil,
+			ApprovalTimeout:         10 * time.Minute,
+			CaptureMetrics:          false,
+			ResultTemplate:          nil,
+			Timings:                 false,
+			At:                      nil,
+			ScheduleRoleArn:         "",
+			ValidateSecrets:         false,
+			Params:                  nil,
+			RunTaskRate:             0,
+			MaxCost:                 0,
+			WatchAlarm:              nil,
+			WatchAlarmWindow:        time.Minute,
 		},
 	},
 	{
 		args: []string{"run", "--no-wait", "--dry-run"},
 		sub:  "run",
 		subOption: &ecspresso.RunOption{
-			DryRun:                 true,
-			TaskDefinition:         "",
-			Wait:                   false,
-			Count:                  int32(1),
-			WatchContainer:         "",
-			PropagateTags:          "",
-			TaskOverrideStr:        "",
-			TaskOverrideFile:       "",
-			SkipTaskDefinition:     false,
-			LatestTaskDefinition:   false,
-			Tags:                   "",
-			WaitUntil:              "stopped",
-			Revision:               ptr(int64(0)),
-			ClientToken:            nil,
-			EBSDeleteOnTermination: ptr(true),
+			DryRun:                  true,
+			TaskDefinition:          "",
+			Wait:                    false,
+			Count:                   int32(1),
+			WatchAll:                false,
+			WatchContainer:          "",
+			PropagateTags:           "",
+			TaskOverrideStr:         "",
+			RunID:                   nil,
+			Format:                  "text",
+			ProgressInterval:        30 * time.Second,
+			TaskOverrideFile:        "",
+			OverridesProfile:        nil,
+			SkipTaskDefinition:      false,
+			LatestTaskDefinition:    false,
+			Tags:                    "",
+			WaitUntil:               "stopped",
+			Revision:                ptr(int64(0)),
+			ArnFile:                 nil,
+			ClientToken:             nil,
+			EBSDeleteOnTermination:  ptr(true),
+			DebugSidecar:            nil,
+			CustomWaiter:            false,
+			PollInterval:            5 * time.Second,
+			PollBackoff:             "constant",
+			PollMaxDelay:            time.Minute,
+			TaskEvents:              false,
+			StrictOverrides:         false,
+			PruneKeep:               0,
+			TargetGroupArn:          nil,
+			TargetGroupTimeout:      5 * time.Minute,
+			ForceAwslogs:            false,
+			ValidateResources:       false,
+			StartedByTemplate:       "",
+			UseDefinitionTimeout:    false,
+			RequireExplicitRevision: false,
+			BySemver:                nil,
+			SemverTag:               "version",
+			LastGood:                false,
+			RetryRun:                0,
+			CapacityProviderCascade: nil,
+			CloneTask:               nil,
+			FromSchedule:            nil,
+			CheckEndpoints:          false,
+			CheckCluster:            false,
+			LogFilterPattern:        nil,
+			LogSinks:                nil,
+			LogPollInterval:         5 * time.Second,
+			LogStreamWait:           3 * time.Second,
+			LogPollConcurrency:      0,
+			OnSuccessScale:          nil,
+			Profile:                 nil,
+			Image:                   nil,
+			KeepTransient:           false,
+			EntryPoint:              nil,
+			EntryPointContainer:     "",
+			Ulimit:                  nil,
+			FromStart:               false,
+			AuditTable:              nil,
+			EnvFile:                 nil,
+			EnvFileContainer:        "",
+			TagSeverity:             false,
+			GoldenLog:               nil,
+			GoldenLogNormalize:      nil,
+			DockerLabel:             nil,
+			MaxClusterTasks:         0,
+			OnCompleteLambda:        nil,
+			OnCompleteLambdaAsync:   false,
+			RequireLogging:          false,
+			RequireLoggingAll:       false,
+			PropagateExitCode:       false,
+			TagExitCode:             false,
+			OverridesSchema:         nil,
+			FIPS:                    false,
+			Regions:                 nil,
+			ApprovalGate:            nil,
+			ApprovalTimeout:         10 * time.Minute,
+			CaptureMetrics:          false,
+			ResultTemplate:          nil,
+			Timings:                 false,
+			At:                      nil,
+			ScheduleRoleArn:         "",
+			ValidateSecrets:         false,
+			Params:                  nil,
+			RunTaskRate:             0,
+			MaxCost:                 0,
+			WatchAlarm:              nil,
+			WatchAlarmWindow:        time.Minute,
 		},
 	},
 	{
@@ -470,42 +617,188 @@ var cliTests = []struct {
 		},
 		sub: "run",
 		subOption: &ecspresso.RunOption{
-			DryRun:                 false,
-			TaskDefinition:         "foo.json",
-			Wait:                   true,
-			Count:                  int32(2),
-			WatchContainer:         "app",
-			PropagateTags:          "SERVICE",
-			TaskOverrideStr:        `{"foo":"bar"}`,
-			TaskOverrideFile:       "overrides.json",
-			SkipTaskDefinition:     false,
-			LatestTaskDefinition:   true,
-			Tags:                   "KeyFoo=ValueFoo,KeyBar=ValueBar",
-			WaitUntil:              "running",
-			Revision:               ptr(int64(1)),
-			ClientToken:            ptr("3abb3a41-c4dc-4c16-a3be-aaab729008a0"),
-			EBSDeleteOnTermination: ptr(true),
+			DryRun:                  false,
+			TaskDefinition:          "foo.json",
+			Wait:                    true,
+			Count:                   int32(2),
+			WatchAll:                false,
+			WatchContainer:          "app",
+			PropagateTags:           "SERVICE",
+			TaskOverrideStr:         `{"foo":"bar"}`,
+			RunID:                   nil,
+			Format:                  "text",
+			ProgressInterval:        30 * time.Second,
+			TaskOverrideFile:        "overrides.json",
+			OverridesProfile:        nil,
+			SkipTaskDefinition:      false,
+			LatestTaskDefinition:    true,
+			Tags:                    "KeyFoo=ValueFoo,KeyBar=ValueBar",
+			WaitUntil:               "running",
+			Revision:                ptr(int64(1)),
+			ArnFile:                 nil,
+			ClientToken:             ptr("3abb3a41-c4dc-4c16-a3be-aaab729008a0"),
+			EBSDeleteOnTermination:  ptr(true),
+			DebugSidecar:            nil,
+			CustomWaiter:            false,
+			PollInterval:            5 * time.Second,
+			PollBackoff:             "constant",
+			PollMaxDelay:            time.Minute,
+			TaskEvents:              false,
+			StrictOverrides:         false,
+			PruneKeep:               0,
+			TargetGroupArn:          nil,
+			TargetGroupTimeout:      5 * time.Minute,
+			ForceAwslogs:            false,
+			ValidateResources:       false,
+			StartedByTemplate:       "",
+			UseDefinitionTimeout:    false,
+			RequireExplicitRevision: false,
+			BySemver:                nil,
+			SemverTag:               "version",
+			LastGood:                false,
+			RetryRun:                0,
+			CapacityProviderCascade: nil,
+			CloneTask:               nil,
+			FromSchedule:            nil,
+			CheckEndpoints:          false,
+			CheckCluster:            false,
+			LogFilterPattern:        nil,
+			LogSinks:                nil,
+			LogPollInterval:         5 * time.Second,
+			LogStreamWait:           3 * time.Second,
+			LogPollConcurrency:      0,
+			OnSuccessScale:          nil,
+			Profile:                 nil,
+			Image:                   nil,
+			KeepTransient:           false,
+			EntryPoint:              nil,
+			EntryPointContainer:     "",
+			Ulimit:                  nil,
+			FromStart:               false,
+			AuditTable:              nil,
+			EnvFile:                 nil,
+			EnvFileContainer:        "",
+			TagSeverity:             false,
+			GoldenLog:               nil,
+			GoldenLogNormalize:      nil,
+			DockerLabel:             nil,
+			MaxClusterTasks:         0,
+			OnCompleteLambda:        nil,
+			OnCompleteLambdaAsync:   false,
+			RequireLogging:          false,
+			RequireLoggingAll:       false,
+			PropagateExitCode:       false,
+			TagExitCode:             false,
+			OverridesSchema:         nil,
+			FIPS:                    false,
+			Regions:                 nil,
+			ApprovalGate:            nil,
+			ApprovalTimeout:         10 * time.Minute,
+			CaptureMetrics:          false,
+			ResultTemplate:          nil,
+			Timings:                 false,
+			At:                      nil,
+			ScheduleRoleArn:         "",
+			ValidateSecrets:         false,
+			Params:                  nil,
+			RunTaskRate:             0,
+			MaxCost:                 0,
+			WatchAlarm:              nil,
+			WatchAlarmWindow:        time.Minute,
 		},
 	},
 	{
 		args: []string{"run", "--no-ebs-delete-on-termination"},
 		sub:  "run",
 		subOption: &ecspresso.RunOption{
-			DryRun:                 false,
-			TaskDefinition:         "",
-			Wait:                   true,
-			Count:                  int32(1),
-			WatchContainer:         "",
-			PropagateTags:          "",
-			TaskOverrideStr:        "",
-			TaskOverrideFile:       "",
-			SkipTaskDefinition:     false,
-			LatestTaskDefinition:   false,
-			Tags:                   "",
-			WaitUntil:              "stopped",
-			Revision:               ptr(int64(0)),
-			ClientToken:            nil,
-			EBSDeleteOnTermination: ptr(false),
+			DryRun:                  false,
+			TaskDefinition:          "",
+			Wait:                    true,
+			Count:                   int32(1),
+			WatchAll:                false,
+			WatchContainer:          "",
+			PropagateTags:           "",
+			TaskOverrideStr:         "",
+			RunID:                   nil,
+			Format:                  "text",
+			ProgressInterval:        30 * time.Second,
+			TaskOverrideFile:        "",
+			OverridesProfile:        nil,
+			SkipTaskDefinition:      false,
+			LatestTaskDefinition:    false,
+			Tags:                    "",
+			WaitUntil:               "stopped",
+			Revision:                ptr(int64(0)),
+			ArnFile:                 nil,
+			ClientToken:             nil,
+			EBSDeleteOnTermination:  ptr(false),
+			DebugSidecar:            nil,
+			CustomWaiter:            false,
+			PollInterval:            5 * time.Second,
+			PollBackoff:             "constant",
+			PollMaxDelay:            time.Minute,
+			TaskEvents:              false,
+			StrictOverrides:         false,
+			PruneKeep:               0,
+			TargetGroupArn:          nil,
+			TargetGroupTimeout:      5 * time.Minute,
+			ForceAwslogs:            false,
+			ValidateResources:       false,
+			StartedByTemplate:       "",
+			UseDefinitionTimeout:    false,
+			RequireExplicitRevision: false,
+			BySemver:                nil,
+			SemverTag:               "version",
+			LastGood:                false,
+			RetryRun:                0,
+			CapacityProviderCascade: nil,
+			CloneTask:               nil,
+			FromSchedule:            nil,
+			CheckEndpoints:          false,
+			CheckCluster:            false,
+			LogFilterPattern:        nil,
+			LogSinks:                nil,
+			LogPollInterval:         5 * time.Second,
+			LogStreamWait:           3 * time.Second,
+			LogPollConcurrency:      0,
+			OnSuccessScale:          nil,
+			Profile:                 nil,
+			Image:                   nil,
+			KeepTransient:           false,
+			EntryPoint:              nil,
+			EntryPointContainer:     "",
+			Ulimit:                  nil,
+			FromStart:               false,
+			AuditTable:              nil,
+			EnvFile:                 nil,
+			EnvFileContainer:        "",
+			TagSeverity:             false,
+			GoldenLog:               nil,
+			GoldenLogNormalize:      nil,
+			DockerLabel:             nil,
+			MaxClusterTasks:         0,
+			OnCompleteLambda:        nil,
+			OnCompleteLambdaAsync:   false,
+			RequireLogging:          false,
+			RequireLoggingAll:       false,
+			PropagateExitCode:       false,
+			TagExitCode:             false,
+			OverridesSchema:         nil,
+			FIPS:                    false,
+			Regions:                 nil,
+			ApprovalGate:            nil,
+			ApprovalTimeout:         10 * time.Minute,
+			CaptureMetrics:          false,
+			ResultTemplate:          nil,
+			Timings:                 false,
+			At:                      nil,
+			ScheduleRoleArn:         "",
+			ValidateSecrets:         false,
+			Params:                  nil,
+			RunTaskRate:             0,
+			MaxCost:                 0,
+			WatchAlarm:              nil,
+			WatchAlarmWindow:        time.Minute,
 		},
 	},
 	{
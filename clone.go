@@ -0,0 +1,38 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// taskOverrideOfTask returns the overrides which the task actually ran with.
+func (d *App) taskOverrideOfTask(ctx context.Context, taskID string) (types.TaskOverride, error) {
+	out, err := d.ecs.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+		Cluster: aws.String(d.Cluster),
+		Tasks:   []string{taskID},
+	})
+	if err != nil {
+		return types.TaskOverride{}, fmt.Errorf("failed to describe task %s: %w", taskID, err)
+	}
+	if len(out.Failures) > 0 {
+		f := out.Failures[0]
+		if aws.ToString(f.Reason) == "MISSING" {
+			return types.TaskOverride{}, ErrNotFound(fmt.Sprintf(
+				"task %s is not found in cluster %s. ECS retains the details of stopped tasks only for a short time, so the task can not be cloned", taskID, d.Cluster))
+		}
+		return types.TaskOverride{}, fmt.Errorf("failed to describe task %s: %s %s", taskID, aws.ToString(f.Reason), aws.ToString(f.Detail))
+	}
+	if len(out.Tasks) == 0 {
+		return types.TaskOverride{}, ErrNotFound(fmt.Sprintf("task %s is not found in cluster %s", taskID, d.Cluster))
+	}
+	task := out.Tasks[0]
+	d.Log("Cloning the overrides of task %s (%s)", arnToName(aws.ToString(task.TaskArn)), arnToName(aws.ToString(task.TaskDefinitionArn)))
+	if task.Overrides == nil {
+		return types.TaskOverride{}, nil
+	}
+	return *task.Overrides, nil
+}
@@ -0,0 +1,78 @@
+package ecspresso
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/samber/lo"
+)
+
+// renderCommandTemplate expands ${NAME} (or $NAME) in each element of the command template with params.
+// The template functions in the config are already evaluated on loading the config.
+// It fails on unresolved parameters.
+func renderCommandTemplate(container string, tmpl []string, params map[string]string) ([]string, error) {
+	var missing []string
+	command := make([]string, 0, len(tmpl))
+	for _, src := range tmpl {
+		command = append(command, os.Expand(src, func(name string) string {
+			v, ok := params[name]
+			if !ok {
+				missing = append(missing, name)
+			}
+			return v
+		}))
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("unresolved parameters in command template of container %s: %s. set them by --param", container, strings.Join(lo.Uniq(missing), ", "))
+	}
+	return command, nil
+}
+
+// applyCommandTemplates sets the commands rendered from the command templates in the config to the overrides.
+// The commands defined in the overrides take precedence.
+func (d *App) applyCommandTemplates(ov *types.TaskOverride, params map[string]string) error {
+	templates := d.config.CommandTemplates
+	if len(templates) == 0 {
+		if len(params) > 0 {
+			d.Log("[WARNING] --param is specified but no command_templates are defined in the config")
+		}
+		return nil
+	}
+	containers := make([]string, 0, len(templates))
+	for name := range templates {
+		containers = append(containers, name)
+	}
+	sort.Strings(containers)
+	for _, name := range containers {
+		command, err := renderCommandTemplate(name, templates[name], params)
+		if err != nil {
+			return err
+		}
+		co := containerOverrideOf(ov, name)
+		if len(co.Command) > 0 {
+			d.Log("[WARNING] command of container %s is defined in the overrides. command template is ignored", name)
+			continue
+		}
+		co.Command = command
+		d.Log("Command of container %s: %s", name, jsonStr(command))
+	}
+	return nil
+}
+
+// containerOverrideOf returns the container override for the container.
+// A new container override is appended if not exists.
+func containerOverrideOf(ov *types.TaskOverride, container string) *types.ContainerOverride {
+	for i := range ov.ContainerOverrides {
+		if aws.ToString(ov.ContainerOverrides[i].Name) == container {
+			return &ov.ContainerOverrides[i]
+		}
+	}
+	ov.ContainerOverrides = append(ov.ContainerOverrides, types.ContainerOverride{
+		Name: aws.String(container),
+	})
+	return &ov.ContainerOverrides[len(ov.ContainerOverrides)-1]
+}
@@ -0,0 +1,43 @@
+package ecspresso_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestApplyCommandTemplates(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/command-templates.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ov := types.TaskOverride{
+		ContainerOverrides: []types.ContainerOverride{
+			{Name: aws.String("worker"), Command: []string{"from-overrides"}},
+		},
+	}
+	if err := app.ApplyCommandTemplates(&ov, map[string]string{"DATE": "2024-01-01", "MODE": "fast"}); err != nil {
+		t.Fatal(err)
+	}
+	commands := map[string][]string{}
+	for _, co := range ov.ContainerOverrides {
+		commands[aws.ToString(co.Name)] = co.Command
+	}
+	expected := map[string][]string{
+		"app":    {"batch", "--date=2024-01-01", "--cluster=default"},
+		"worker": {"from-overrides"}, // overrides take precedence
+	}
+	if diff := cmp.Diff(expected, commands); diff != "" {
+		t.Error(diff)
+	}
+
+	// unresolved parameter
+	if err := app.ApplyCommandTemplates(&types.TaskOverride{}, nil); err == nil {
+		t.Error("expected error for the unresolved parameter, got nil")
+	}
+}
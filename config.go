@@ -5,12 +5,14 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"text/template"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/google/go-jsonnet"
 	goVersion "github.com/hashicorp/go-version"
@@ -28,6 +30,14 @@ var awsv2ConfigLoadOptionsFunc []func(*awsConfig.LoadOptions) error
 type configLoader struct {
 	*goConfig.Loader
 	VM *jsonnet.VM
+
+	vmMu sync.Mutex // jsonnet.VM is not safe for concurrent use
+}
+
+func (l *configLoader) evaluateFile(path string) (string, error) {
+	l.vmMu.Lock()
+	defer l.vmMu.Unlock()
+	return l.VM.EvaluateFile(path)
 }
 
 func newConfigLoader(extStr, extCode map[string]string) *configLoader {
@@ -46,17 +56,21 @@ func newConfigLoader(extStr, extCode map[string]string) *configLoader {
 
 // Config represents a configuration.
 type Config struct {
-	RequiredVersion       string            `yaml:"required_version,omitempty" json:"required_version,omitempty"`
-	Region                string            `yaml:"region" json:"region"`
-	Cluster               string            `yaml:"cluster" json:"cluster"`
-	Service               string            `yaml:"service" json:"service"`
-	ServiceDefinitionPath string            `yaml:"service_definition" json:"service_definition"`
-	TaskDefinitionPath    string            `yaml:"task_definition" json:"task_definition"`
-	Plugins               []ConfigPlugin    `yaml:"plugins,omitempty" json:"plugins,omitempty"`
-	AppSpec               *appspec.AppSpec  `yaml:"appspec,omitempty" json:"appspec,omitempty"`
-	FilterCommand         string            `yaml:"filter_command,omitempty" json:"filter_command,omitempty"`
-	Timeout               *Duration         `yaml:"timeout,omitempty" json:"timeout,omitempty"`
-	CodeDeploy            *ConfigCodeDeploy `yaml:"codedeploy,omitempty" json:"codedeploy,omitempty"`
+	RequiredVersion       string                         `yaml:"required_version,omitempty" json:"required_version,omitempty"`
+	Region                string                         `yaml:"region" json:"region"`
+	Cluster               string                         `yaml:"cluster" json:"cluster"`
+	Service               string                         `yaml:"service" json:"service"`
+	ServiceDefinitionPath string                         `yaml:"service_definition" json:"service_definition"`
+	TaskDefinitionPath    string                         `yaml:"task_definition" json:"task_definition"`
+	Plugins               []ConfigPlugin                 `yaml:"plugins,omitempty" json:"plugins,omitempty"`
+	AppSpec               *appspec.AppSpec               `yaml:"appspec,omitempty" json:"appspec,omitempty"`
+	FilterCommand         string                         `yaml:"filter_command,omitempty" json:"filter_command,omitempty"`
+	Timeout               *Duration                      `yaml:"timeout,omitempty" json:"timeout,omitempty"`
+	CodeDeploy            *ConfigCodeDeploy              `yaml:"codedeploy,omitempty" json:"codedeploy,omitempty"`
+	ExitCodeSeverities    []*ConfigExitCodeSeverity      `yaml:"exit_code_severities,omitempty" json:"exit_code_severities,omitempty"`
+	CommandTemplates      map[string][]string            `yaml:"command_templates,omitempty" json:"command_templates,omitempty"`
+	OverridesProfiles     map[string]*types.TaskOverride `yaml:"overrides_profiles,omitempty" json:"overrides_profiles,omitempty"`
+	DefaultTags           map[string]string              `yaml:"default_tags,omitempty" json:"default_tags,omitempty"`
 
 	path               string
 	templateFuncs      []template.FuncMap
@@ -148,6 +162,11 @@ func (c *Config) Restrict(ctx context.Context) error {
 	if c.Region == "" {
 		c.Region = os.Getenv("AWS_REGION")
 	}
+	for _, s := range c.ExitCodeSeverities {
+		if err := s.Restrict(); err != nil {
+			return fmt.Errorf("exit_code_severities: %w", err)
+		}
+	}
 	var err error
 	var optsFunc []func(*awsConfig.LoadOptions) error
 	if len(awsv2ConfigLoadOptionsFunc) == 0 {
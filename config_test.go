@@ -323,3 +323,37 @@ func TestFilterCommandDeprecated(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadConfigExitCodeSeverities(t *testing.T) {
+	ctx := context.Background()
+	loader := ecspresso.NewConfigLoader(nil, nil)
+	conf, err := loader.Load(ctx, "tests/exit_code_severities.yml", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []struct {
+		code     int32
+		severity string
+		exitCode *int
+	}{
+		{0, "success", nil},
+		{1, "retryable", aws.Int(75)},
+		{9, "retryable", aws.Int(75)},
+		{10, "fatal", nil},
+		{137, "killed", nil}, // the first matched entry wins
+		{255, "fatal", nil},
+	} {
+		severity, exitCode := conf.ExitCodeSeverity(s.code)
+		if severity != s.severity {
+			t.Errorf("exit code %d: unexpected severity %s, expected %s", s.code, severity, s.severity)
+		}
+		if aws.ToInt(exitCode) != aws.ToInt(s.exitCode) {
+			t.Errorf("exit code %d: unexpected process exit code %v, expected %v", s.code, exitCode, s.exitCode)
+		}
+	}
+
+	conf = ecspresso.NewDefaultConfig()
+	if severity, _ := conf.ExitCodeSeverity(1); severity != "failure" {
+		t.Errorf("unexpected default severity %s", severity)
+	}
+}
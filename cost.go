@@ -0,0 +1,152 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// fargatePrice is the on-demand price of Fargate in USD (us-east-1).
+// The estimate is an approximation; the actual price depends on the region and Fargate Spot.
+// See https://aws.amazon.com/fargate/pricing/
+type fargatePrice struct {
+	vCPUHour    float64
+	gbHour      float64
+	storageHour float64 // per GB of ephemeral storage over 20 GB
+}
+
+var (
+	fargatePriceX86   = fargatePrice{vCPUHour: 0.04048, gbHour: 0.004445, storageHour: 0.000111}
+	fargatePriceARM64 = fargatePrice{vCPUHour: 0.03238, gbHour: 0.00356, storageHour: 0.000111}
+)
+
+var costReportInterval = time.Minute
+
+// effectiveTaskResources returns the task-level cpu (units) and memory (MiB) of td overridden by ov.
+func effectiveTaskResources(td *TaskDefinitionInput, ov *types.TaskOverride) (float64, float64, error) {
+	cpu, memory := aws.ToString(td.Cpu), aws.ToString(td.Memory)
+	if ov != nil {
+		if c := aws.ToString(ov.Cpu); c != "" {
+			cpu = c
+		}
+		if m := aws.ToString(ov.Memory); m != "" {
+			memory = m
+		}
+	}
+	units, err := strconv.ParseFloat(aws.ToString(toNumberCPU(cpu)), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid cpu %q of the task: %w", cpu, err)
+	}
+	mib, err := strconv.ParseFloat(aws.ToString(toNumberMemory(memory)), 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid memory %q of the task: %w", memory, err)
+	}
+	return units, mib, nil
+}
+
+// validateMaxResources validates the task-level cpu and memory of td overridden by ov do not exceed maxCPU and maxMemory.
+// 0 means unlimited.
+func validateMaxResources(td *TaskDefinitionInput, ov *types.TaskOverride, maxCPU, maxMemory int) error {
+	units, mib, err := effectiveTaskResources(td, ov)
+	if err != nil {
+		return fmt.Errorf("failed to check the resources of the task: %w", err)
+	}
+	var exceeded []string
+	if maxCPU > 0 && units > float64(maxCPU) {
+		exceeded = append(exceeded, fmt.Sprintf("cpu %g exceeds --max-cpu %d", units, maxCPU))
+	}
+	if maxMemory > 0 && mib > float64(maxMemory) {
+		exceeded = append(exceeded, fmt.Sprintf("memory %g MiB exceeds --max-memory %d", mib, maxMemory))
+	}
+	if len(exceeded) > 0 {
+		return fmt.Errorf("refused to run the task: %s", strings.Join(exceeded, ", "))
+	}
+	return nil
+}
+
+// fargateHourlyCost estimates the hourly cost of the Fargate task from the resources of td overridden by ov.
+func fargateHourlyCost(td *TaskDefinitionInput, ov *types.TaskOverride) (float64, error) {
+	units, mib, err := effectiveTaskResources(td, ov)
+	if err != nil {
+		return 0, err
+	}
+	price := fargatePriceX86
+	if rp := td.RuntimePlatform; rp != nil && rp.CpuArchitecture == types.CPUArchitectureArm64 {
+		price = fargatePriceARM64
+	}
+	hourly := units/1024*price.vCPUHour + mib/1024*price.gbHour
+	storage := int32(0)
+	if td.EphemeralStorage != nil {
+		storage = td.EphemeralStorage.SizeInGiB
+	}
+	if ov != nil && ov.EphemeralStorage != nil {
+		storage = ov.EphemeralStorage.SizeInGiB
+	}
+	if storage > 20 {
+		hourly += float64(storage-20) * price.storageHour
+	}
+	return hourly, nil
+}
+
+// hourlyCostForRun estimates the hourly cost of the task to run. It supports Fargate only.
+func (d *App) hourlyCostForRun(td *TaskDefinitionInput, ov *types.TaskOverride, opt *RunOption) (float64, error) {
+	sv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
+	if err != nil {
+		return 0, err
+	}
+	launchType, strategy, err := launchSettingsForRun(sv, opt)
+	if err != nil {
+		return 0, err
+	}
+	if !isFargate(launchType, strategy) {
+		return 0, ErrConflictOptions("max-cost supports Fargate tasks only")
+	}
+	hourly, err := fargateHourlyCost(td, ov)
+	if err != nil {
+		return 0, err
+	}
+	d.Log("Estimated hourly cost of the task: $%.4f", hourly)
+	return hourly, nil
+}
+
+// watchCost estimates the accrued cost of the tasks and stops all the tasks when it exceeds maxCost.
+// hourly is the hourly cost of a task.
+func (d *App) watchCost(ctx context.Context, tasks []types.Task, hourly float64, maxCost float64) {
+	startedAt := time.Now()
+	ticker := time.NewTicker(costReportInterval)
+	defer ticker.Stop()
+	total := hourly * float64(len(tasks))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		cost := total * time.Since(startedAt).Hours()
+		d.Log("Estimated cost of %d tasks: $%.4f (max $%.4f)", len(tasks), cost, maxCost)
+		if cost < maxCost {
+			continue
+		}
+		d.Log("[WARNING] estimated cost $%.4f exceeds max $%.4f. stopping %d tasks", cost, maxCost, len(tasks))
+		failed := false
+		for _, task := range tasks {
+			if _, err := d.ecs.StopTask(ctx, &ecs.StopTaskInput{
+				Cluster: aws.String(d.Cluster),
+				Task:    task.TaskArn,
+				Reason:  aws.String(fmt.Sprintf("stopped by ecspresso: estimated cost exceeds $%.4f", maxCost)),
+			}); err != nil {
+				d.Log("[WARNING] failed to stop the task %s: %s", arnToName(aws.ToString(task.TaskArn)), err)
+				failed = true
+			}
+		}
+		if !failed {
+			return
+		}
+	}
+}
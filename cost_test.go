@@ -0,0 +1,136 @@
+package ecspresso_test
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestFargateHourlyCost(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		td       *ecspresso.TaskDefinitionInput
+		ov       *types.TaskOverride
+		expected float64
+	}{
+		{
+			name:     "1 vCPU 2 GB",
+			td:       &ecspresso.TaskDefinitionInput{Cpu: aws.String("1024"), Memory: aws.String("2048")},
+			expected: 0.04048 + 2*0.004445,
+		},
+		{
+			name:     "vCPU and GB notation",
+			td:       &ecspresso.TaskDefinitionInput{Cpu: aws.String("0.5 vCPU"), Memory: aws.String("1 GB")},
+			expected: 0.5*0.04048 + 0.004445,
+		},
+		{
+			name:     "overridden",
+			td:       &ecspresso.TaskDefinitionInput{Cpu: aws.String("256"), Memory: aws.String("512")},
+			ov:       &types.TaskOverride{Cpu: aws.String("2048"), Memory: aws.String("4096")},
+			expected: 2*0.04048 + 4*0.004445,
+		},
+		{
+			name: "arm64 with ephemeral storage",
+			td: &ecspresso.TaskDefinitionInput{
+				Cpu:              aws.String("1024"),
+				Memory:           aws.String("2048"),
+				RuntimePlatform:  &types.RuntimePlatform{CpuArchitecture: types.CPUArchitectureArm64},
+				EphemeralStorage: &types.EphemeralStorage{SizeInGiB: 30},
+			},
+			expected: 0.03238 + 2*0.00356 + 10*0.000111,
+		},
+	} {
+		got, err := ecspresso.FargateHourlyCost(c.td, c.ov)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", c.name, err)
+			continue
+		}
+		if math.Abs(got-c.expected) > 1e-9 {
+			t.Errorf("%s: expected %f, got %f", c.name, c.expected, got)
+		}
+	}
+	if _, err := ecspresso.FargateHourlyCost(&ecspresso.TaskDefinitionInput{}, nil); err == nil {
+		t.Error("expected error for the task without cpu and memory")
+	}
+}
+
+func TestValidateMaxResources(t *testing.T) {
+	td := &ecspresso.TaskDefinitionInput{Cpu: aws.String("1024"), Memory: aws.String("2 GB")}
+	for _, c := range []struct {
+		name      string
+		ov        *types.TaskOverride
+		maxCPU    int
+		maxMemory int
+		expected  string
+	}{
+		{name: "within", maxCPU: 1024, maxMemory: 2048},
+		{name: "unlimited", ov: &types.TaskOverride{Cpu: aws.String("16384")}},
+		{
+			name:     "cpu exceeded",
+			maxCPU:   512,
+			expected: "refused to run the task: cpu 1024 exceeds --max-cpu 512",
+		},
+		{
+			name:      "overridden",
+			ov:        &types.TaskOverride{Cpu: aws.String("4 vCPU"), Memory: aws.String("8192")},
+			maxCPU:    2048,
+			maxMemory: 4096,
+			expected:  "refused to run the task: cpu 4096 exceeds --max-cpu 2048, memory 8192 MiB exceeds --max-memory 4096",
+		},
+	} {
+		err := ecspresso.ValidateMaxResources(td, c.ov, c.maxCPU, c.maxMemory)
+		if c.expected == "" {
+			if err != nil {
+				t.Errorf("%s: unexpected error %s", c.name, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != c.expected {
+			t.Errorf("%s: expected error %q, got %v", c.name, c.expected, err)
+		}
+	}
+	if err := ecspresso.ValidateMaxResources(&ecspresso.TaskDefinitionInput{}, nil, 1024, 0); err == nil {
+		t.Error("expected error for the task without cpu and memory")
+	}
+}
+
+func TestHourlyCostForRunLaunchType(t *testing.T) {
+	// the service definition of run-with-sv.yaml has the EC2 launch type
+	app := newRunTestApp(t)
+	td := &ecspresso.TaskDefinitionInput{Cpu: aws.String("256"), Memory: aws.String("512")}
+	if _, err := app.HourlyCostForRun(td, ecspresso.RunOption{}); err == nil {
+		t.Error("expected error for the EC2 launch type")
+	}
+	for _, opt := range []ecspresso.RunOption{
+		{LaunchType: "FARGATE"},
+		{CapacityProviderStrategy: `[{"capacityProvider":"FARGATE_SPOT","weight":1}]`},
+	} {
+		if cost, err := app.HourlyCostForRun(td, opt); err != nil {
+			t.Errorf("unexpected error: %s", err)
+		} else if cost <= 0 {
+			t.Errorf("unexpected cost %f", cost)
+		}
+	}
+}
+
+func TestWatchCostStopsAllTasks(t *testing.T) {
+	app := newRunTestApp(t)
+	tasks := []types.Task{
+		{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001")},
+		{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0002")},
+		{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0003")},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	stopTaskCalls = 0
+	// $1 per task per hour exceeds $0.0000001 in total at the first tick
+	app.WatchCost(ctx, tasks, 1, 0.0000001, 10*time.Millisecond)
+	if stopTaskCalls != len(tasks) {
+		t.Errorf("expected %d calls of StopTask, got %d", len(tasks), stopTaskCalls)
+	}
+}
@@ -109,16 +109,20 @@ func (d *App) deregiserRevision(ctx context.Context, opt DeregisterOption, inUse
 }
 
 func (d *App) deregisterKeeps(ctx context.Context, opt DeregisterOption, inUse map[string]string) error {
-	keeps := aws.ToInt(opt.Keeps)
 	td, err := d.LoadTaskDefinition(d.config.TaskDefinitionPath)
 	if err != nil {
 		return err
 	}
+	return d.deregisterKeepsFamily(ctx, aws.ToString(td.Family), opt, inUse)
+}
+
+func (d *App) deregisterKeepsFamily(ctx context.Context, family string, opt DeregisterOption, inUse map[string]string) error {
+	keeps := aws.ToInt(opt.Keeps)
 	names := []string{}
 	var nextToken *string
 	for {
 		res, err := d.ecs.ListTaskDefinitions(ctx, &ecs.ListTaskDefinitionsInput{
-			FamilyPrefix: td.Family,
+			FamilyPrefix: aws.String(family),
 			NextToken:    nextToken,
 		})
 		if err != nil {
@@ -0,0 +1,68 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// registersTaskDefinition reports whether the run registers a new task definition.
+func (opt RunOption) registersTaskDefinition() bool {
+	return *opt.Revision <= 0 && opt.BySemver == nil && !opt.LastGood &&
+		!opt.LatestTaskDefinition && !opt.SkipTaskDefinition
+}
+
+// dryRunTaskInput builds the RunTaskInput which would be sent to ECS.
+// When the run registers a new task definition, the family of the local task definition is used instead of the ARN.
+func (d *App) dryRunTaskInput(ctx context.Context, tdArn string, ov types.TaskOverride, opt RunOption, registers bool) (*ecs.RunTaskInput, error) {
+	var td *TaskDefinitionInput
+	var err error
+	if registers {
+		tdPath := opt.TaskDefinition
+		if tdPath == "" {
+			tdPath = d.config.TaskDefinitionPath
+		}
+		if td, err = d.LoadTaskDefinition(tdPath); err != nil {
+			return nil, err
+		}
+		tdArn = aws.ToString(td.Family)
+	} else if td, err = d.DescribeTaskDefinition(ctx, tdArn); err != nil {
+		return nil, err
+	}
+	if opt.Cpu != "" || opt.Memory != "" {
+		if err := d.validateResourceOverridesForRun(td, &ov, &opt); err != nil {
+			return nil, err
+		}
+	}
+	watchContainer, err := watchContainerOf(td, opt.WatchContainer)
+	if err != nil {
+		return nil, err
+	}
+	if len(opt.EnvFile) > 0 {
+		container := opt.EnvFileContainer
+		if container == "" {
+			container = *watchContainer.Name
+		}
+		if err := d.applyEnvFiles(&ov, opt.EnvFile, container, td); err != nil {
+			return nil, err
+		}
+	}
+	setRunIDEnv(&ov, *watchContainer.Name, *opt.RunID)
+	in, err := d.runTaskInput(ctx, tdArn, &ov, &opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build the input of RunTask: %w", err)
+	}
+	return in, nil
+}
+
+func (d *App) outputDryRunTaskInput(ctx context.Context, tdArn string, ov types.TaskOverride, opt RunOption, registers bool) error {
+	in, err := d.dryRunTaskInput(ctx, tdArn, ov, opt, registers)
+	if err != nil {
+		return err
+	}
+	return d.OutputJSONForAPI(os.Stdout, in)
+}
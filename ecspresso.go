@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"sort"
@@ -14,6 +15,7 @@ import (
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/applicationautoscaling"
 	aasTypes "github.com/aws/aws-sdk-go-v2/service/applicationautoscaling/types"
 	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
@@ -122,12 +124,35 @@ type App struct {
 	config *Config
 	loader *configLoader
 	logger *log.Logger
+
+	// logPrefix is prepended to the logs (e.g. the region for multi-region runs)
+	logPrefix string
+	// runIDPrefix is prepended to the logs of ecspresso only, not to the logs of the task
+	runIDPrefix string
+
+	// logWriter is the output of the logs of the task (--log-sink). nil means stdout
+	logWriter io.Writer
+
+	// waitTimeoutOverride overrides the timeout of the config for waiting for the task (--wait-timeout, --use-definition-timeout)
+	waitTimeoutOverride time.Duration
+
+	// serviceCluster is the cluster of the service when Cluster is overridden to run the task (run --cluster)
+	serviceCluster string
+
+	// taskDefinitionHook modifies the task definition before registering it in run
+	taskDefinitionHook func(*TaskDefinitionInput) error
+
+	// logsLimiter limits the calls of GetLogEvents (and FilterLogEvents) for tailing the logs.
+	// It is shared by the copies of the App, so all the runs by the App are limited together.
+	logsLimiter *tokenBucket
 }
 
 type appOptions struct {
-	config *Config
-	loader *configLoader
-	logger *log.Logger
+	config             *Config
+	loader             *configLoader
+	logger             *log.Logger
+	taskDefinitionHook func(*TaskDefinitionInput) error
+	logsLimiter        *tokenBucket
 }
 
 type AppOption func(*appOptions)
@@ -150,12 +175,30 @@ func WithLogger(l *log.Logger) AppOption {
 	}
 }
 
+// WithTaskDefinitionHook sets the hook which modifies the task definition
+// just before registering it in run (e.g. injecting a sidecar, or bumping the cpu).
+// The task definition is not registered when the hook returns an error.
+func WithTaskDefinitionHook(fn func(*TaskDefinitionInput) error) AppOption {
+	return func(o *appOptions) {
+		o.taskDefinitionHook = fn
+	}
+}
+
+// WithLogRateLimit limits the calls of GetLogEvents for tailing the logs to rate (calls per second)
+// allowing burst, shared by all the runs by the App. rate <= 0 means unlimited.
+func WithLogRateLimit(rate float64, burst int) AppOption {
+	return func(o *appOptions) {
+		o.logsLimiter = newTokenBucket(rate, burst)
+	}
+}
+
 func New(ctx context.Context, opt *CLIOptions, newAppOptions ...AppOption) (*App, error) {
 	opt.resolveConfigFilePath()
 
 	appOpts := appOptions{
-		loader: newConfigLoader(opt.ExtStr, opt.ExtCode),
-		logger: newLogger(),
+		loader:      newConfigLoader(opt.ExtStr, opt.ExtCode),
+		logger:      newLogger(),
+		logsLimiter: newTokenBucket(opt.LogRateLimit, 1),
 	}
 	for _, fn := range newAppOptions {
 		fn(&appOpts)
@@ -167,6 +210,14 @@ func New(ctx context.Context, opt *CLIOptions, newAppOptions ...AppOption) (*App
 	} else {
 		appOpts.logger.SetOutput(newLogFilter(os.Stderr, "INFO"))
 	}
+	if opt.LogFormat == "json" {
+		level := "INFO"
+		if opt.Debug {
+			level = "DEBUG"
+		}
+		setJSONLogFormat(appOpts.logger, level)
+		setJSONLogFormat(commonLogger, "INFO")
+	}
 	Log("[INFO] ecspresso version: %s", Version)
 
 	// load config file
@@ -196,6 +247,9 @@ func New(ctx context.Context, opt *CLIOptions, newAppOptions ...AppOption) (*App
 		loader:      appOpts.loader,
 		config:      appOpts.config,
 		logger:      appOpts.logger,
+
+		taskDefinitionHook: appOpts.taskDefinitionHook,
+		logsLimiter:        appOpts.logsLimiter,
 	}
 
 	d.Log("[DEBUG] config file path: %s", opt.ConfigFilePath)
@@ -203,6 +257,38 @@ func New(ctx context.Context, opt *CLIOptions, newAppOptions ...AppOption) (*App
 	return d, nil
 }
 
+// withAWSConfig returns a copy of the App which uses the AWS clients built from cfg.
+func (d *App) withAWSConfig(cfg aws.Config) *App {
+	nd := *d
+	conf := *d.config
+	conf.awsv2Config = cfg
+	nd.config = &conf
+	nd.ecs = ecs.NewFromConfig(cfg)
+	nd.autoScaling = applicationautoscaling.NewFromConfig(cfg)
+	nd.codedeploy = codedeploy.NewFromConfig(cfg)
+	nd.cwl = cloudwatchlogs.NewFromConfig(cfg)
+	nd.iam = iam.NewFromConfig(cfg)
+	nd.elbv2 = elasticloadbalancingv2.NewFromConfig(cfg)
+	nd.sd = servicediscovery.NewFromConfig(cfg)
+	return &nd
+}
+
+// withProfile returns a copy of the App which uses the AWS clients for the shared config profile.
+func (d *App) withProfile(ctx context.Context, profile string) (*App, error) {
+	cfg, err := awsConfig.LoadDefaultConfig(ctx,
+		awsConfig.WithRegion(d.config.Region),
+		awsConfig.WithSharedConfigProfile(profile),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load aws config for profile %s: %w", profile, err)
+	}
+	if _, err := cfg.Credentials.Retrieve(ctx); err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials for profile %s: %w", profile, err)
+	}
+	d.Log("Using AWS profile %s", profile)
+	return d.withAWSConfig(cfg), nil
+}
+
 func (d *App) Config() *Config {
 	return d.config
 }
@@ -221,12 +307,29 @@ func (d *App) Start(ctx context.Context) (context.Context, context.CancelFunc) {
 
 func (d *App) DescribeServicesInput() *ecs.DescribeServicesInput {
 	return &ecs.DescribeServicesInput{
-		Cluster:  aws.String(d.Cluster),
+		Cluster:  aws.String(d.clusterOfService()),
 		Services: []string{d.Service},
 		Include:  []types.ServiceField{types.ServiceFieldTags},
 	}
 }
 
+// clusterOfService returns the cluster of the service, which may differ from the cluster to run the task.
+func (d *App) clusterOfService() string {
+	if d.serviceCluster != "" {
+		return d.serviceCluster
+	}
+	return d.Cluster
+}
+
+// withRunCluster returns a copy of the App which runs the task in the cluster.
+// The service is still looked up in the cluster of the config.
+func (d *App) withRunCluster(cluster string) *App {
+	nd := *d
+	nd.serviceCluster = d.clusterOfService()
+	nd.Cluster = cluster
+	return &nd
+}
+
 func (d *App) DescribeTasksInput(task *types.Task) *ecs.DescribeTasksInput {
 	return &ecs.DescribeTasksInput{
 		Cluster: aws.String(d.Cluster),
@@ -349,42 +452,8 @@ func (d *App) describeAutoScaling(ctx context.Context, s *Service) error {
 }
 
 func (d *App) DescribeTaskStatus(ctx context.Context, task *types.Task, watchContainer *types.ContainerDefinition) error {
-	out, err := d.ecs.DescribeTasks(ctx, d.DescribeTasksInput(task))
-	if err != nil {
-		return fmt.Errorf("failed to describe tasks: %w", err)
-	}
-	if len(out.Failures) > 0 {
-		f := out.Failures[0]
-		d.Log("Task ARN: " + *f.Arn)
-		return fmt.Errorf(*f.Reason)
-	}
-
-	ts := out.Tasks[0]
-	if ts.StopCode == types.TaskStopCodeTaskFailedToStart {
-		return fmt.Errorf("task failed to start: %s", aws.ToString(ts.StoppedReason))
-	}
-
-	var container *types.Container
-	for _, c := range ts.Containers {
-		if *c.Name == *watchContainer.Name {
-			container = &c
-			break
-		}
-	}
-	if container == nil {
-		container = &(ts.Containers[0])
-	}
-
-	if container.ExitCode != nil && *container.ExitCode != 0 {
-		msg := fmt.Sprintf("container: %s, exit code: %s", *container.Name, strconv.FormatInt(int64(*container.ExitCode), 10))
-		if container.Reason != nil {
-			msg += ", reason: " + *container.Reason
-		}
-		return fmt.Errorf(msg)
-	} else if container.Reason != nil {
-		return fmt.Errorf("container: %s, reason: %s", *container.Name, *container.Reason)
-	}
-	return nil
+	_, err := d.describeRunResult(ctx, task, watchContainer)
+	return err
 }
 
 func (d *App) DescribeTaskDefinition(ctx context.Context, tdArn string) (*TaskDefinitionInput, error) {
@@ -400,7 +469,16 @@ func (d *App) DescribeTaskDefinition(ctx context.Context, tdArn string) (*TaskDe
 
 func (d *App) GetLogEvents(ctx context.Context, logGroup string, logStream string, startedAt time.Time, nextToken *string) (*string, error) {
 	ms := startedAt.UnixNano() / (int64(time.Millisecond) / int64(time.Nanosecond))
-	out, err := d.cwl.GetLogEvents(ctx, d.GetLogEventsInput(logGroup, logStream, ms, nextToken))
+	return d.printLogEvents(ctx, d.GetLogEventsInput(logGroup, logStream, ms, nextToken), "")
+}
+
+// printLogEvents prints the log events with the prefix (e.g. the container name).
+func (d *App) printLogEvents(ctx context.Context, in *cloudwatchlogs.GetLogEventsInput, prefix string) (*string, error) {
+	nextToken := in.NextToken
+	if err := d.logsLimiter.wait(ctx); err != nil {
+		return nextToken, err
+	}
+	out, err := d.cwl.GetLogEvents(ctx, in)
 	if err != nil {
 		return nextToken, err
 	}
@@ -408,7 +486,7 @@ func (d *App) GetLogEvents(ctx context.Context, logGroup string, logStream strin
 		return nextToken, nil
 	}
 	for _, event := range out.Events {
-		fmt.Println(formatLogEvent(event))
+		fmt.Fprintln(d.logOutput(), d.logPrefix+prefix+formatLogEvent(event))
 	}
 	return out.NextForwardToken, nil
 }
@@ -426,21 +504,72 @@ func containerOf(td *TaskDefinitionInput, name *string) *types.ContainerDefiniti
 	return nil
 }
 
+// watchContainerOf returns the container to watch. An all-digits name is treated as
+// the index of the container definitions.
+func watchContainerOf(td *TaskDefinitionInput, name string) (*types.ContainerDefinition, error) {
+	if len(td.ContainerDefinitions) == 0 {
+		return nil, ErrNotFound("no container definitions in the task definition")
+	}
+	if isContainerIndex(name) {
+		i, err := strconv.Atoi(name)
+		if err != nil || i >= len(td.ContainerDefinitions) {
+			return nil, ErrNotFound(fmt.Sprintf("watch container index %s is out of range. the task definition has %d containers", name, len(td.ContainerDefinitions)))
+		}
+		c := td.ContainerDefinitions[i]
+		return &c, nil
+	}
+	if c := containerOf(td, &name); c != nil {
+		return c, nil
+	}
+	names := lo.Map(td.ContainerDefinitions, func(c types.ContainerDefinition, _ int) string { return aws.ToString(c.Name) })
+	return nil, ErrNotFound(fmt.Sprintf("watch container %s is not found in the task definition. containers: %s", name, strings.Join(names, ", ")))
+}
+
+// isContainerIndex reports whether the watch container name is the index of the container definitions.
+func isContainerIndex(name string) bool {
+	return name != "" && strings.Trim(name, "0123456789") == ""
+}
+
+// findLatestTaskDefinitionArn returns the highest ACTIVE revision of the family.
+// Deregistered (INACTIVE) revisions are skipped.
 func (d *App) findLatestTaskDefinitionArn(ctx context.Context, family string) (string, error) {
-	out, err := d.ecs.ListTaskDefinitions(ctx,
-		&ecs.ListTaskDefinitionsInput{
-			FamilyPrefix: aws.String(family),
-			MaxResults:   aws.Int32(1),
-			Sort:         types.SortOrderDesc,
-		},
-	)
+	p := ecs.NewListTaskDefinitionsPaginator(d.ecs, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Status:       types.TaskDefinitionStatusActive,
+		Sort:         types.SortOrderDesc,
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list taskdefinitions: %w", err)
+		}
+		// FamilyPrefix also matches the other families which have the family as a prefix
+		for _, arn := range out.TaskDefinitionArns {
+			if familyOfTaskDefinitionArn(arn) == family {
+				return arn, nil
+			}
+		}
+	}
+	out, err := d.ecs.ListTaskDefinitions(ctx, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Status:       types.TaskDefinitionStatusInactive,
+		Sort:         types.SortOrderDesc,
+	})
 	if err != nil {
 		return "", fmt.Errorf("failed to list taskdefinitions: %w", err)
 	}
-	if len(out.TaskDefinitionArns) == 0 {
-		return "", ErrNotFound(fmt.Sprintf("no task definitions family %s are found", family))
+	for _, arn := range out.TaskDefinitionArns {
+		if familyOfTaskDefinitionArn(arn) == family {
+			return "", ErrNotFound(fmt.Sprintf("all revisions of task definition family %s are inactive (deregistered)", family))
+		}
 	}
-	return out.TaskDefinitionArns[0], nil
+	return "", ErrNotFound(fmt.Sprintf("no task definitions family %s are found", family))
+}
+
+// familyOfTaskDefinitionArn returns the family of the task definition ARN (or family:revision).
+func familyOfTaskDefinitionArn(arn string) string {
+	family, _, _ := strings.Cut(arnToName(arn), ":")
+	return family
 }
 
 func (d *App) Name() string {
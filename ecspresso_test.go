@@ -2,8 +2,10 @@ package ecspresso_test
 
 import (
 	"context"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/kayac/ecspresso/v2"
 )
 
@@ -76,3 +78,69 @@ func TestLoadTaskDefinitionTags(t *testing.T) {
 		}
 	}
 }
+
+func TestLoadTaskDefinitionTemplateError(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/td-config.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.LoadTaskDefinition("tests/td-template-error.json")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	for _, s := range []string{"at line 6:", `"image":`, "must_env"} {
+		if !strings.Contains(err.Error(), s) {
+			t.Errorf("error must contain %q: %s", s, err)
+		}
+	}
+}
+
+func TestLoadTaskDefinitionTemplateEnvError(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/td-config.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.LoadTaskDefinition("tests/td-template-env-error.json")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if s := "unset environment variables: ECSPRESSO_TEST_UNSET_IMAGE (must_env), ECSPRESSO_TEST_UNSET_TAG (env)"; !strings.Contains(err.Error(), s) {
+		t.Errorf("error must contain %q: %s", s, err)
+	}
+}
+
+func TestWatchContainerOf(t *testing.T) {
+	td := &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: ptr("app")},
+			{Name: ptr("sidecar")},
+		},
+	}
+	for name, expected := range map[string]string{
+		"":        "app",
+		"app":     "app",
+		"sidecar": "sidecar",
+		"0":       "app",
+		"1":       "sidecar",
+	} {
+		c, err := ecspresso.WatchContainerOf(td, name)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+			continue
+		}
+		if *c.Name != expected {
+			t.Errorf("%s: expected %s, got %s", name, expected, *c.Name)
+		}
+	}
+	for name, msg := range map[string]string{
+		"2":     "out of range",
+		"99999": "out of range",
+		"proxy": "containers: app, sidecar",
+	} {
+		if _, err := ecspresso.WatchContainerOf(td, name); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("%s: unexpected error: %v", name, err)
+		}
+	}
+}
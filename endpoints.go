@@ -0,0 +1,158 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// requiredEndpoints returns the VPC endpoint services which the task requires to launch without internet access.
+func requiredEndpoints(td *TaskDefinitionInput, region string) []string {
+	services := map[string]bool{}
+	for _, c := range td.ContainerDefinitions {
+		if ecrImageURLRegex.MatchString(aws.ToString(c.Image)) {
+			services["ecr.api"] = true
+			services["ecr.dkr"] = true
+			services["s3"] = true // image layers are stored in S3
+		}
+		if lc := c.LogConfiguration; lc != nil && lc.LogDriver == types.LogDriverAwslogs {
+			services["logs"] = true
+		}
+		if c.RepositoryCredentials != nil {
+			services["secretsmanager"] = true
+		}
+		for _, s := range c.Secrets {
+			if strings.Contains(aws.ToString(s.ValueFrom), ":secretsmanager:") {
+				services["secretsmanager"] = true
+			} else {
+				services["ssm"] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(services))
+	for s := range services {
+		names = append(names, fmt.Sprintf("com.amazonaws.%s.%s", region, s))
+	}
+	sort.Strings(names)
+	return names
+}
+
+// hasInternetRoute reports whether the route table routes 0.0.0.0/0 to the internet.
+// An internet gateway is usable only when the task has a public IP.
+func hasInternetRoute(rt ec2Types.RouteTable, publicIP bool) bool {
+	for _, r := range rt.Routes {
+		if aws.ToString(r.DestinationCidrBlock) != "0.0.0.0/0" || r.State == ec2Types.RouteStateBlackhole {
+			continue
+		}
+		switch {
+		case r.NatGatewayId != nil, r.TransitGatewayId != nil, r.NetworkInterfaceId != nil:
+			return true
+		case strings.HasPrefix(aws.ToString(r.GatewayId), "igw-") && publicIP:
+			return true
+		}
+	}
+	return false
+}
+
+// checkEndpointsForRun warns when the subnets of the task can reach neither the internet nor the VPC endpoints required to launch the task.
+// Such tasks are likely to fail with ResourceInitializationError or CannotPullContainerError.
+func (d *App) checkEndpointsForRun(ctx context.Context, td *TaskDefinitionInput, opt *RunOption) error {
+	sv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
+	if err != nil {
+		return err
+	}
+	nc, err := networkConfigurationForRun(sv, opt, false)
+	if err != nil {
+		return err
+	}
+	if nc == nil || nc.AwsvpcConfiguration == nil || len(nc.AwsvpcConfiguration.Subnets) == 0 {
+		d.Log("[INFO] no subnets in the network configuration. skip checking endpoints")
+		return nil
+	}
+	vpcConf := nc.AwsvpcConfiguration
+	publicIP := vpcConf.AssignPublicIp == types.AssignPublicIpEnabled
+	client := ec2.NewFromConfig(d.config.awsv2Config)
+
+	subnets, err := client.DescribeSubnets(ctx, &ec2.DescribeSubnetsInput{SubnetIds: vpcConf.Subnets})
+	if err != nil {
+		return fmt.Errorf("failed to describe subnets: %w", err)
+	}
+	var isolated []string
+	vpcs := map[string]bool{}
+	for _, subnet := range subnets.Subnets {
+		subnetID, vpcID := aws.ToString(subnet.SubnetId), aws.ToString(subnet.VpcId)
+		vpcs[vpcID] = true
+		rt, err := routeTableOfSubnet(ctx, client, subnetID, vpcID)
+		if err != nil {
+			return err
+		}
+		if rt != nil && hasInternetRoute(*rt, publicIP) {
+			d.Log("[DEBUG] subnet %s has a route to the internet", subnetID)
+			continue
+		}
+		isolated = append(isolated, subnetID)
+	}
+	if len(isolated) == 0 {
+		d.Log("All subnets have a route to the internet")
+		return nil
+	}
+	d.Log("Subnets %s have no route to the internet. checking VPC endpoints", strings.Join(isolated, ", "))
+
+	vpcIDs := make([]string, 0, len(vpcs))
+	for id := range vpcs {
+		vpcIDs = append(vpcIDs, id)
+	}
+	existing := map[string]bool{}
+	p := ec2.NewDescribeVpcEndpointsPaginator(client, &ec2.DescribeVpcEndpointsInput{
+		Filters: []ec2Types.Filter{{Name: aws.String("vpc-id"), Values: vpcIDs}},
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe VPC endpoints: %w", err)
+		}
+		for _, ep := range out.VpcEndpoints {
+			if ep.State != ec2Types.StateAvailable {
+				continue
+			}
+			existing[aws.ToString(ep.ServiceName)] = true
+		}
+	}
+	var missing []string
+	for _, s := range requiredEndpoints(td, d.config.Region) {
+		if !existing[s] {
+			missing = append(missing, s)
+		}
+	}
+	if len(missing) == 0 {
+		d.Log("All required VPC endpoints exist")
+		return nil
+	}
+	d.Log("[WARNING] VPC endpoints %s are missing. the task is likely to fail with ResourceInitializationError. add a NAT gateway or the VPC endpoints, or assign a public IP in the public subnets", strings.Join(missing, ", "))
+	return nil
+}
+
+// routeTableOfSubnet returns the route table associated with the subnet, or the main route table of the VPC.
+func routeTableOfSubnet(ctx context.Context, client *ec2.Client, subnetID, vpcID string) (*ec2Types.RouteTable, error) {
+	for _, filter := range []ec2Types.Filter{
+		{Name: aws.String("association.subnet-id"), Values: []string{subnetID}},
+		{Name: aws.String("association.main"), Values: []string{"true"}},
+	} {
+		out, err := client.DescribeRouteTables(ctx, &ec2.DescribeRouteTablesInput{
+			Filters: []ec2Types.Filter{filter, {Name: aws.String("vpc-id"), Values: []string{vpcID}}},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe route tables of subnet %s: %w", subnetID, err)
+		}
+		if len(out.RouteTables) > 0 {
+			return &out.RouteTables[0], nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,66 @@
+package ecspresso_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	ec2Types "github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestRequiredEndpoints(t *testing.T) {
+	td := &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{
+				Image: aws.String("123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:latest"),
+				LogConfiguration: &types.LogConfiguration{
+					LogDriver: types.LogDriverAwslogs,
+				},
+				Secrets: []types.Secret{
+					{Name: aws.String("TOKEN"), ValueFrom: aws.String("arn:aws:secretsmanager:ap-northeast-1:123456789012:secret:token")},
+					{Name: aws.String("PASSWORD"), ValueFrom: aws.String("/app/password")},
+				},
+			},
+			{
+				Image: aws.String("debian:bookworm-slim"),
+			},
+		},
+	}
+	expected := []string{
+		"com.amazonaws.ap-northeast-1.ecr.api",
+		"com.amazonaws.ap-northeast-1.ecr.dkr",
+		"com.amazonaws.ap-northeast-1.logs",
+		"com.amazonaws.ap-northeast-1.s3",
+		"com.amazonaws.ap-northeast-1.secretsmanager",
+		"com.amazonaws.ap-northeast-1.ssm",
+	}
+	if diff := cmp.Diff(expected, ecspresso.RequiredEndpoints(td, "ap-northeast-1")); diff != "" {
+		t.Errorf("unexpected endpoints %s", diff)
+	}
+}
+
+func TestHasInternetRoute(t *testing.T) {
+	local := ec2Types.Route{DestinationCidrBlock: aws.String("10.0.0.0/16"), GatewayId: aws.String("local")}
+	igw := ec2Types.RouteTable{Routes: []ec2Types.Route{local, {DestinationCidrBlock: aws.String("0.0.0.0/0"), GatewayId: aws.String("igw-0123")}}}
+	nat := ec2Types.RouteTable{Routes: []ec2Types.Route{local, {DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-0123")}}}
+	blackhole := ec2Types.RouteTable{Routes: []ec2Types.Route{local, {DestinationCidrBlock: aws.String("0.0.0.0/0"), NatGatewayId: aws.String("nat-0123"), State: ec2Types.RouteStateBlackhole}}}
+	isolated := ec2Types.RouteTable{Routes: []ec2Types.Route{local}}
+
+	if !ecspresso.HasInternetRoute(igw, true) {
+		t.Error("internet gateway with public IP must have internet route")
+	}
+	if ecspresso.HasInternetRoute(igw, false) {
+		t.Error("internet gateway without public IP must not have internet route")
+	}
+	if !ecspresso.HasInternetRoute(nat, false) {
+		t.Error("NAT gateway must have internet route")
+	}
+	if ecspresso.HasInternetRoute(blackhole, false) {
+		t.Error("blackhole route must not be internet route")
+	}
+	if ecspresso.HasInternetRoute(isolated, true) {
+		t.Error("isolated route table must not have internet route")
+	}
+}
@@ -1,8 +1,13 @@
 package ecspresso
 
 import (
+	"fmt"
 	"os"
+	"regexp"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/hashicorp/go-envparse"
 )
 
@@ -28,3 +33,135 @@ func ExportEnvFile(file string) error {
 	}
 	return nil
 }
+
+// envFileVar is an environment variable in the envfile for --env-file.
+type envFileVar struct {
+	types.KeyValuePair
+	// container is the name of the container specified by KEY=VALUE@container. empty means the default container.
+	container string
+}
+
+var (
+	envFileKeyRegex       = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	envFileContainerRegex = regexp.MustCompile(`@([A-Za-z0-9_-]+)$`)
+)
+
+// parseEnvFile parses envfile for --env-file.
+// Each line is KEY=VALUE or KEY=VALUE@container. Empty lines and lines starting with # are ignored,
+// and the "export " prefix is allowed. The value may be quoted with single or double quotes.
+// Quote the value which ends with @name (e.g. FOO="user@host") not to be taken as the container.
+func parseEnvFile(file string) ([]envFileVar, error) {
+	b, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var vars []envFileVar
+	for i, line := range strings.Split(string(b), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		v, err := parseEnvFileLine(strings.TrimPrefix(line, "export "))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse envfile %s at line %d: %w", file, i+1, err)
+		}
+		vars = append(vars, v)
+	}
+	return vars, nil
+}
+
+func parseEnvFileLine(line string) (envFileVar, error) {
+	var v envFileVar
+	key, value, ok := strings.Cut(line, "=")
+	if !ok {
+		return v, fmt.Errorf("expected KEY=VALUE: %q", line)
+	}
+	key = strings.TrimSpace(key)
+	if !envFileKeyRegex.MatchString(key) {
+		return v, fmt.Errorf("invalid key %q", key)
+	}
+	value = strings.TrimSpace(value)
+	if value != "" && (value[0] == '"' || value[0] == '\'') {
+		unquoted, rest, err := unquoteEnvValue(value)
+		if err != nil {
+			return v, fmt.Errorf("invalid value of %s: %w", key, err)
+		}
+		rest = strings.TrimSpace(rest)
+		if m := envFileContainerRegex.FindStringSubmatch(rest); m != nil && len(m[0]) == len(rest) {
+			v.container = m[1]
+		} else if rest != "" && !strings.HasPrefix(rest, "#") {
+			return v, fmt.Errorf("unexpected characters after the quoted value of %s: %q", key, rest)
+		}
+		value = unquoted
+	} else {
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = strings.TrimSpace(value[:i]) // inline comment
+		}
+		if m := envFileContainerRegex.FindStringSubmatchIndex(value); m != nil {
+			v.container = value[m[2]:m[3]]
+			value = value[:m[0]]
+		}
+	}
+	v.Name = aws.String(key)
+	v.Value = aws.String(value)
+	return v, nil
+}
+
+// unquoteEnvValue unquotes the value starting with a quote and returns the rest after the closing quote.
+// Escape sequences are interpreted only in double quotes.
+func unquoteEnvValue(s string) (string, string, error) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == quote:
+			return b.String(), s[i+1:], nil
+		case c == '\\' && quote == '"' && i+1 < len(s):
+			i++
+			switch s[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", "", fmt.Errorf("unterminated quote: %s", s)
+}
+
+// applyEnvFiles sets the environment variables in the envfiles to the container overrides.
+// The variables without a container are set to defaultContainer.
+// The envfiles take precedence over the environment in the overrides, and the later envfile wins.
+func (d *App) applyEnvFiles(ov *types.TaskOverride, files []string, defaultContainer string, td *TaskDefinitionInput) error {
+	for _, file := range files {
+		vars, err := parseEnvFile(file)
+		if err != nil {
+			return err
+		}
+		envs := map[string][]types.KeyValuePair{}
+		var containers []string
+		for _, v := range vars {
+			c := v.container
+			if c == "" {
+				c = defaultContainer
+			}
+			if containerOf(td, &c) == nil {
+				return fmt.Errorf("container %s for %s in envfile %s is not found in the task definition", c, aws.ToString(v.Name), file)
+			}
+			if _, ok := envs[c]; !ok {
+				containers = append(containers, c)
+			}
+			envs[c] = append(envs[c], v.KeyValuePair)
+		}
+		for _, c := range containers {
+			d.Log("Setting %d environment variables from %s to container %s", len(envs[c]), file, c)
+			mergeContainerOverride(containerOverrideOf(ov, c), types.ContainerOverride{Environment: envs[c]})
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package ecspresso_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestParseEnvFile(t *testing.T) {
+	vars, err := ecspresso.ParseEnvFile("tests/run.env")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 2 {
+		t.Fatalf("expected 2 variables, got %d", len(vars))
+	}
+
+	_, err = ecspresso.ParseEnvFile("tests/run-broken.env")
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("error should contain the line number: %s", err)
+	}
+}
+
+func TestParseEnvFileLine(t *testing.T) {
+	for _, c := range []struct {
+		line      string
+		key       string
+		value     string
+		container string
+		isErr     bool
+	}{
+		{line: "FOO=foo", key: "FOO", value: "foo"},
+		{line: "FOO=", key: "FOO", value: ""},
+		{line: "FOO=foo@app", key: "FOO", value: "foo", container: "app"},
+		{line: "FOO=foo # comment", key: "FOO", value: "foo"},
+		{line: "FOO=foo@app # comment", key: "FOO", value: "foo", container: "app"},
+		{line: "URL=https://example.com/#anchor", key: "URL", value: "https://example.com/#anchor"},
+		{line: "MAIL=user@example.com", key: "MAIL", value: "user@example.com"},
+		{line: `FOO="bar baz"`, key: "FOO", value: "bar baz"},
+		{line: `FOO="user@host"`, key: "FOO", value: "user@host"},
+		{line: `FOO="bar # baz"@side-car`, key: "FOO", value: "bar # baz", container: "side-car"},
+		{line: `FOO="a\"b\nc"`, key: "FOO", value: "a\"b\nc"},
+		{line: `FOO='a\nb'@app`, key: "FOO", value: `a\nb`, container: "app"},
+		{line: `FOO="unterminated`, isErr: true},
+		{line: `FOO="bar"baz`, isErr: true},
+		{line: "FOO", isErr: true},
+		{line: "1FOO=bar", isErr: true},
+	} {
+		t.Run(c.line, func(t *testing.T) {
+			key, value, container, err := ecspresso.ParseEnvFileLine(c.line)
+			if c.isErr {
+				if err == nil {
+					t.Errorf("expected error, got %s=%s@%s", key, value, container)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if key != c.key || value != c.value || container != c.container {
+				t.Errorf("expected %s=%q@%s, got %s=%q@%s", c.key, c.value, c.container, key, value, container)
+			}
+		})
+	}
+}
+
+func TestApplyEnvFiles(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/command-templates.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td := &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("app")},
+			{Name: aws.String("sidecar")},
+		},
+	}
+	ov := types.TaskOverride{
+		ContainerOverrides: []types.ContainerOverride{
+			{
+				Name:    aws.String("app"),
+				Command: []string{"run"},
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("FOO"), Value: aws.String("from-json")},
+					{Name: aws.String("BAR"), Value: aws.String("from-json")},
+				},
+			},
+		},
+	}
+	if err := app.ApplyEnvFiles(&ov, []string{"tests/run-containers.env"}, "app", td); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := types.TaskOverride{
+		ContainerOverrides: []types.ContainerOverride{
+			{
+				Name:    aws.String("app"),
+				Command: []string{"run"},
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("FOO"), Value: aws.String("from-file")}, // envfile takes precedence
+					{Name: aws.String("BAR"), Value: aws.String("from-json")},
+					{Name: aws.String("DEBUG"), Value: aws.String("true")},
+					{Name: aws.String("EMAIL"), Value: aws.String("user@example")},
+				},
+			},
+			{
+				Name: aws.String("sidecar"),
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("LEVEL"), Value: aws.String("info")},
+					{Name: aws.String("GREETING"), Value: aws.String("hello\nworld")},
+				},
+			},
+		},
+	}
+	opts := cmpopts.IgnoreUnexported(types.TaskOverride{}, types.ContainerOverride{}, types.KeyValuePair{})
+	if diff := cmp.Diff(expected, ov, opts); diff != "" {
+		t.Error(diff)
+	}
+
+	// unknown container
+	if err := app.ApplyEnvFiles(&ov, []string{"tests/run-containers.env"}, "worker", td); err == nil {
+		t.Error("expected error for unknown container, got nil")
+	}
+}
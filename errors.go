@@ -1,5 +1,12 @@
 package ecspresso
 
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
 type ErrSkipVerify string
 
 func (e ErrSkipVerify) Error() string {
@@ -18,6 +25,55 @@ func (e ErrConflictOptions) Error() string {
 	return string(e)
 }
 
+// ErrPlacementFailure represents a failure of RunTask caused by task placement.
+type ErrPlacementFailure struct {
+	Reason               string
+	Detail               string
+	PlacementConstraints []types.PlacementConstraint
+	PlacementStrategy    []types.PlacementStrategy
+}
+
+func (e *ErrPlacementFailure) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "failed to place task: %s %s", e.Reason, e.Detail)
+	if len(e.PlacementConstraints) > 0 {
+		fmt.Fprintf(&b, ", placement constraints: %s", jsonStr(e.PlacementConstraints))
+	}
+	if len(e.PlacementStrategy) > 0 {
+		fmt.Fprintf(&b, ", placement strategy: %s", jsonStr(e.PlacementStrategy))
+	}
+	b.WriteString(". check the attributes and the capacity of the container instances in the cluster")
+	return b.String()
+}
+
+// ErrExitCode represents an error with the exit code of the process.
+type ErrExitCode struct {
+	Code int
+	Err  error
+}
+
+func (e *ErrExitCode) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ErrExitCode) Unwrap() error {
+	return e.Err
+}
+
+// ErrApprovalDenied represents the run is denied by the approval gate.
+type ErrApprovalDenied struct {
+	Gate    string
+	Message string
+}
+
+func (e *ErrApprovalDenied) Error() string {
+	msg := fmt.Sprintf("the run is denied by the approval gate %s", e.Gate)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
 var (
 	errNotFound   = ErrNotFound("not found")
 	errSkipVerify = ErrSkipVerify("skip verify")
@@ -0,0 +1,180 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	eventbridgeTypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+)
+
+// scheduledTask represents the ECS target of an EventBridge rule.
+type scheduledTask struct {
+	rule              string
+	targetID          string
+	clusterArn        string
+	taskDefinitionArn string
+	launchType        string
+	strategy          []types.CapacityProviderStrategyItem
+	network           *types.AwsVpcConfiguration
+	platformVersion   string
+	overrides         types.TaskOverride
+}
+
+// parseScheduledTaskName parses the name in the format rule[/target-id].
+func parseScheduledTaskName(s string) (rule string, targetID string, err error) {
+	rule, targetID, _ = strings.Cut(s, "/")
+	if rule == "" {
+		return "", "", fmt.Errorf("invalid schedule format. rule[/target-id] is required: %s", s)
+	}
+	return rule, targetID, nil
+}
+
+// resolveScheduledTask resolves the task definition and the overrides from the ECS target of the EventBridge rule.
+func (d *App) resolveScheduledTask(ctx context.Context, name string) (*scheduledTask, error) {
+	rule, targetID, err := parseScheduledTaskName(name)
+	if err != nil {
+		return nil, err
+	}
+	client := eventbridge.NewFromConfig(d.config.awsv2Config)
+	var targets []eventbridgeTypes.Target
+	var nextToken *string
+	for {
+		out, err := client.ListTargetsByRule(ctx, &eventbridge.ListTargetsByRuleInput{
+			Rule:      aws.String(rule),
+			NextToken: nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list targets of rule %s: %w", rule, err)
+		}
+		targets = append(targets, out.Targets...)
+		if nextToken = out.NextToken; nextToken == nil {
+			break
+		}
+	}
+	st, err := scheduledTaskOf(rule, targetID, targets)
+	if err != nil {
+		return nil, err
+	}
+	d.Log("Resolved the scheduled task of rule %s target %s", st.rule, st.targetID)
+	d.Log("  task definition: %s", st.taskDefinitionArn)
+	d.Log("  cluster: %s", st.clusterArn)
+	if st.launchType != "" {
+		d.Log("  launch type: %s", st.launchType)
+	}
+	for _, item := range st.strategy {
+		d.Log("  capacity provider: %s (base %d, weight %d)", aws.ToString(item.CapacityProvider), item.Base, item.Weight)
+	}
+	if st.network != nil {
+		d.Log("  subnets: %s", strings.Join(st.network.Subnets, ","))
+		d.Log("  security groups: %s", strings.Join(st.network.SecurityGroups, ","))
+	}
+	if st.platformVersion != "" {
+		d.Log("  platform version: %s", st.platformVersion)
+	}
+	return st, nil
+}
+
+// applyScheduledTask sets the cluster, the launch settings, the network configuration and the platform version
+// of the scheduled task to opt, unless they are specified by the options.
+func (d *App) applyScheduledTask(st *scheduledTask, opt *RunOption) error {
+	if c := aws.ToString(opt.Cluster); c != "" {
+		if arnToName(c) != arnToName(st.clusterArn) {
+			d.Log("[WARNING] the scheduled task targets cluster %s, but --cluster %s is specified", arnToName(st.clusterArn), c)
+		}
+	} else if st.clusterArn != "" && arnToName(st.clusterArn) != arnToName(d.Cluster) {
+		opt.Cluster = aws.String(st.clusterArn)
+	}
+
+	if opt.LaunchType == "" && opt.CapacityProviderStrategy == "" && len(opt.CapacityProviderCascade) == 0 {
+		switch {
+		case len(st.strategy) > 0:
+			b, err := json.Marshal(st.strategy)
+			if err != nil {
+				return fmt.Errorf("failed to marshal the capacity provider strategy of the scheduled task: %w", err)
+			}
+			opt.CapacityProviderStrategy = string(b)
+		case st.launchType != "":
+			opt.LaunchType = st.launchType
+		}
+	}
+
+	if vpc := st.network; vpc != nil {
+		if len(opt.Subnets) == 0 {
+			opt.Subnets = vpc.Subnets
+		}
+		if len(opt.SecurityGroups) == 0 {
+			opt.SecurityGroups = vpc.SecurityGroups
+		}
+		if opt.AssignPublicIp == "" {
+			opt.AssignPublicIp = string(vpc.AssignPublicIp)
+		}
+	}
+
+	if opt.PlatformVersion == "" {
+		opt.PlatformVersion = st.platformVersion
+	}
+	return nil
+}
+
+// scheduledTaskOf finds the ECS target in targets. An empty targetID means the only ECS target of the rule.
+func scheduledTaskOf(rule string, targetID string, targets []eventbridgeTypes.Target) (*scheduledTask, error) {
+	var found []eventbridgeTypes.Target
+	for _, t := range targets {
+		if t.EcsParameters == nil {
+			continue
+		}
+		if targetID != "" && aws.ToString(t.Id) != targetID {
+			continue
+		}
+		found = append(found, t)
+	}
+	switch {
+	case len(found) == 0 && targetID != "":
+		return nil, ErrNotFound(fmt.Sprintf("ECS target %s is not found in rule %s", targetID, rule))
+	case len(found) == 0:
+		return nil, ErrNotFound(fmt.Sprintf("no ECS targets are found in rule %s", rule))
+	case len(found) > 1:
+		ids := make([]string, 0, len(found))
+		for _, t := range found {
+			ids = append(ids, aws.ToString(t.Id))
+		}
+		return nil, fmt.Errorf("rule %s has multiple ECS targets (%s). specify the target as %s/target-id", rule, strings.Join(ids, ", "), rule)
+	}
+	t := found[0]
+	st := &scheduledTask{
+		rule:              rule,
+		targetID:          aws.ToString(t.Id),
+		clusterArn:        aws.ToString(t.Arn),
+		taskDefinitionArn: aws.ToString(t.EcsParameters.TaskDefinitionArn),
+		launchType:        string(t.EcsParameters.LaunchType),
+		platformVersion:   aws.ToString(t.EcsParameters.PlatformVersion),
+	}
+	for _, item := range t.EcsParameters.CapacityProviderStrategy {
+		st.strategy = append(st.strategy, types.CapacityProviderStrategyItem{
+			CapacityProvider: item.CapacityProvider,
+			Base:             item.Base,
+			Weight:           item.Weight,
+		})
+	}
+	if nc := t.EcsParameters.NetworkConfiguration; nc != nil && nc.AwsvpcConfiguration != nil {
+		st.network = &types.AwsVpcConfiguration{
+			Subnets:        nc.AwsvpcConfiguration.Subnets,
+			SecurityGroups: nc.AwsvpcConfiguration.SecurityGroups,
+			AssignPublicIp: types.AssignPublicIp(nc.AwsvpcConfiguration.AssignPublicIp),
+		}
+	}
+	if t.InputPath != nil || t.InputTransformer != nil {
+		return nil, fmt.Errorf("target %s of rule %s builds its input from the event. the overrides can not be resolved", st.targetID, rule)
+	}
+	if input := aws.ToString(t.Input); input != "" {
+		if err := json.Unmarshal([]byte(input), &st.overrides); err != nil {
+			return nil, fmt.Errorf("failed to parse the input of target %s of rule %s as overrides: %w", st.targetID, rule, err)
+		}
+	}
+	return st, nil
+}
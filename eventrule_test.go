@@ -0,0 +1,138 @@
+package ecspresso_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	eventbridgeTypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+var testRuleTargets = []eventbridgeTypes.Target{
+	{
+		Id:  aws.String("notify"),
+		Arn: aws.String("arn:aws:sns:ap-northeast-1:123456789012:notify"),
+	},
+	{
+		Id:  aws.String("batch"),
+		Arn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/default"),
+		EcsParameters: &eventbridgeTypes.EcsParameters{
+			TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/batch:3"),
+		},
+		Input: aws.String(`{"containerOverrides":[{"name":"app","command":["run","--all"],"environment":[{"name":"MODE","value":"nightly"}]}]}`),
+	},
+}
+
+func TestScheduledTaskOf(t *testing.T) {
+	id, tdArn, ov, err := ecspresso.ScheduledTaskOf("nightly", "", testRuleTargets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "batch" {
+		t.Errorf("unexpected target id %s", id)
+	}
+	if tdArn != "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/batch:3" {
+		t.Errorf("unexpected task definition %s", tdArn)
+	}
+	if len(ov.ContainerOverrides) != 1 {
+		t.Fatalf("unexpected container overrides %#v", ov.ContainerOverrides)
+	}
+	c := ov.ContainerOverrides[0]
+	if aws.ToString(c.Name) != "app" || len(c.Command) != 2 || c.Command[1] != "--all" {
+		t.Errorf("unexpected container override %#v", c)
+	}
+	if len(c.Environment) != 1 || aws.ToString(c.Environment[0].Value) != "nightly" {
+		t.Errorf("unexpected environment %#v", c.Environment)
+	}
+}
+
+func TestScheduledTaskOfErrors(t *testing.T) {
+	if _, _, _, err := ecspresso.ScheduledTaskOf("nightly", "notify", testRuleTargets); err == nil {
+		t.Error("non-ECS target must be an error")
+	}
+	if _, _, _, err := ecspresso.ScheduledTaskOf("nightly", "", testRuleTargets[:1]); err == nil {
+		t.Error("rule without ECS targets must be an error")
+	}
+	multi := append(append([]eventbridgeTypes.Target{}, testRuleTargets...), eventbridgeTypes.Target{
+		Id:            aws.String("batch2"),
+		EcsParameters: &eventbridgeTypes.EcsParameters{},
+	})
+	if _, _, _, err := ecspresso.ScheduledTaskOf("nightly", "", multi); err == nil {
+		t.Error("multiple ECS targets without target id must be an error")
+	}
+	if id, _, _, err := ecspresso.ScheduledTaskOf("nightly", "batch2", multi); err != nil || id != "batch2" {
+		t.Errorf("unexpected result %s %v", id, err)
+	}
+	transformed := []eventbridgeTypes.Target{{
+		Id:               aws.String("batch"),
+		EcsParameters:    &eventbridgeTypes.EcsParameters{},
+		InputTransformer: &eventbridgeTypes.InputTransformer{InputTemplate: aws.String(`{}`)},
+	}}
+	if _, _, _, err := ecspresso.ScheduledTaskOf("nightly", "", transformed); err == nil {
+		t.Error("target with input transformer must be an error")
+	}
+}
+
+func TestApplyScheduledTask(t *testing.T) {
+	targets := []eventbridgeTypes.Target{{
+		Id:  aws.String("batch"),
+		Arn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/batch"),
+		EcsParameters: &eventbridgeTypes.EcsParameters{
+			TaskDefinitionArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/batch:3"),
+			CapacityProviderStrategy: []eventbridgeTypes.CapacityProviderStrategyItem{
+				{CapacityProvider: aws.String("FARGATE_SPOT"), Weight: 1},
+			},
+			NetworkConfiguration: &eventbridgeTypes.NetworkConfiguration{
+				AwsvpcConfiguration: &eventbridgeTypes.AwsVpcConfiguration{
+					Subnets:        []string{"subnet-1", "subnet-2"},
+					SecurityGroups: []string{"sg-1"},
+					AssignPublicIp: eventbridgeTypes.AssignPublicIpDisabled,
+				},
+			},
+			PlatformVersion: aws.String("1.4.0"),
+		},
+	}}
+	app := newRunTestApp(t)
+
+	opt := ecspresso.RunOption{}
+	if err := app.ApplyScheduledTask("nightly", "", targets, &opt); err != nil {
+		t.Fatal(err)
+	}
+	expected := ecspresso.RunOption{
+		Cluster:                  aws.String("arn:aws:ecs:ap-northeast-1:123456789012:cluster/batch"),
+		CapacityProviderStrategy: `[{"CapacityProvider":"FARGATE_SPOT","Base":0,"Weight":1}]`,
+		Subnets:                  []string{"subnet-1", "subnet-2"},
+		SecurityGroups:           []string{"sg-1"},
+		AssignPublicIp:           "DISABLED",
+		PlatformVersion:          "1.4.0",
+	}
+	if diff := cmp.Diff(expected, opt); diff != "" {
+		t.Error(diff)
+	}
+	if _, strategy, err := ecspresso.LaunchSettingsForRun(&ecspresso.Service{}, &opt); err != nil || aws.ToString(strategy[0].CapacityProvider) != "FARGATE_SPOT" {
+		t.Errorf("unexpected capacity provider strategy %#v %v", strategy, err)
+	}
+
+	// the options take precedence over the scheduled task
+	opt = ecspresso.RunOption{
+		Cluster:         aws.String("maintenance"),
+		LaunchType:      "FARGATE",
+		Subnets:         []string{"subnet-3"},
+		PlatformVersion: "LATEST",
+	}
+	if err := app.ApplyScheduledTask("nightly", "", targets, &opt); err != nil {
+		t.Fatal(err)
+	}
+	expected = ecspresso.RunOption{
+		Cluster:         aws.String("maintenance"),
+		LaunchType:      "FARGATE",
+		Subnets:         []string{"subnet-3"},
+		SecurityGroups:  []string{"sg-1"},
+		AssignPublicIp:  "DISABLED",
+		PlatformVersion: "LATEST",
+	}
+	if diff := cmp.Diff(expected, opt); diff != "" {
+		t.Error(diff)
+	}
+}
@@ -0,0 +1,55 @@
+package ecspresso
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// expectedDurationKey is the docker label or the tag of the task definition which encodes the expected runtime of the task.
+const expectedDurationKey = "ecspresso.expected-duration"
+
+// expectedDuration returns the expected runtime of the task from the docker label of the watch container or the tag of the task definition.
+// The docker label takes precedence. It returns 0 if neither exists.
+func expectedDuration(td *TaskDefinitionInput, watchContainer *types.ContainerDefinition) (time.Duration, string, error) {
+	value, source := "", ""
+	if v, ok := watchContainer.DockerLabels[expectedDurationKey]; ok {
+		value, source = v, fmt.Sprintf("docker label of container %s", aws.ToString(watchContainer.Name))
+	} else {
+		for _, t := range td.Tags {
+			if aws.ToString(t.Key) == expectedDurationKey {
+				value, source = aws.ToString(t.Value), "tag of the task definition"
+				break
+			}
+		}
+	}
+	if source == "" {
+		return 0, "", nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid %s %q in the %s: %w", expectedDurationKey, value, source, err)
+	}
+	if d <= 0 {
+		return 0, "", fmt.Errorf("invalid %s %q in the %s: must be positive", expectedDurationKey, value, source)
+	}
+	return d, source, nil
+}
+
+// waitTimeout returns the timeout for waiting for the task.
+func (d *App) waitTimeout() time.Duration {
+	if d.waitTimeoutOverride > 0 {
+		return d.waitTimeoutOverride
+	}
+	return d.Timeout()
+}
+
+// withWaitTimeout returns a copy of the App which waits for the task up to the timeout instead of the timeout of the config.
+// The timeout of the config for the other operations (e.g. deploy) is not changed.
+func (d *App) withWaitTimeout(timeout time.Duration) *App {
+	nd := *d
+	nd.waitTimeoutOverride = timeout
+	return &nd
+}
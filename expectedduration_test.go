@@ -0,0 +1,94 @@
+package ecspresso_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestExpectedDuration(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		labels   map[string]string
+		tags     []types.Tag
+		expected time.Duration
+		isErr    bool
+	}{
+		{
+			name: "no hint",
+		},
+		{
+			name:     "docker label",
+			labels:   map[string]string{"ecspresso.expected-duration": "30m"},
+			expected: 30 * time.Minute,
+		},
+		{
+			name:     "tag",
+			tags:     []types.Tag{{Key: aws.String("ecspresso.expected-duration"), Value: aws.String("1h30m")}},
+			expected: 90 * time.Minute,
+		},
+		{
+			name:     "docker label takes precedence",
+			labels:   map[string]string{"ecspresso.expected-duration": "10m"},
+			tags:     []types.Tag{{Key: aws.String("ecspresso.expected-duration"), Value: aws.String("1h")}},
+			expected: 10 * time.Minute,
+		},
+		{
+			name:   "invalid",
+			labels: map[string]string{"ecspresso.expected-duration": "30"},
+			isErr:  true,
+		},
+		{
+			name:  "not positive",
+			tags:  []types.Tag{{Key: aws.String("ecspresso.expected-duration"), Value: aws.String("0s")}},
+			isErr: true,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			container := types.ContainerDefinition{Name: aws.String("app"), DockerLabels: c.labels}
+			td := &ecspresso.TaskDefinitionInput{
+				ContainerDefinitions: []types.ContainerDefinition{container},
+				Tags:                 c.tags,
+			}
+			d, _, err := ecspresso.ExpectedDuration(td, &container)
+			if c.isErr {
+				if err == nil {
+					t.Error("expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d != c.expected {
+				t.Errorf("expected %s, got %s", c.expected, d)
+			}
+		})
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/run-with-sv.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// timeout: 300s in the config
+	if got := app.WaitTimeout(); got != 5*time.Minute {
+		t.Errorf("expected the timeout of the config, got %s", got)
+	}
+	wapp := app.WithWaitTimeout(3 * time.Hour)
+	if got := wapp.WaitTimeout(); got != 3*time.Hour {
+		t.Errorf("expected the overridden timeout, got %s", got)
+	}
+	if got := wapp.Timeout(); got != 5*time.Minute {
+		t.Errorf("the timeout of the config should not be changed, got %s", got)
+	}
+	if got := app.WaitTimeout(); got != 5*time.Minute {
+		t.Errorf("the original app should not be changed, got %s", got)
+	}
+}
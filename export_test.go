@@ -2,34 +2,60 @@ package ecspresso
 
 import (
 	"context"
+	"io"
 	"log"
+	"strings"
+	"time"
 
+	"github.com/Masterminds/semver/v3"
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	eventbridgeTypes "github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
 )
 
 var (
-	SortTaskDefinition = sortTaskDefinition
-	ToNumberCPU        = toNumberCPU
-	ToNumberMemory     = toNumberMemory
-	CalcDesiredCount   = calcDesiredCount
-	ParseTags          = parseTags
-	ExtractRoleName    = extractRoleName
-	IsLongArnFormat    = isLongArnFormat
-	ECRImageURLRegex   = ecrImageURLRegex
-	NewLogger          = newLogger
-	NewLogFilter       = newLogFilter
-	NewConfigLoader    = newConfigLoader
-	NewVerifier        = newVerifier
-	ArnToName          = arnToName
-	InitVerifyState    = initVerifyState
-	VerifyResource     = verifyResource
-	Map2str            = map2str
-	DiffServices       = diffServices
-	DiffTaskDefs       = diffTaskDefs
+	SortTaskDefinition    = sortTaskDefinition
+	ToNumberCPU           = toNumberCPU
+	ToNumberMemory        = toNumberMemory
+	CalcDesiredCount      = calcDesiredCount
+	ParseTags             = parseTags
+	ExtractRoleName       = extractRoleName
+	IsLongArnFormat       = isLongArnFormat
+	ECRImageURLRegex      = ecrImageURLRegex
+	NewLogger             = newLogger
+	NewLogFilter          = newLogFilter
+	NewConfigLoader       = newConfigLoader
+	NewVerifier           = newVerifier
+	ArnToName             = arnToName
+	InitVerifyState       = initVerifyState
+	VerifyResource        = verifyResource
+	Map2str               = map2str
+	DiffServices          = diffServices
+	DiffTaskDefs          = diffTaskDefs
+	IsFailedTask          = isFailedTask
+	MergeTags             = mergeTags
+	IsPlacementFailure    = isPlacementFailure
+	RelaxJSON             = relaxJSON
+	ValidateTaskResources = validateTaskResources
+	ValidateCluster       = validateCluster
+	ValidateLogging       = validateLogging
+	ParseEnvFile          = parseEnvFile
+	DiffGoldenLog         = diffGoldenLog
+	ValidateJSONSchema    = validateJSONSchema
+	CompileLogNormalizers = compileLogNormalizers
+	MergeTaskOverride     = mergeTaskOverride
 )
 
 type ModifyAutoScalingParams = modifyAutoScalingParams
 
+func ParseUlimit(s string) (string, types.Ulimit, error) {
+	u, err := parseUlimit(s)
+	return u.container, u.ulimit, err
+}
+
 func (d *App) SetLogger(logger *log.Logger) {
 	d.logger = logger
 }
@@ -49,3 +75,376 @@ func ResetAWSV2ConfigLoadOptionsFunc() {
 func (d *App) TaskDefinitionArnForRun(ctx context.Context, opt RunOption) (string, error) {
 	return d.taskDefinitionArnForRun(ctx, opt)
 }
+
+func (c *Config) ExitCodeSeverity(code int32) (string, *int) {
+	return c.exitCodeSeverity(code)
+}
+
+func ParseDockerLabel(s string) (string, string, string, error) {
+	l, err := parseDockerLabel(s)
+	return l.container, l.key, l.value, err
+}
+
+func (d *App) CheckClusterTasksForRun(ctx context.Context, max int, count int32) error {
+	return d.checkClusterTasksForRun(ctx, max, count)
+}
+
+func ParsePropagateTags(s string) (string, error) {
+	p, err := parsePropagateTags(s)
+	return p.String(), err
+}
+
+func RunTimingsForTest(submitted time.Time, taskStartedAt *time.Time) []RunPhaseTiming {
+	t := &runTimings{}
+	t.addDuration(phaseRegister, time.Second)
+	t.addDuration(phaseRegister, 2*time.Second)
+	t.addWait(submitted, taskStartedAt)
+	return t.phases
+}
+
+var (
+	ScheduleName             = scheduleName
+	ToSchedulerEcsParameters = toSchedulerEcsParameters
+)
+
+func (d *App) ApplyCommandTemplates(ov *types.TaskOverride, params map[string]string) error {
+	return d.applyCommandTemplates(ov, params)
+}
+
+func NewPacer(logf func(string, ...interface{})) func(context.Context, float64) error {
+	p := &pacer{}
+	return func(ctx context.Context, rate float64) error {
+		return p.wait(ctx, rate, logf)
+	}
+}
+
+var IsThrottlingError = isThrottlingError
+
+func ScheduledTaskOf(rule, targetID string, targets []eventbridgeTypes.Target) (string, string, *types.TaskOverride, error) {
+	st, err := scheduledTaskOf(rule, targetID, targets)
+	if err != nil {
+		return "", "", nil, err
+	}
+	return st.targetID, st.taskDefinitionArn, &st.overrides, nil
+}
+
+func (d *App) ApplyScheduledTask(rule, targetID string, targets []eventbridgeTypes.Target, opt *RunOption) error {
+	st, err := scheduledTaskOf(rule, targetID, targets)
+	if err != nil {
+		return err
+	}
+	return d.applyScheduledTask(st, opt)
+}
+
+func TaskLifecycleEventNames(t types.Task) []string {
+	var names []string
+	for _, ev := range taskLifecycleEvents(t) {
+		names = append(names, ev.name)
+	}
+	return names
+}
+
+var ValidateFIPSRegion = validateFIPSRegion
+
+func (d *App) TaskOverrideOfTask(ctx context.Context, taskID string) (types.TaskOverride, error) {
+	return d.taskOverrideOfTask(ctx, taskID)
+}
+
+var (
+	RequiredEndpoints = requiredEndpoints
+	HasInternetRoute  = hasInternetRoute
+)
+
+var IsTransientTaskFailure = isTransientTaskFailure
+
+func RenderRunResult(text string, result *RunResult) (string, error) {
+	tmpl, err := parseResultTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	err = writeRunResult(&b, tmpl, result)
+	return b.String(), err
+}
+
+func RunStatusJSON(result *RunResult) (string, error) {
+	var b strings.Builder
+	err := writeRunStatusJSON(&b, result)
+	return b.String(), err
+}
+
+var (
+	RequestApproval = requestApproval
+	CommandApproval = commandApproval
+)
+
+// FilteredEventsForTest returns the IDs of the events printed in each poll of FilterLogEvents.
+func FilteredEventsForTest(polls ...[]logsTypes.FilteredLogEvent) [][]string {
+	s := &tailStream{}
+	var res [][]string
+	for _, events := range polls {
+		var ids []string
+		for _, e := range newFilteredEvents(s, events) {
+			ids = append(ids, *e.EventId)
+		}
+		res = append(res, ids)
+	}
+	return res
+}
+
+func SetEntryPoint(td *TaskDefinitionInput, name string, entryPoint []string) error {
+	return (&App{logger: newLogger()}).setEntryPoint(name, entryPoint)(td)
+}
+
+var (
+	FargateHourlyCost    = fargateHourlyCost
+	ValidateMaxResources = validateMaxResources
+)
+
+func PollBackoffDelays(exponential bool, base, max time.Duration, attempts int) []time.Duration {
+	b := pollBackoff{exponential: exponential, base: base, max: max}
+	ds := make([]time.Duration, 0, attempts)
+	for i := 0; i < attempts; i++ {
+		ds = append(ds, b.delay(i))
+	}
+	return ds
+}
+
+func PollBackoffMaxPolls(exponential bool, base, max, timeout time.Duration) int {
+	return pollBackoff{exponential: exponential, base: base, max: max}.maxPolls(timeout)
+}
+
+func PollBackoffJitter(base time.Duration) time.Duration {
+	return pollBackoff{exponential: true, base: base}.jitter(base)
+}
+
+var (
+	RunMetricsOf        = runMetricsOf
+	TaskMetricDataInput = taskMetricDataInput
+)
+
+var ExpectedDuration = expectedDuration
+
+func ParseEnvFileLine(line string) (string, string, string, error) {
+	v, err := parseEnvFileLine(line)
+	return aws.ToString(v.Name), aws.ToString(v.Value), v.container, err
+}
+
+func (d *App) ApplyEnvFiles(ov *types.TaskOverride, files []string, defaultContainer string, td *TaskDefinitionInput) error {
+	return d.applyEnvFiles(ov, files, defaultContainer, td)
+}
+
+var ParseImageOverride = parseImageOverride
+
+func (d *App) RegisterTransientTaskDefinition(ctx context.Context, td *TaskDefinitionInput, opt RunOption) (string, *TaskDefinitionInput, error) {
+	mods, err := d.transientModifiers(opt)
+	if err != nil {
+		return "", nil, err
+	}
+	arn, transientTd, _, err := d.registerTransientTaskDefinition(ctx, td, mods)
+	return arn, transientTd, err
+}
+
+func SetImage(td *TaskDefinitionInput, name string, image string) error {
+	return (&App{logger: newLogger()}).setImage(name, image)(td)
+}
+
+func NewLogFanout(names []LogSink, sinks []io.WriteCloser, logf func(string, ...interface{})) io.WriteCloser {
+	return newLogFanout(names, sinks, logf)
+}
+
+func NewWebhookLogSink(u string, batchSize int) io.WriteCloser {
+	return newWebhookLogSink(u, batchSize)
+}
+
+func (d *App) OpenLogSink(sink LogSink) (io.WriteCloser, error) {
+	return d.openLogSink(sink)
+}
+
+func RunResultError(exitCode *int32, processExitCode *int, statusErr error, propagate bool) error {
+	return runResultError(&RunResult{ExitCode: exitCode, processExitCode: processExitCode}, statusErr, propagate)
+}
+
+// SelectSemverRevision returns the ARN of the selected revision. versions is a map of ARN to the version.
+func SelectSemverRevision(versions map[string]string, constraint string) (string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", err
+	}
+	var revs []semverRevision
+	for arn, v := range versions {
+		revs = append(revs, semverRevision{arn: arn, version: semver.MustParse(v)})
+	}
+	r, _ := selectSemverRevision(revs, c)
+	return r.arn, nil
+}
+
+var WriteTaskArnFile = writeTaskArnFile
+
+func LogContainerNames(td *TaskDefinitionInput, watchContainer string, all bool) []string {
+	var names []string
+	for _, c := range logContainers(td, containerOf(td, &watchContainer), all) {
+		names = append(names, aws.ToString(c.Name))
+	}
+	return names
+}
+
+func (d *App) TaskOverrideForRun(opt RunOption) (types.TaskOverride, error) {
+	return d.taskOverrideForRun(context.Background(), opt)
+}
+
+var (
+	ValidateRunID = validateRunID
+	SetRunIDEnv   = setRunIDEnv
+)
+
+func (d *App) WithRunID(id string) *App {
+	return d.withRunID(id)
+}
+
+func (d *App) SetLogWriter(w io.Writer) {
+	d.logWriter = w
+}
+
+func (d *App) LogPollError(ctx context.Context, stream string, errs ...error) {
+	s := &tailStream{group: "/ecs/test", stream: stream}
+	for _, err := range errs {
+		d.logPollError(ctx, s, err)
+	}
+}
+
+func (d *App) RunTaskInput(ctx context.Context, opt RunOption) (*ecs.RunTaskInput, error) {
+	return d.RunTaskInputWithTaskDefinition(ctx, "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:39", opt)
+}
+
+func (d *App) RunTaskInputWithTaskDefinition(ctx context.Context, tdArn string, opt RunOption) (*ecs.RunTaskInput, error) {
+	return d.runTaskInput(ctx, tdArn, &types.TaskOverride{}, &opt)
+}
+
+func (d *App) WithRunCluster(cluster string) *App {
+	return d.withRunCluster(cluster)
+}
+
+var LaunchSettingsForRun = launchSettingsForRun
+
+var NetworkConfigurationForRun = networkConfigurationForRun
+
+func (d *App) StartedByForRun(opt RunOption) (string, error) {
+	return d.startedByForRun(&opt)
+}
+
+var TagsToString = tagsToString
+
+var ValidateContainerOverrides = validateContainerOverrides
+
+func (d *App) WaitTimeout() time.Duration {
+	return d.waitTimeout()
+}
+
+func (d *App) WithWaitTimeout(timeout time.Duration) *App {
+	return d.withWaitTimeout(timeout)
+}
+
+var LogsConsoleURL = logsConsoleURL
+
+var IsRetryableRunTaskFailure = isRetryableRunTaskFailure
+
+func (d *App) SubmitRunTaskWithRetry(ctx context.Context, maxRetries int) error {
+	defer func(min, max time.Duration) {
+		runTaskRetryMinDelay, runTaskRetryMaxDelay = min, max
+	}(runTaskRetryMinDelay, runTaskRetryMaxDelay)
+	runTaskRetryMinDelay, runTaskRetryMaxDelay = time.Millisecond, time.Millisecond
+	_, err := d.submitRunTaskWithRetry(ctx, &ecs.RunTaskInput{TaskDefinition: aws.String("katsubushi:39")}, 0, maxRetries)
+	return err
+}
+
+func (d *App) AggregateRunResults(ctx context.Context, taskArns []string, watchContainer string, statusErr error) error {
+	tasks := make([]types.Task, 0, len(taskArns))
+	for _, arn := range taskArns {
+		tasks = append(tasks, types.Task{TaskArn: aws.String(arn)})
+	}
+	return d.aggregateRunResults(ctx, tasks, &types.ContainerDefinition{Name: aws.String(watchContainer)}, statusErr)
+}
+
+func (d *App) DryRunTaskInput(ctx context.Context, tdArn string, opt RunOption, registers bool) (*ecs.RunTaskInput, error) {
+	return d.dryRunTaskInput(ctx, tdArn, types.TaskOverride{}, opt, registers)
+}
+
+func (d *App) FindLatestTaskDefinitionArn(ctx context.Context, family string) (string, error) {
+	return d.findLatestTaskDefinitionArn(ctx, family)
+}
+
+var FamilyOfTaskDefinitionArn = familyOfTaskDefinitionArn
+
+var WatchContainerOf = watchContainerOf
+
+var NewJSONLogFilter = newJSONLogFilter
+
+func (d *App) WithLogTaskArn(arn string) *App {
+	return d.withLogTaskArn(arn)
+}
+
+func (d *App) WaitLogStreams(ctx context.Context, taskArn string, wait time.Duration) {
+	s := &tailStream{group: "/ecs/test", stream: "ecs/app/0001"}
+	d.waitLogStreams(ctx, &types.Task{TaskArn: aws.String(taskArn)}, []*tailStream{s}, wait)
+}
+
+func (d *App) StopTasksOnCancel(ctx context.Context, taskArns ...string) {
+	tasks := make([]types.Task, 0, len(taskArns))
+	for _, arn := range taskArns {
+		tasks = append(tasks, types.Task{TaskArn: aws.String(arn)})
+	}
+	d.stopTasksOnCancel(ctx, tasks)
+}
+
+var ValidateTags = validateTags
+
+var SetEphemeralStorage = setEphemeralStorage
+
+func (d *App) ValidateResourceOverridesForRun(td *TaskDefinitionInput, opt RunOption) error {
+	return d.validateResourceOverridesForRun(td, &types.TaskOverride{Cpu: aws.String(opt.Cpu), Memory: aws.String(opt.Memory)}, &opt)
+}
+
+func (d *App) ReportTaskProgress(ctx context.Context, taskArn string, startedAt time.Time, interval time.Duration) {
+	d.reportTaskProgress(ctx, &types.Task{TaskArn: aws.String(taskArn)}, startedAt, interval)
+}
+
+// ListFamilyTasks returns the listed tasks in the format.
+func (d *App) ListFamilyTasks(ctx context.Context, family string, opt TasksOption) (string, error) {
+	tasks, err := d.listFamilyTasks(ctx, family, opt)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	err = tasks.output(&b, opt.Output)
+	return b.String(), err
+}
+
+func (d *App) WaitRunTaskWithOption(ctx context.Context, task *types.Task, watchContainer *types.ContainerDefinition, opt RunOption) error {
+	return d.waitRunTask(ctx, []types.Task{*task}, []*types.ContainerDefinition{watchContainer}, time.Now(), opt)
+}
+
+func (d *App) LogStoppedDetails(result *RunResult) {
+	d.logStoppedDetails(result)
+}
+
+func NewTokenBucket(rate float64, burst int) func(context.Context) error {
+	return newTokenBucket(rate, burst).wait
+}
+
+var NextLogPollInterval = nextLogPollInterval
+
+func (d *App) FindLastGoodTaskDefinitionArn(ctx context.Context, family string, opt RunOption) (string, error) {
+	return d.findLastGoodTaskDefinitionArn(ctx, family, opt)
+}
+
+func (d *App) WatchCost(ctx context.Context, tasks []types.Task, hourly, maxCost float64, interval time.Duration) {
+	orig := costReportInterval
+	costReportInterval = interval
+	defer func() { costReportInterval = orig }()
+	d.watchCost(ctx, tasks, hourly, maxCost)
+}
+
+func (d *App) HourlyCostForRun(td *TaskDefinitionInput, opt RunOption) (float64, error) {
+	return d.hourlyCostForRun(td, &types.TaskOverride{}, &opt)
+}
@@ -0,0 +1,197 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/olekukonko/tablewriter"
+	"github.com/samber/lo"
+)
+
+// RunFailure represents a summary of a stopped task that has failed.
+type RunFailure struct {
+	TaskID         string    `json:"task_id"`
+	TaskDefinition string    `json:"task_definition"`
+	StoppedAt      time.Time `json:"stopped_at"`
+	StopCode       string    `json:"stop_code"`
+	StoppedReason  string    `json:"stopped_reason"`
+	ExitCodes      string    `json:"exit_codes"`
+}
+
+func (f RunFailure) Cols() []string {
+	return []string{
+		f.TaskID,
+		f.TaskDefinition,
+		f.StoppedAt.In(time.Local).Format(EventTimeFormat),
+		f.StopCode,
+		f.StoppedReason,
+		f.ExitCodes,
+	}
+}
+
+type RunFailures []RunFailure
+
+func (fs RunFailures) Header() []string {
+	return []string{"Task ID", "Task Definition", "Stopped At", "Stop Code", "Stopped Reason", "Exit Codes"}
+}
+
+func (fs RunFailures) OutputTable(w io.Writer) error {
+	t := tablewriter.NewWriter(w)
+	t.SetHeader(fs.Header())
+	t.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+	for _, f := range fs {
+		t.Append(f.Cols())
+	}
+	t.Render()
+	return nil
+}
+
+// RecentRunFailures returns the last n failed tasks stopped in the family.
+func (d *App) RecentRunFailures(ctx context.Context, family string, n int) (RunFailures, error) {
+	tasks, err := d.stoppedTasks(ctx, family, nil)
+	if err != nil {
+		return nil, err
+	}
+	fs := RunFailures{}
+	for _, task := range tasks {
+		if len(fs) >= n {
+			break
+		}
+		if !isFailedTask(task) {
+			continue
+		}
+		fs = append(fs, newRunFailure(task))
+	}
+	return fs, nil
+}
+
+// stoppedTasks returns the stopped tasks in the family sorted by stoppedAt in descending order.
+// A non-nil startedBy filters the tasks after describing them,
+// because ListTasks API does not accept startedBy with the other filters.
+func (d *App) stoppedTasks(ctx context.Context, family string, startedBy *string) ([]types.Task, error) {
+	arns := []string{}
+	tp := ecs.NewListTasksPaginator(d.ecs, &ecs.ListTasksInput{
+		Cluster:       aws.String(d.Cluster),
+		Family:        aws.String(family),
+		DesiredStatus: types.DesiredStatusStopped,
+	})
+	for tp.HasMorePages() {
+		out, err := tp.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tasks: %w", err)
+		}
+		arns = append(arns, out.TaskArns...)
+	}
+	d.Log("[DEBUG] %d stopped tasks found in family %s", len(arns), family)
+
+	tasks := []types.Task{}
+	for _, chunk := range lo.Chunk(arns, 100) { // 100 is max batch size
+		out, err := d.ecs.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+			Cluster: aws.String(d.Cluster),
+			Tasks:   chunk,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to describe tasks: %w", err)
+		}
+		tasks = append(tasks, out.Tasks...)
+	}
+	if startedBy != nil {
+		tasks = lo.Filter(tasks, func(t types.Task, _ int) bool {
+			return aws.ToString(t.StartedBy) == *startedBy
+		})
+		d.Log("[DEBUG] %d stopped tasks started by %s", len(tasks), *startedBy)
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return aws.ToTime(tasks[i].StoppedAt).After(aws.ToTime(tasks[j].StoppedAt))
+	})
+	return tasks, nil
+}
+
+func isFailedTask(task types.Task) bool {
+	if task.StopCode == types.TaskStopCodeTaskFailedToStart {
+		return true
+	}
+	for _, c := range task.Containers {
+		if c.ExitCode != nil && *c.ExitCode != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// transientStoppedReasons are the stopped reasons of the infrastructure failures which may succeed on retry.
+// See https://docs.aws.amazon.com/AmazonECS/latest/developerguide/stopped-task-error-codes.html
+var transientStoppedReasons = []string{
+	"CannotPullContainerError",
+	"ResourceInitializationError",
+	"CannotCreateVolumeError",
+	"InternalError",
+	"Timeout waiting for network interface provisioning",
+}
+
+// isTransientTaskFailure reports whether the task stopped by a transient infrastructure failure, not by the application.
+func isTransientTaskFailure(stopCode string, stoppedReason string) bool {
+	switch types.TaskStopCode(stopCode) {
+	case types.TaskStopCodeSpotInterruption:
+		return true
+	case types.TaskStopCodeTaskFailedToStart:
+		for _, r := range transientStoppedReasons {
+			if strings.Contains(stoppedReason, r) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func newRunFailure(task types.Task) RunFailure {
+	codes := make([]string, 0, len(task.Containers))
+	for _, c := range task.Containers {
+		code := "-"
+		if c.ExitCode != nil {
+			code = strconv.FormatInt(int64(*c.ExitCode), 10)
+		}
+		codes = append(codes, aws.ToString(c.Name)+":"+code)
+	}
+	return RunFailure{
+		TaskID:         arnToName(aws.ToString(task.TaskArn)),
+		TaskDefinition: arnToName(aws.ToString(task.TaskDefinitionArn)),
+		StoppedAt:      aws.ToTime(task.StoppedAt),
+		StopCode:       string(task.StopCode),
+		StoppedReason:  aws.ToString(task.StoppedReason),
+		ExitCodes:      strings.Join(codes, ","),
+	}
+}
+
+// isSucceededTask reports whether the container in the task exited with 0.
+// An empty name means all containers which have an exit code.
+func isSucceededTask(task types.Task, name string) bool {
+	if task.StopCode == types.TaskStopCodeTaskFailedToStart {
+		return false
+	}
+	exited := false
+	for _, c := range task.Containers {
+		if name != "" && aws.ToString(c.Name) != name {
+			continue
+		}
+		if c.ExitCode == nil {
+			if name != "" {
+				return false
+			}
+			continue
+		}
+		if *c.ExitCode != 0 {
+			return false
+		}
+		exited = true
+	}
+	return exited
+}
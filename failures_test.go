@@ -0,0 +1,70 @@
+package ecspresso_test
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+var testIsFailedTaskSuite = []struct {
+	name   string
+	task   types.Task
+	failed bool
+}{
+	{
+		name: "succeeded",
+		task: types.Task{
+			StopCode:   types.TaskStopCodeEssentialContainerExited,
+			Containers: []types.Container{{Name: aws.String("app"), ExitCode: aws.Int32(0)}},
+		},
+		failed: false,
+	},
+	{
+		name: "exit code 1",
+		task: types.Task{
+			StopCode: types.TaskStopCodeEssentialContainerExited,
+			Containers: []types.Container{
+				{Name: aws.String("app"), ExitCode: aws.Int32(1)},
+				{Name: aws.String("sidecar"), ExitCode: aws.Int32(0)},
+			},
+		},
+		failed: true,
+	},
+	{
+		name: "failed to start",
+		task: types.Task{
+			StopCode:   types.TaskStopCodeTaskFailedToStart,
+			Containers: []types.Container{{Name: aws.String("app")}},
+		},
+		failed: true,
+	},
+}
+
+func TestIsFailedTask(t *testing.T) {
+	for _, s := range testIsFailedTaskSuite {
+		if got := ecspresso.IsFailedTask(s.task); got != s.failed {
+			t.Errorf("%s: expected %t, got %t", s.name, s.failed, got)
+		}
+	}
+}
+
+func TestIsTransientTaskFailure(t *testing.T) {
+	for _, c := range []struct {
+		stopCode      string
+		stoppedReason string
+		expected      bool
+	}{
+		{"TaskFailedToStart", "CannotPullContainerError: pull image manifest has been retried 5 time(s)", true},
+		{"TaskFailedToStart", "ResourceInitializationError: unable to pull secrets or registry auth", true},
+		{"TaskFailedToStart", "Task failed container health checks", false},
+		{"SpotInterruption", "Your Spot Task was interrupted.", true},
+		{"EssentialContainerExited", "Essential container in task exited", false},
+		{"UserInitiated", "Task stopped by user", false},
+	} {
+		if got := ecspresso.IsTransientTaskFailure(c.stopCode, c.stoppedReason); got != c.expected {
+			t.Errorf("%s %s: expected %t, got %t", c.stopCode, c.stoppedReason, c.expected, got)
+		}
+	}
+}
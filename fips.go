@@ -0,0 +1,50 @@
+package ecspresso
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+)
+
+// fipsRegions are the regions which provide FIPS endpoints for both ECS and CloudWatch Logs.
+var fipsRegions = map[string]bool{
+	"us-east-1":     true,
+	"us-east-2":     true,
+	"us-west-1":     true,
+	"us-west-2":     true,
+	"us-gov-east-1": true,
+	"us-gov-west-1": true,
+}
+
+func validateFIPSRegion(region string) error {
+	if fipsRegions[region] {
+		return nil
+	}
+	regions := make([]string, 0, len(fipsRegions))
+	for r := range fipsRegions {
+		regions = append(regions, r)
+	}
+	sort.Strings(regions)
+	return fmt.Errorf("region %q does not support FIPS endpoints. supported regions: %s", region, strings.Join(regions, ", "))
+}
+
+// withFIPSEndpoints returns a copy of the App whose ECS and CloudWatch Logs clients use FIPS endpoints.
+func (d *App) withFIPSEndpoints() (*App, error) {
+	cfg := d.config.awsv2Config
+	if err := validateFIPSRegion(cfg.Region); err != nil {
+		return nil, err
+	}
+	nd := *d
+	nd.ecs = ecs.NewFromConfig(cfg, func(o *ecs.Options) {
+		o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	})
+	nd.cwl = cloudwatchlogs.NewFromConfig(cfg, func(o *cloudwatchlogs.Options) {
+		o.EndpointOptions.UseFIPSEndpoint = aws.FIPSEndpointStateEnabled
+	})
+	nd.Log("[DEBUG] use FIPS endpoints for ECS and CloudWatch Logs in %s", cfg.Region)
+	return &nd, nil
+}
@@ -0,0 +1,104 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/hexops/gotextdiff"
+	"github.com/hexops/gotextdiff/myers"
+	"github.com/hexops/gotextdiff/span"
+)
+
+func compileLogNormalizers(exprs []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, 0, len(exprs))
+	for _, expr := range exprs {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid normalize pattern %q: %w", expr, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// normalizeLog removes the strings matched with the patterns from each line of the log.
+func normalizeLog(lines []string, patterns []*regexp.Regexp) string {
+	var b strings.Builder
+	for _, line := range lines {
+		for _, re := range patterns {
+			line = re.ReplaceAllString(line, "")
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// diffGoldenLog returns the unified diff between the golden file and the log lines.
+// An empty string means they are matched.
+func diffGoldenLog(goldenPath string, lines []string, patterns []*regexp.Regexp) (string, error) {
+	b, err := os.ReadFile(goldenPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read golden file: %w", err)
+	}
+	golden := normalizeLog(strings.Split(strings.TrimSuffix(string(b), "\n"), "\n"), patterns)
+	actual := normalizeLog(lines, patterns)
+	if golden == actual {
+		return "", nil
+	}
+	edits := myers.ComputeEdits(span.URIFromPath(goldenPath), golden, actual)
+	return fmt.Sprint(gotextdiff.ToUnified(goldenPath, "logs", golden, edits)), nil
+}
+
+// readLogMessages reads all the messages of the log stream from the head.
+func (d *App) readLogMessages(ctx context.Context, logGroup, logStream string) ([]string, error) {
+	var lines []string
+	var nextToken *string
+	for {
+		out, err := d.cwl.GetLogEvents(ctx, &cloudwatchlogs.GetLogEventsInput{
+			LogGroupName:  aws.String(logGroup),
+			LogStreamName: aws.String(logStream),
+			StartFromHead: aws.Bool(true),
+			NextToken:     nextToken,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get log events: %w", err)
+		}
+		for _, ev := range out.Events {
+			lines = append(lines, aws.ToString(ev.Message))
+		}
+		if len(out.Events) == 0 || aws.ToString(out.NextForwardToken) == aws.ToString(nextToken) {
+			return lines, nil
+		}
+		nextToken = out.NextForwardToken
+	}
+}
+
+// checkGoldenLog compares the logs of the watch container with the golden file.
+func (d *App) checkGoldenLog(ctx context.Context, task *types.Task, watchContainer *types.ContainerDefinition, goldenPath string, patterns []*regexp.Regexp) error {
+	lc := watchContainer.LogConfiguration
+	if lc == nil || lc.LogDriver != types.LogDriverAwslogs || lc.Options["awslogs-stream-prefix"] == "" {
+		return fmt.Errorf("golden log requires awslogs with awslogs-stream-prefix for container %s", aws.ToString(watchContainer.Name))
+	}
+	logGroup, logStream := d.GetLogInfo(task, watchContainer)
+	lines, err := d.readLogMessages(ctx, logGroup, logStream)
+	if err != nil {
+		return err
+	}
+	ds, err := diffGoldenLog(goldenPath, lines, patterns)
+	if err != nil {
+		return err
+	}
+	if ds != "" {
+		fmt.Print(coloredDiff(ds))
+		return fmt.Errorf("logs of container %s do not match golden file %s", aws.ToString(watchContainer.Name), goldenPath)
+	}
+	d.Log("Logs of container %s match golden file %s", aws.ToString(watchContainer.Name), goldenPath)
+	return nil
+}
@@ -0,0 +1,44 @@
+package ecspresso_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestDiffGoldenLog(t *testing.T) {
+	patterns, err := ecspresso.CompileLogNormalizers([]string{`^\d{4}-\d{2}-\d{2}T\S+ `, ` id=\w+`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	matched := []string{
+		"2024-01-01T00:00:00Z start batch",
+		"2024-01-01T00:00:01Z processed 3 records id=abc123",
+		"2024-01-01T00:00:02Z done",
+	}
+	ds, err := ecspresso.DiffGoldenLog("tests/golden.log", matched, patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ds != "" {
+		t.Errorf("unexpected diff: %s", ds)
+	}
+
+	unmatched := []string{
+		"2024-01-01T00:00:00Z start batch",
+		"2024-01-01T00:00:01Z processed 2 records",
+		"2024-01-01T00:00:02Z done",
+	}
+	ds, err = ecspresso.DiffGoldenLog("tests/golden.log", unmatched, patterns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(ds, "-processed 3 records") || !strings.Contains(ds, "+processed 2 records") {
+		t.Errorf("unexpected diff: %s", ds)
+	}
+
+	if _, err := ecspresso.CompileLogNormalizers([]string{"("}); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
@@ -142,3 +142,79 @@ func jqFilter(m map[string]interface{}, q string) (map[string]interface{}, error
 	}
 	return m, nil
 }
+
+// relaxJSON converts a JSON document including comments (// and /* */) and
+// trailing commas into a plain JSON document.
+func relaxJSON(src []byte) []byte {
+	return removeTrailingCommas(stripJSONComments(src))
+}
+
+func stripJSONComments(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	inString := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				out = append(out, src[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(src) && src[i+1] == '/':
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+			if i < len(src) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(src) && src[i+1] == '*':
+			i += 2
+			for i+1 < len(src) && !(src[i] == '*' && src[i+1] == '/') {
+				i++
+			}
+			i++ // skip the closing '/'
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func removeTrailingCommas(src []byte) []byte {
+	out := make([]byte, 0, len(src))
+	inString := false
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(src) {
+				i++
+				out = append(out, src[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == ',':
+			j := i + 1
+			for j < len(src) && strings.ContainsRune(" \t\r\n", rune(src[j])) {
+				j++
+			}
+			if j < len(src) && (src[j] == '}' || src[j] == ']') {
+				continue // drop a trailing comma
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
@@ -89,3 +89,30 @@ func TestUnmarshalJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestRelaxJSON(t *testing.T) {
+	src := `{
+  // line comment
+  "containerOverrides": [
+    {
+      "name": "app", /* block comment */
+      "command": ["echo", "http://example.com/*not a comment*/", "a,]"],
+    },
+  ],
+}`
+	expected := map[string]interface{}{
+		"containerOverrides": []interface{}{
+			map[string]interface{}{
+				"name":    "app",
+				"command": []interface{}{"echo", "http://example.com/*not a comment*/", "a,]"},
+			},
+		},
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(ecspresso.RelaxJSON([]byte(src)), &got); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected json: %s", diff)
+	}
+}
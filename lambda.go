@@ -0,0 +1,43 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	lambdaTypes "github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// invokeOnCompleteLambda invokes the Lambda function with the result of the run as the payload.
+// Failures are logged as warnings and do not affect the result of the run.
+func (d *App) invokeOnCompleteLambda(ctx context.Context, function string, async bool, result *RunResult) {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		d.Log("[WARNING] failed to marshal the result for Lambda: %s", err)
+		return
+	}
+	invocationType := lambdaTypes.InvocationTypeRequestResponse
+	if async {
+		invocationType = lambdaTypes.InvocationTypeEvent
+	}
+	d.Log("Invoking Lambda function %s (%s)", function, invocationType)
+	d.Log("[DEBUG] payload: %s", string(payload))
+
+	client := lambda.NewFromConfig(d.config.awsv2Config)
+	out, err := client.Invoke(ctx, &lambda.InvokeInput{
+		FunctionName:   aws.String(function),
+		InvocationType: invocationType,
+		Payload:        payload,
+	})
+	if err != nil {
+		d.Log("[WARNING] %s", fmt.Errorf("failed to invoke Lambda function %s: %w", function, err))
+		return
+	}
+	if fe := aws.ToString(out.FunctionError); fe != "" {
+		d.Log("[WARNING] Lambda function %s returned an error: %s %s", function, fe, string(out.Payload))
+		return
+	}
+	d.Log("Lambda function %s is invoked. status code: %d", function, out.StatusCode)
+}
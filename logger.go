@@ -5,6 +5,9 @@ import (
 	"io"
 	"log"
 	"os"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/fatih/color"
 	"github.com/fujiwara/logutils"
@@ -33,6 +36,94 @@ func newLogFilter(w io.Writer, minLevel string) *logutils.LevelFilter {
 	}
 }
 
+// newJSONLogFilter returns the level filter which writes the log lines as JSON objects.
+func newJSONLogFilter(w io.Writer, minLevel string) *logutils.LevelFilter {
+	return &logutils.LevelFilter{
+		Levels:   []logutils.LogLevel{"DEBUG", "INFO", "WARNING", "ERROR"},
+		MinLevel: logutils.LogLevel(minLevel),
+		Writer:   &jsonLogWriter{w: w, mu: &sync.Mutex{}},
+	}
+}
+
+// setJSONLogFormat makes the logger write the log lines as JSON objects (--log-format=json).
+func setJSONLogFormat(logger *log.Logger, minLevel string) {
+	logger.SetFlags(0)
+	logger.SetOutput(newJSONLogFilter(os.Stderr, minLevel))
+}
+
+// jsonLogWriter converts a log line to a JSON object {"time", "level", "msg"}.
+// taskArn is added to correlate the logs with the running task.
+type jsonLogWriter struct {
+	w       io.Writer
+	mu      *sync.Mutex
+	taskArn string
+}
+
+type jsonLogLine struct {
+	Time    string `json:"time"`
+	Level   string `json:"level"`
+	Msg     string `json:"msg"`
+	TaskArn string `json:"task_arn,omitempty"`
+}
+
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	level, msg := splitLogLevel(strings.TrimSuffix(string(p), "\n"))
+	b, err := json.Marshal(jsonLogLine{
+		Time:    time.Now().Format(time.RFC3339Nano),
+		Level:   level,
+		Msg:     msg,
+		TaskArn: w.taskArn,
+	})
+	if err != nil {
+		return 0, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.w.Write(append(b, '\n')); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// splitLogLevel removes the first [LEVEL] tag in the line as the level filter finds it.
+// The level is INFO when the line has no tag.
+func splitLogLevel(line string) (string, string) {
+	i := strings.Index(line, "[")
+	if i < 0 {
+		return "INFO", line
+	}
+	j := strings.Index(line[i:], "]")
+	if j < 0 {
+		return "INFO", line
+	}
+	switch level := line[i+1 : i+j]; level {
+	case "DEBUG", "INFO", "WARNING", "ERROR":
+		msg := line[:i] + strings.TrimPrefix(line[i+j+1:], " ")
+		return level, msg
+	}
+	return "INFO", line
+}
+
+// withLogTaskArn returns a copy of the App which adds the task ARN to the logs in JSON.
+// The logs in text are not changed.
+func (d *App) withLogTaskArn(arn string) *App {
+	f, ok := d.logger.Writer().(*logutils.LevelFilter)
+	if !ok {
+		return d
+	}
+	jw, ok := f.Writer.(*jsonLogWriter)
+	if !ok {
+		return d
+	}
+	nd := *d
+	nd.logger = log.New(&logutils.LevelFilter{
+		Levels:   f.Levels,
+		MinLevel: f.MinLevel,
+		Writer:   &jsonLogWriter{w: jw.w, mu: jw.mu, taskArn: arn},
+	}, d.logger.Prefix(), d.logger.Flags())
+	return &nd
+}
+
 func newLogger() *log.Logger {
 	return log.New(io.Discard, "", log.LstdFlags)
 }
@@ -42,7 +133,7 @@ func Log(f string, v ...interface{}) {
 }
 
 func (d *App) Log(f string, v ...interface{}) {
-	d.logger.Printf(d.Name()+" "+f, v...)
+	d.logger.Printf(d.logPrefix+d.runIDPrefix+d.Name()+" "+f, v...)
 }
 
 func (d *App) LogJSON(v interface{}) {
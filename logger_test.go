@@ -2,8 +2,11 @@ package ecspresso_test
 
 import (
 	"bytes"
+	"encoding/json"
+	"strings"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/kayac/ecspresso/v2"
 )
 
@@ -41,3 +44,52 @@ func TestLogger(t *testing.T) {
 		t.Log(b.String())
 	}
 }
+
+func TestJSONLogger(t *testing.T) {
+	b := new(bytes.Buffer)
+	logger := ecspresso.NewLogger()
+	logger.SetFlags(0)
+	logger.SetOutput(ecspresso.NewJSONLogFilter(b, "INFO"))
+	app := &ecspresso.App{}
+	app.SetLogger(logger)
+
+	app.Log("[DEBUG] should be filtered")
+	app.Log("[WARNING] test %s", "warning")
+	app.WithLogTaskArn("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001").Log("test %d", 1)
+
+	expected := []map[string]string{
+		{"level": "WARNING", "msg": "/ test warning"},
+		{"level": "INFO", "msg": "/ test 1", "task_arn": "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001"},
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != len(expected) {
+		t.Fatalf("unexpected logs: %s", b.String())
+	}
+	for i, line := range lines {
+		var got map[string]string
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		if got["time"] == "" {
+			t.Errorf("time is empty: %s", line)
+		}
+		delete(got, "time")
+		if diff := cmp.Diff(expected[i], got); diff != "" {
+			t.Error(diff)
+		}
+	}
+}
+
+func TestTextLoggerWithTaskArn(t *testing.T) {
+	b := new(bytes.Buffer)
+	logger := ecspresso.NewLogger()
+	logger.SetFlags(0)
+	logger.SetOutput(ecspresso.NewLogFilter(b, "INFO"))
+	app := &ecspresso.App{}
+	app.SetLogger(logger)
+
+	app.WithLogTaskArn("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001").Log("test %d", 1)
+	if s := b.String(); s != "/ test 1\n" {
+		t.Errorf("unexpected logs: %q", s)
+	}
+}
@@ -0,0 +1,312 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+var (
+	logPollInterval = 5 * time.Second
+	// logStreamWait is the time to wait for the log streams to be created after the task launched.
+	logStreamWait = 3 * time.Second
+	// logStreamPollInterval is the interval to check the log stream and the task while waiting for the log streams.
+	logStreamPollInterval = 500 * time.Millisecond
+	// maxLogPollInterval is the max interval of polling the logs lengthened on throttling.
+	maxLogPollInterval = time.Minute
+)
+
+// tailStream represents a CloudWatch Logs stream to tail.
+type tailStream struct {
+	group     string
+	stream    string
+	nextToken *string
+	fromHead  bool
+	// prefix is prepended to each log line (e.g. the container name)
+	prefix string
+
+	// filterPattern switches GetLogEvents to FilterLogEvents to filter events in server side.
+	filterPattern string
+	lastTimestamp int64
+	seenEventIDs  map[string]bool
+
+	// lastError is the last error logged not to repeat the same warning on every poll.
+	lastError string
+}
+
+// waitLogStreams waits up to wait for the log streams to be created.
+// It returns early when the first stream exists or the task has already stopped (e.g. failed to pull the image).
+func (d *App) waitLogStreams(ctx context.Context, task *types.Task, streams []*tailStream, wait time.Duration) {
+	d.Log("[DEBUG] waiting up to %s for log streams", wait)
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+	ticker := time.NewTicker(logStreamPollInterval)
+	defer ticker.Stop()
+	for {
+		if d.logStreamExists(ctx, streams[0]) {
+			d.Log("[DEBUG] log stream %s is found", streams[0].stream)
+			return
+		}
+		if d.taskStopped(ctx, task) {
+			d.Log("[DEBUG] task %s has already stopped. not waiting for log streams", arnToName(aws.ToString(task.TaskArn)))
+			return
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *App) logStreamExists(ctx context.Context, s *tailStream) bool {
+	out, err := d.cwl.DescribeLogStreams(ctx, &cloudwatchlogs.DescribeLogStreamsInput{
+		LogGroupName:        aws.String(s.group),
+		LogStreamNamePrefix: aws.String(s.stream),
+	})
+	if err != nil {
+		if ctx.Err() == nil {
+			d.Log("[DEBUG] failed to describe log streams of %s: %s", s.group, err)
+		}
+		return false
+	}
+	for _, ls := range out.LogStreams {
+		if aws.ToString(ls.LogStreamName) == s.stream {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *App) taskStopped(ctx context.Context, task *types.Task) bool {
+	out, err := d.ecs.DescribeTasks(ctx, d.DescribeTasksInput(task))
+	if err != nil {
+		if ctx.Err() == nil {
+			d.Log("[DEBUG] failed to describe task %s: %s", arnToName(aws.ToString(task.TaskArn)), err)
+		}
+		return false
+	}
+	for _, t := range out.Tasks {
+		if aws.ToString(t.LastStatus) == "STOPPED" {
+			return true
+		}
+	}
+	return false
+}
+
+// maxLogPagesPerPoll limits the number of pages read at once from the head of a log stream.
+const maxLogPagesPerPoll = 10
+
+// tailLogs polls GetLogEvents for the streams every interval until ctx is done.
+// concurrency bounds the number of concurrent GetLogEvents calls (0 means unbounded).
+func (d *App) tailLogs(ctx context.Context, streams []*tailStream, startedAt time.Time, interval time.Duration, concurrency int) {
+	if len(streams) == 0 {
+		return
+	}
+	if interval <= 0 {
+		interval = logPollInterval
+	}
+	if concurrency <= 0 || concurrency > len(streams) {
+		concurrency = len(streams)
+	}
+	d.Log("[DEBUG] tailing %d log streams every %s with concurrency %d", len(streams), interval, concurrency)
+	current := interval
+	ticker := time.NewTicker(current)
+	defer ticker.Stop()
+	offset := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		sem := make(chan struct{}, concurrency)
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		throttled := false
+		for i := range streams {
+			// round-robin the order of streams not to starve the tail of streams
+			s := streams[(offset+i)%len(streams)]
+			sem <- struct{}{}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+				if isThrottlingError(d.pollLogStream(ctx, s, startedAt)) {
+					mu.Lock()
+					throttled = true
+					mu.Unlock()
+				}
+			}()
+		}
+		wg.Wait()
+		offset = (offset + 1) % len(streams)
+		if next := nextLogPollInterval(current, interval, throttled); next != current {
+			d.Log("[DEBUG] polling the log streams every %s", next)
+			current = next
+			ticker.Reset(current)
+		}
+	}
+}
+
+// nextLogPollInterval doubles the interval up to maxLogPollInterval on throttling,
+// and halves it back to the base interval after the throttling has gone.
+func nextLogPollInterval(current, base time.Duration, throttled bool) time.Duration {
+	if throttled {
+		if current *= 2; current > maxLogPollInterval {
+			current = maxLogPollInterval
+		}
+		if current < base {
+			current = base
+		}
+		return current
+	}
+	if current /= 2; current < base {
+		current = base
+	}
+	return current
+}
+
+// pollLogStream polls the log stream once and returns the error of polling, which is already logged.
+func (d *App) pollLogStream(ctx context.Context, s *tailStream, startedAt time.Time) error {
+	var err error
+	if s.filterPattern != "" {
+		err = d.filterLogStream(ctx, s, startedAt)
+	} else if s.fromHead {
+		// read forward from the head to the tail of the stream
+		for i := 0; i < maxLogPagesPerPoll; i++ {
+			in := d.GetLogEventsInput(s.group, s.stream, 0, s.nextToken)
+			in.StartTime = nil
+			in.StartFromHead = aws.Bool(true)
+			var nextToken *string
+			nextToken, err = d.printLogEvents(ctx, in, s.prefix)
+			if err != nil || aws.ToString(nextToken) == aws.ToString(s.nextToken) {
+				break
+			}
+			s.nextToken = nextToken
+		}
+	} else {
+		in := d.GetLogEventsInput(s.group, s.stream, startedAt.UnixMilli(), s.nextToken)
+		s.nextToken, err = d.printLogEvents(ctx, in, s.prefix)
+	}
+	d.logPollError(ctx, s, err)
+	return err
+}
+
+// logPollError logs the error of polling the log stream. The polling continues regardless of the error.
+// The log stream may not be created yet right after the task launched, so ResourceNotFound is logged in debug.
+// Other errors (e.g. AccessDenied) are logged as a warning once until the error changes.
+func (d *App) logPollError(ctx context.Context, s *tailStream, err error) {
+	var notFound *logsTypes.ResourceNotFoundException
+	switch {
+	case err == nil:
+		s.lastError = ""
+	case ctx.Err() != nil:
+		// canceled by the task stopped
+	case isThrottlingError(err):
+		d.Log("[WARNING] GetLogEvents for %s is throttled. polling log streams slows down", s.stream)
+	case errors.As(err, &notFound):
+		d.Log("[DEBUG] log stream %s in %s is not found yet. retrying", s.stream, s.group)
+	case err.Error() != s.lastError:
+		s.lastError = err.Error()
+		d.Log("[WARNING] failed to get log events of %s in %s: %s", s.stream, s.group, err)
+	}
+}
+
+// filterLogStream prints the events matched with the filter pattern since the last event printed.
+// FilterLogEvents has no forward token like GetLogEvents, so it reads from the timestamp of the last event
+// and skips the events at the timestamp which are already printed.
+func (d *App) filterLogStream(ctx context.Context, s *tailStream, startedAt time.Time) error {
+	if s.lastTimestamp == 0 && !s.fromHead {
+		s.lastTimestamp = startedAt.UnixMilli()
+	}
+	in := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName:   aws.String(s.group),
+		LogStreamNames: []string{s.stream},
+		FilterPattern:  aws.String(s.filterPattern),
+		StartTime:      aws.Int64(s.lastTimestamp),
+	}
+	for i := 0; i < maxLogPagesPerPoll; i++ {
+		if err := d.logsLimiter.wait(ctx); err != nil {
+			return err
+		}
+		out, err := d.cwl.FilterLogEvents(ctx, in)
+		if err != nil {
+			return err
+		}
+		for _, e := range newFilteredEvents(s, out.Events) {
+			fmt.Fprintln(d.logOutput(), d.logPrefix+s.prefix+formatLogEvent(logsTypes.OutputLogEvent{
+				Timestamp:     e.Timestamp,
+				Message:       e.Message,
+				IngestionTime: e.IngestionTime,
+			}))
+		}
+		if out.NextToken == nil {
+			return nil
+		}
+		in.NextToken = out.NextToken
+	}
+	return nil
+}
+
+// newFilteredEvents returns the events which are not printed yet, and records them in s.
+func newFilteredEvents(s *tailStream, events []logsTypes.FilteredLogEvent) []logsTypes.FilteredLogEvent {
+	var res []logsTypes.FilteredLogEvent
+	for _, e := range events {
+		ts, id := aws.ToInt64(e.Timestamp), aws.ToString(e.EventId)
+		if ts < s.lastTimestamp || s.seenEventIDs[id] {
+			continue
+		}
+		if ts > s.lastTimestamp || s.seenEventIDs == nil {
+			s.lastTimestamp = ts
+			s.seenEventIDs = map[string]bool{}
+		}
+		s.seenEventIDs[id] = true
+		res = append(res, e)
+	}
+	return res
+}
+
+// logsConsoleURL returns the URL of the log stream in CloudWatch Logs console.
+// The log group is linked when the stream is empty.
+func logsConsoleURL(region, group, stream string) string {
+	// the console escapes the URL encoded values again with $ instead of %
+	escape := func(s string) string {
+		return strings.ReplaceAll(url.QueryEscape(s), "%", "$25")
+	}
+	u := fmt.Sprintf("https://%s.console.aws.amazon.com/cloudwatch/home?region=%s#logsV2:log-groups/log-group/%s",
+		region, region, escape(group))
+	if stream != "" {
+		u += "/log-events/" + escape(stream)
+	}
+	return u
+}
+
+// logLogsConsoleURL logs the URL of the log stream of the container in CloudWatch Logs console
+// to check the logs of the task not waited for.
+func (d *App) logLogsConsoleURL(task *types.Task, c *types.ContainerDefinition) {
+	lc := c.LogConfiguration
+	if lc == nil || lc.LogDriver != types.LogDriverAwslogs || lc.Options["awslogs-group"] == "" {
+		d.Log("[DEBUG] container %s is not configured with awslogs. no logs URL", aws.ToString(c.Name))
+		return
+	}
+	region := lc.Options["awslogs-region"]
+	if region == "" {
+		region = d.config.Region
+	}
+	group, stream := d.GetLogInfo(task, c)
+	if lc.Options["awslogs-stream-prefix"] == "" {
+		// the log stream is named by the container ID without the prefix
+		stream = ""
+	}
+	d.Log("Logs: %s", logsConsoleURL(region, group, stream))
+}
@@ -0,0 +1,137 @@
+package ecspresso_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/smithy-go"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func filteredEvent(id string, ts int64) logsTypes.FilteredLogEvent {
+	return logsTypes.FilteredLogEvent{EventId: aws.String(id), Timestamp: aws.Int64(ts), Message: aws.String(id)}
+}
+
+func TestNewFilteredEvents(t *testing.T) {
+	got := ecspresso.FilteredEventsForTest(
+		[]logsTypes.FilteredLogEvent{filteredEvent("a", 100), filteredEvent("b", 200), filteredEvent("c", 200)},
+		// the next poll starts from the last timestamp 200
+		[]logsTypes.FilteredLogEvent{filteredEvent("b", 200), filteredEvent("c", 200), filteredEvent("d", 200), filteredEvent("e", 300)},
+		[]logsTypes.FilteredLogEvent{filteredEvent("e", 300)},
+		[]logsTypes.FilteredLogEvent{filteredEvent("e", 300), filteredEvent("f", 400)},
+	)
+	expected := [][]string{{"a", "b", "c"}, {"d", "e"}, nil, {"f"}}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Errorf("unexpected events %s", diff)
+	}
+}
+
+func TestLogContainers(t *testing.T) {
+	awslogs := &types.LogConfiguration{LogDriver: types.LogDriverAwslogs}
+	td := &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("sidecar"), LogConfiguration: awslogs},
+			{Name: aws.String("app"), LogConfiguration: awslogs},
+			{Name: aws.String("fluentbit"), LogConfiguration: &types.LogConfiguration{LogDriver: types.LogDriverFluentd}},
+			{Name: aws.String("proxy"), LogConfiguration: awslogs},
+		},
+	}
+	if diff := cmp.Diff([]string{"app"}, ecspresso.LogContainerNames(td, "app", false)); diff != "" {
+		t.Error(diff)
+	}
+	if diff := cmp.Diff([]string{"app", "sidecar", "proxy"}, ecspresso.LogContainerNames(td, "app", true)); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestLogPollIntervalOption(t *testing.T) {
+	_, cliopts, _, err := ecspresso.ParseCLIv2([]string{"run", "--log-poll-interval=1s", "--log-stream-wait=0s"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt := cliopts.Run; opt.LogPollInterval != time.Second || opt.LogStreamWait != 0 {
+		t.Errorf("unexpected options: interval %s, stream wait %s", opt.LogPollInterval, opt.LogStreamWait)
+	}
+}
+
+func TestLogPollError(t *testing.T) {
+	b := new(bytes.Buffer)
+	logger := ecspresso.NewLogger()
+	logger.SetOutput(ecspresso.NewLogFilter(b, "DEBUG"))
+	app := &ecspresso.App{}
+	app.SetLogger(logger)
+
+	denied := &smithy.GenericAPIError{Code: "AccessDeniedException", Message: "not authorized to perform: logs:GetLogEvents"}
+	app.LogPollError(context.Background(), "ecs/app/0123",
+		&logsTypes.ResourceNotFoundException{Message: aws.String("The specified log stream does not exist.")},
+		denied,
+		denied, // not repeated
+		nil,
+		denied, // logged again after recovered
+	)
+	out := b.String()
+	if n := strings.Count(out, "[DEBUG] log stream ecs/app/0123 in /ecs/test is not found yet"); n != 1 {
+		t.Errorf("not found should be logged in debug once, got %d: %s", n, out)
+	}
+	if n := strings.Count(out, "[WARNING] failed to get log events of ecs/app/0123"); n != 2 {
+		t.Errorf("access denied should be logged twice, got %d: %s", n, out)
+	}
+
+	b.Reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	app.LogPollError(ctx, "ecs/app/0123", context.Canceled)
+	if b.Len() > 0 {
+		t.Errorf("canceled error should not be logged: %s", b.String())
+	}
+}
+
+func TestLogsConsoleURL(t *testing.T) {
+	for _, c := range []struct {
+		region, group, stream string
+		expected              string
+	}{
+		{
+			region:   "ap-northeast-1",
+			group:    "/ecs/app",
+			stream:   "ecs/app/0123456789abcdef",
+			expected: "https://ap-northeast-1.console.aws.amazon.com/cloudwatch/home?region=ap-northeast-1#logsV2:log-groups/log-group/$252Fecs$252Fapp/log-events/ecs$252Fapp$252F0123456789abcdef",
+		},
+		{
+			region:   "us-east-1",
+			group:    "/aws/batch#job",
+			expected: "https://us-east-1.console.aws.amazon.com/cloudwatch/home?region=us-east-1#logsV2:log-groups/log-group/$252Faws$252Fbatch$2523job",
+		},
+	} {
+		if got := ecspresso.LogsConsoleURL(c.region, c.group, c.stream); got != c.expected {
+			t.Errorf("expected %s, got %s", c.expected, got)
+		}
+	}
+}
+
+func TestWaitLogStreamsStoppedTask(t *testing.T) {
+	ctx := context.Background()
+	app := newRunTestApp(t)
+	// the log stream does not exist and the task has already stopped in the mock
+	start := time.Now()
+	app.WaitLogStreams(ctx, "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001", time.Minute)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("waited for the log streams of the stopped task: %s", elapsed)
+	}
+
+	// respects the cancellation
+	cctx, cancel := context.WithCancel(ctx)
+	cancel()
+	start = time.Now()
+	app.WaitLogStreams(cctx, "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001", time.Minute)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("waited for the log streams after the cancellation: %s", elapsed)
+	}
+}
@@ -0,0 +1,283 @@
+package ecspresso
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// LogSink is a destination of the logs of the task.
+// stdout, file:PATH, s3://BUCKET/KEY and http(s)://URL (webhook) are supported.
+type LogSink string
+
+// logWebhookBatchSize is the max number of log lines in a webhook request.
+var logWebhookBatchSize = 100
+
+// logWebhookFlushInterval is the interval to post the buffered lines to the webhook
+// even if a batch is not filled.
+var logWebhookFlushInterval = 5 * time.Second
+
+// logSinkTimeout is the timeout for a request to put or post the logs of a sink.
+var logSinkTimeout = 30 * time.Second
+
+// openLogSink opens the sink as a writer. Each Write is a log line terminated by a newline.
+func (d *App) openLogSink(sink LogSink) (io.WriteCloser, error) {
+	s := string(sink)
+	switch {
+	case s == "stdout":
+		return nopWriteCloser{os.Stdout}, nil
+	case strings.HasPrefix(s, "file:"):
+		path := strings.TrimPrefix(s, "file:")
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open log sink %s: %w", s, err)
+		}
+		return f, nil
+	case strings.HasPrefix(s, "s3://"):
+		u, err := url.Parse(s)
+		if err != nil || u.Host == "" || strings.TrimPrefix(u.Path, "/") == "" {
+			return nil, fmt.Errorf("invalid log sink %s. s3://BUCKET/KEY is required", s)
+		}
+		return &s3LogSink{
+			client: s3.NewFromConfig(d.config.awsv2Config),
+			bucket: u.Host,
+			key:    strings.TrimPrefix(u.Path, "/"),
+		}, nil
+	case strings.HasPrefix(s, "http://"), strings.HasPrefix(s, "https://"):
+		return newWebhookLogSink(s, logWebhookBatchSize), nil
+	}
+	return nil, fmt.Errorf("unsupported log sink %s. stdout, file:PATH, s3://BUCKET/KEY or http(s)://URL is required", s)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
+
+// s3LogSink buffers the logs and puts them as an S3 object on close.
+type s3LogSink struct {
+	client *s3.Client
+	bucket string
+	key    string
+	buf    bytes.Buffer
+}
+
+func (s *s3LogSink) Write(p []byte) (int, error) {
+	return s.buf.Write(p)
+}
+
+func (s *s3LogSink) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), logSinkTimeout)
+	defer cancel()
+	if _, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.key),
+		Body:        bytes.NewReader(s.buf.Bytes()),
+		ContentType: aws.String("text/plain; charset=utf-8"),
+	}); err != nil {
+		return fmt.Errorf("failed to put logs to s3://%s/%s: %w", s.bucket, s.key, err)
+	}
+	return nil
+}
+
+// webhookLogSink POSTs the logs in batches as JSON {"lines": [...]}.
+// Write only buffers the lines, and a background goroutine posts them
+// when a batch is filled or on every logWebhookFlushInterval, not to block the log tailing.
+type webhookLogSink struct {
+	url       string
+	batchSize int
+
+	mu    sync.Mutex
+	lines []string
+	err   error // the first error of posting. the lines after it are dropped
+
+	notify  chan struct{}
+	closing chan struct{}
+	done    chan struct{}
+}
+
+func newWebhookLogSink(u string, batchSize int) *webhookLogSink {
+	s := &webhookLogSink{
+		url:       u,
+		batchSize: batchSize,
+		notify:    make(chan struct{}, 1),
+		closing:   make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+	go s.run()
+	return s
+}
+
+func (s *webhookLogSink) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return 0, s.err
+	}
+	s.lines = append(s.lines, strings.TrimSuffix(string(p), "\n"))
+	if len(s.lines) >= s.batchSize {
+		select {
+		case s.notify <- struct{}{}:
+		default: // already notified
+		}
+	}
+	return len(p), nil
+}
+
+func (s *webhookLogSink) run() {
+	defer close(s.done)
+	ticker := time.NewTicker(logWebhookFlushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.notify:
+		case <-ticker.C:
+		case <-s.closing:
+			s.flush()
+			return
+		}
+		s.flush()
+	}
+}
+
+// flush posts all the buffered lines in batches.
+func (s *webhookLogSink) flush() {
+	s.mu.Lock()
+	lines := s.lines
+	s.lines = nil
+	failed := s.err != nil
+	s.mu.Unlock()
+	if failed {
+		return
+	}
+	for len(lines) > 0 {
+		n := s.batchSize
+		if n <= 0 || n > len(lines) {
+			n = len(lines)
+		}
+		if err := s.post(lines[:n]); err != nil {
+			s.mu.Lock()
+			s.err = err
+			s.lines = nil
+			s.mu.Unlock()
+			return
+		}
+		lines = lines[n:]
+	}
+}
+
+func (s *webhookLogSink) post(lines []string) error {
+	body, err := json.Marshal(map[string][]string{"lines": lines})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), logSinkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "ecspresso/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to post logs to %s: %w", s.url, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("failed to post logs to %s: %s", s.url, resp.Status)
+	}
+	return nil
+}
+
+// Close posts the remaining lines and stops the background goroutine.
+func (s *webhookLogSink) Close() error {
+	close(s.closing)
+	<-s.done
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// logFanout writes each log line to all the sinks.
+// A sink which failed to write is disabled with a warning and does not affect the others.
+type logFanout struct {
+	mu     sync.Mutex
+	names  []LogSink
+	sinks  []io.WriteCloser
+	failed []bool
+	logf   func(string, ...interface{})
+}
+
+func newLogFanout(names []LogSink, sinks []io.WriteCloser, logf func(string, ...interface{})) *logFanout {
+	return &logFanout{
+		names:  names,
+		sinks:  sinks,
+		failed: make([]bool, len(sinks)),
+		logf:   logf,
+	}
+}
+
+func (f *logFanout) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, s := range f.sinks {
+		if f.failed[i] {
+			continue
+		}
+		if _, err := s.Write(p); err != nil {
+			f.failed[i] = true
+			f.logf("[WARNING] log sink %s is disabled: %s", f.names[i], err)
+		}
+	}
+	return len(p), nil
+}
+
+func (f *logFanout) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i, s := range f.sinks {
+		if err := s.Close(); err != nil && !f.failed[i] {
+			f.logf("[WARNING] failed to close log sink %s: %s", f.names[i], err)
+		}
+	}
+	return nil
+}
+
+// openLogSinks opens the sinks and returns the writer which fans out to them.
+func (d *App) openLogSinks(names []LogSink) (*logFanout, error) {
+	sinks := make([]io.WriteCloser, 0, len(names))
+	for _, name := range names {
+		s, err := d.openLogSink(name)
+		if err != nil {
+			for _, s := range sinks {
+				s.Close()
+			}
+			return nil, err
+		}
+		d.Log("[DEBUG] log sink %s is opened", name)
+		sinks = append(sinks, s)
+	}
+	return newLogFanout(names, sinks, d.Log), nil
+}
+
+// logOutput returns the writer for the logs of the task.
+func (d *App) logOutput() io.Writer {
+	if d.logWriter != nil {
+		return d.logWriter
+	}
+	return os.Stdout
+}
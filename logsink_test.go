@@ -0,0 +1,180 @@
+package ecspresso_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+type bufferSink struct {
+	bytes.Buffer
+}
+
+func (s *bufferSink) Close() error { return nil }
+
+type brokenSink struct {
+	writes int
+}
+
+func (s *brokenSink) Write(p []byte) (int, error) {
+	s.writes++
+	return 0, errors.New("broken")
+}
+
+func (s *brokenSink) Close() error { return nil }
+
+func TestLogFanout(t *testing.T) {
+	var warnings []string
+	logf := func(f string, v ...interface{}) { warnings = append(warnings, fmt.Sprintf(f, v...)) }
+	a, b := &bufferSink{}, &bufferSink{}
+	broken := &brokenSink{}
+	w := ecspresso.NewLogFanout(
+		[]ecspresso.LogSink{"a", "broken", "b"},
+		[]io.WriteCloser{a, broken, b},
+		logf,
+	)
+	for _, line := range []string{"foo\n", "bar\n"} {
+		if _, err := io.WriteString(w, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []*bufferSink{a, b} {
+		if s.String() != "foo\nbar\n" {
+			t.Errorf("unexpected logs in the sink: %q", s.String())
+		}
+	}
+	if broken.writes != 1 {
+		t.Errorf("the broken sink must be disabled after the first error. writes: %d", broken.writes)
+	}
+	if len(warnings) != 1 {
+		t.Errorf("expected a warning, got %v", warnings)
+	}
+}
+
+func TestWebhookLogSink(t *testing.T) {
+	var batches [][]string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Lines []string `json:"lines"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		batches = append(batches, body.Lines)
+	}))
+	defer ts.Close()
+
+	s := ecspresso.NewWebhookLogSink(ts.URL, 2)
+	for _, line := range []string{"1\n", "2\n", "3\n"} {
+		if _, err := io.WriteString(s, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]string{{"1", "2"}, {"3"}}
+	if diff := cmp.Diff(expected, batches); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestWebhookLogSinkNotBlocking(t *testing.T) {
+	release := make(chan struct{})
+	var mu sync.Mutex
+	var lines []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		var body struct {
+			Lines []string `json:"lines"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		mu.Lock()
+		lines = append(lines, body.Lines...)
+		mu.Unlock()
+	}))
+	defer ts.Close()
+
+	s := ecspresso.NewWebhookLogSink(ts.URL, 1)
+	// writes must not wait for the slow webhook
+	for _, line := range []string{"1\n", "2\n", "3\n"} {
+		if _, err := io.WriteString(s, line); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(release)
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if diff := cmp.Diff([]string{"1", "2", "3"}, lines); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestWebhookLogSinkError(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer ts.Close()
+
+	s := ecspresso.NewWebhookLogSink(ts.URL, 100)
+	if _, err := io.WriteString(s, "1\n"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err == nil {
+		t.Error("expected error on close, got nil")
+	}
+}
+
+func TestOpenLogSink(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/command-templates.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "task.log")
+	s, err := app.OpenLogSink(ecspresso.LogSink("file:" + path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	io.WriteString(s, "foo\n")
+	s.Close()
+	if b, _ := os.ReadFile(path); string(b) != "foo\n" {
+		t.Errorf("unexpected logs in the file: %q", string(b))
+	}
+
+	for _, sink := range []ecspresso.LogSink{"stderr", "s3://bucket", "file"} {
+		if _, err := app.OpenLogSink(sink); err == nil {
+			t.Errorf("expected error for %s, got nil", sink)
+		}
+	}
+
+	_, cliopts, _, err := ecspresso.ParseCLIv2([]string{
+		"run", "--log-sink=stdout", "--log-sink=s3://bucket/logs/task.log",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff([]ecspresso.LogSink{"stdout", "s3://bucket/logs/task.log"}, cliopts.Run.LogSinks); diff != "" {
+		t.Error(diff)
+	}
+}
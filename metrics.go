@@ -0,0 +1,146 @@
+package ecspresso
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const containerInsightsNamespace = "ECS/ContainerInsights"
+
+var (
+	// Container Insights metrics are available a few minutes after the task stopped.
+	metricsRetryInterval = 30 * time.Second
+	metricsMaxAttempts   = 4
+)
+
+// RunMetrics represents the peak resource utilization of the task from Container Insights.
+type RunMetrics struct {
+	CPUUtilized       float64 `json:"cpu_utilized"`       // CPU units
+	CPUReserved       float64 `json:"cpu_reserved"`       // CPU units
+	MemoryUtilized    float64 `json:"memory_utilized"`    // MiB
+	MemoryReserved    float64 `json:"memory_reserved"`    // MiB
+	CPUUtilization    float64 `json:"cpu_utilization"`    // percent of reserved
+	MemoryUtilization float64 `json:"memory_utilization"` // percent of reserved
+}
+
+// captureTaskMetrics fetches the peak CPU and memory utilization of the task from Container Insights.
+// It returns nil with warnings when the metrics are not available.
+func (d *App) captureTaskMetrics(ctx context.Context, result *RunResult) *RunMetrics {
+	if !d.containerInsightsEnabled(ctx) {
+		d.Log("[WARNING] Container Insights is not enabled on cluster %s. metrics are not captured", d.Cluster)
+		return nil
+	}
+	start, end := aws.ToTime(result.StartedAt), aws.ToTime(result.StoppedAt)
+	if start.IsZero() || end.IsZero() {
+		d.Log("[WARNING] the task has not started or stopped. metrics are not captured")
+		return nil
+	}
+	family, _, _ := strings.Cut(arnToName(result.TaskDefinitionArn), ":")
+	in := taskMetricDataInput(arnToName(d.Cluster), family, arnToName(result.TaskArn), start, end)
+
+	client := cloudwatch.NewFromConfig(d.config.awsv2Config)
+	for attempt := 1; ; attempt++ {
+		out, err := client.GetMetricData(ctx, in)
+		if err != nil {
+			d.Log("[WARNING] failed to get metrics of the task: %s", err)
+			return nil
+		}
+		if m := runMetricsOf(out.MetricDataResults); m != nil {
+			d.Log("Peak utilization of the task: cpu %.1f%% (%.0f/%.0f units), memory %.1f%% (%.0f/%.0f MiB)",
+				m.CPUUtilization, m.CPUUtilized, m.CPUReserved, m.MemoryUtilization, m.MemoryUtilized, m.MemoryReserved)
+			return m
+		}
+		if attempt >= metricsMaxAttempts {
+			d.Log("[WARNING] metrics of the task are not found in %s. Container Insights may not have collected them yet", containerInsightsNamespace)
+			return nil
+		}
+		d.Log("Waiting for metrics of the task to be available (%d/%d)", attempt, metricsMaxAttempts)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(metricsRetryInterval):
+		}
+	}
+}
+
+func (d *App) containerInsightsEnabled(ctx context.Context) bool {
+	out, err := d.ecs.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{d.Cluster},
+		Include:  []types.ClusterField{types.ClusterFieldSettings},
+	})
+	if err != nil || len(out.Clusters) == 0 {
+		// may lack the permission. try to get metrics anyway
+		d.Log("[DEBUG] failed to describe cluster settings: %v", err)
+		return true
+	}
+	for _, s := range out.Clusters[0].Settings {
+		if s.Name == types.ClusterSettingNameContainerInsights {
+			return aws.ToString(s.Value) != "disabled"
+		}
+	}
+	return false
+}
+
+var taskMetricNames = []string{"CpuUtilized", "CpuReserved", "MemoryUtilized", "MemoryReserved"}
+
+func taskMetricDataInput(cluster, family, taskID string, start, end time.Time) *cloudwatch.GetMetricDataInput {
+	in := &cloudwatch.GetMetricDataInput{
+		// include the minutes of the start and the end
+		StartTime: aws.Time(start.Truncate(time.Minute)),
+		EndTime:   aws.Time(end.Truncate(time.Minute).Add(time.Minute)),
+	}
+	for _, name := range taskMetricNames {
+		in.MetricDataQueries = append(in.MetricDataQueries, cwTypes.MetricDataQuery{
+			Id: aws.String(strings.ToLower(name)),
+			MetricStat: &cwTypes.MetricStat{
+				Metric: &cwTypes.Metric{
+					Namespace:  aws.String(containerInsightsNamespace),
+					MetricName: aws.String(name),
+					Dimensions: []cwTypes.Dimension{
+						{Name: aws.String("ClusterName"), Value: aws.String(cluster)},
+						{Name: aws.String("TaskDefinitionFamily"), Value: aws.String(family)},
+						{Name: aws.String("TaskId"), Value: aws.String(taskID)},
+					},
+				},
+				Period: aws.Int32(60),
+				Stat:   aws.String("Maximum"),
+			},
+		})
+	}
+	return in
+}
+
+// runMetricsOf returns the peak values of the metrics. It returns nil if no data points exist.
+func runMetricsOf(results []cwTypes.MetricDataResult) *RunMetrics {
+	peaks := map[string]float64{}
+	for _, r := range results {
+		for _, v := range r.Values {
+			if id := aws.ToString(r.Id); v > peaks[id] {
+				peaks[id] = v
+			}
+		}
+	}
+	if len(peaks) == 0 {
+		return nil
+	}
+	m := &RunMetrics{
+		CPUUtilized:    peaks["cpuutilized"],
+		CPUReserved:    peaks["cpureserved"],
+		MemoryUtilized: peaks["memoryutilized"],
+		MemoryReserved: peaks["memoryreserved"],
+	}
+	if m.CPUReserved > 0 {
+		m.CPUUtilization = m.CPUUtilized / m.CPUReserved * 100
+	}
+	if m.MemoryReserved > 0 {
+		m.MemoryUtilization = m.MemoryUtilized / m.MemoryReserved * 100
+	}
+	return m
+}
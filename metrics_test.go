@@ -0,0 +1,61 @@
+package ecspresso_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	cwTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestRunMetricsOf(t *testing.T) {
+	m := ecspresso.RunMetricsOf([]cwTypes.MetricDataResult{
+		{Id: aws.String("cpuutilized"), Values: []float64{10, 128, 64}},
+		{Id: aws.String("cpureserved"), Values: []float64{256, 256}},
+		{Id: aws.String("memoryutilized"), Values: []float64{300, 256}},
+		{Id: aws.String("memoryreserved"), Values: []float64{512}},
+	})
+	if m == nil {
+		t.Fatal("expected metrics, got nil")
+	}
+	expected := ecspresso.RunMetrics{
+		CPUUtilized:       128,
+		CPUReserved:       256,
+		MemoryUtilized:    300,
+		MemoryReserved:    512,
+		CPUUtilization:    50,
+		MemoryUtilization: 300.0 / 512 * 100,
+	}
+	if *m != expected {
+		t.Errorf("unexpected metrics: %#v", m)
+	}
+
+	if m := ecspresso.RunMetricsOf([]cwTypes.MetricDataResult{{Id: aws.String("cpuutilized")}}); m != nil {
+		t.Errorf("expected nil for no data points, got %#v", m)
+	}
+}
+
+func TestTaskMetricDataInput(t *testing.T) {
+	start := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	end := start.Add(90 * time.Second)
+	in := ecspresso.TaskMetricDataInput("default", "app", "abcdef", start, end)
+	if s := aws.ToTime(in.StartTime); !s.Equal(time.Date(2023, 1, 2, 3, 4, 0, 0, time.UTC)) {
+		t.Errorf("unexpected start time: %s", s)
+	}
+	if e := aws.ToTime(in.EndTime); !e.Equal(time.Date(2023, 1, 2, 3, 6, 0, 0, time.UTC)) {
+		t.Errorf("unexpected end time: %s", e)
+	}
+	if len(in.MetricDataQueries) != 4 {
+		t.Fatalf("expected 4 queries, got %d", len(in.MetricDataQueries))
+	}
+	for _, q := range in.MetricDataQueries {
+		dims := map[string]string{}
+		for _, d := range q.MetricStat.Metric.Dimensions {
+			dims[aws.ToString(d.Name)] = aws.ToString(d.Value)
+		}
+		if dims["ClusterName"] != "default" || dims["TaskDefinitionFamily"] != "app" || dims["TaskId"] != "abcdef" {
+			t.Errorf("unexpected dimensions of %s: %v", aws.ToString(q.Id), dims)
+		}
+	}
+}
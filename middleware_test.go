@@ -2,20 +2,29 @@ package ecspresso_test
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	logsTypes "github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/smithy-go/middleware"
 	smithyhttp "github.com/aws/smithy-go/transport/http"
 )
 
+var runTaskCalls, stopTaskCalls int
+
 var middlewareResults = map[string]func(string) any{
 	"DescribeServices": func(family string) any {
 		return &ecs.DescribeServicesOutput{
 			Services: []types.Service{
 				{
+					ServiceArn:     ptr("arn:aws:ecs:ap-northeast-1:123456789012:service/default2/test"),
+					Status:         ptr("ACTIVE"),
 					TaskDefinition: ptr(fmt.Sprintf("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/%s:39", family)),
 				},
 			},
@@ -31,6 +40,85 @@ var middlewareResults = map[string]func(string) any{
 			},
 		}
 	},
+	"ListTagsForResource": func(family string) any {
+		return &ecs.ListTagsForResourceOutput{
+			Tags: []types.Tag{
+				{Key: ptr("Team"), Value: ptr("service-team")},
+				{Key: ptr("Env"), Value: ptr("prod")},
+			},
+		}
+	},
+	"DescribeClusters": func(family string) any {
+		return &ecs.DescribeClustersOutput{
+			Clusters: []types.Cluster{{ClusterName: ptr("default")}},
+		}
+	},
+	// RunTask always fails to place the task, and counts the calls
+	"RunTask": func(family string) any {
+		runTaskCalls++
+		return &ecs.RunTaskOutput{
+			Failures: []types.Failure{{Reason: ptr("RESOURCE:MEMORY")}},
+		}
+	},
+	// no log streams are created yet
+	"DescribeLogStreams": func(family string) any {
+		return &cloudwatchlogs.DescribeLogStreamsOutput{}
+	},
+	"GetLogEvents": func(family string) any {
+		return &cloudwatchlogs.GetLogEventsOutput{
+			Events: []logsTypes.OutputLogEvent{{Message: ptr("hello"), Timestamp: ptr(int64(0))}},
+		}
+	},
+	"RegisterTaskDefinition": func(family string) any {
+		return &ecs.RegisterTaskDefinitionOutput{
+			TaskDefinition: &types.TaskDefinition{
+				TaskDefinitionArn: ptr("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/" + family + ":46"),
+				Family:            ptr(family),
+				Revision:          46,
+			},
+		}
+	},
+	"StopTask": func(family string) any {
+		stopTaskCalls++
+		return &ecs.StopTaskOutput{}
+	},
+	"ListTasks": func(family string) any {
+		return &ecs.ListTasksOutput{
+			TaskArns: []string{
+				"arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001",
+				"arn:aws:ecs:ap-northeast-1:123456789012:task/default/0002",
+				"arn:aws:ecs:ap-northeast-1:123456789012:task/default/0003",
+			},
+		}
+	},
+	"DescribeTasks": func(family string) any {
+		task := func(id string, rev int, stoppedAt time.Time, exitCode int32, startedBy string) types.Task {
+			return types.Task{
+				TaskArn:           ptr("arn:aws:ecs:ap-northeast-1:123456789012:task/default/" + id),
+				StartedBy:         ptr(startedBy),
+				TaskDefinitionArn: ptr(fmt.Sprintf("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/%s:%d", family, rev)),
+				StoppedAt:         &stoppedAt,
+				LastStatus:        ptr("STOPPED"),
+				StopCode:          types.TaskStopCodeEssentialContainerExited,
+				Containers: []types.Container{
+					{Name: ptr("app"), ExitCode: &exitCode},
+				},
+				Overrides: &types.TaskOverride{
+					ContainerOverrides: []types.ContainerOverride{
+						{Name: ptr("app"), Command: []string{"echo", id}},
+					},
+				},
+			}
+		}
+		now := time.Now()
+		return &ecs.DescribeTasksOutput{
+			Tasks: []types.Task{
+				task("0001", 38, now.Add(-3*time.Hour), 0, "batch"),
+				task("0002", 40, now.Add(-2*time.Hour), 0, "ecspresso-run"),
+				task("0003", 41, now.Add(-1*time.Hour), 1, "ecspresso-run"),
+			},
+		}
+	},
 }
 
 func SDKTestingMiddleware(family string) func(*middleware.Stack) error {
@@ -41,6 +129,23 @@ func SDKTestingMiddleware(family string) func(*middleware.Stack) error {
 				func(ctx context.Context, in middleware.FinalizeInput, handler middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
 					req := in.Request.(*smithyhttp.Request)
 					target := strings.SplitN(req.Header.Get("X-Amz-Target"), ".", 2)[1]
+					if target == "DescribeTaskDefinition" {
+						out, err := describeTaskDefinitionResult(family, req)
+						return middleware.FinalizeOutput{Result: out}, middleware.Metadata{}, err
+					}
+					if target == "ListTaskDefinitions" {
+						out, err := listTaskDefinitionsResult(family, req)
+						return middleware.FinalizeOutput{Result: out}, middleware.Metadata{}, err
+					}
+					if target == "DescribeTasks" {
+						out, err := describeTasksResult(family, req)
+						return middleware.FinalizeOutput{Result: out}, middleware.Metadata{}, err
+					}
+					if target == "ListTasks" {
+						if err := validateListTasksInput(req); err != nil {
+							return middleware.FinalizeOutput{}, middleware.Metadata{}, err
+						}
+					}
 					return middleware.FinalizeOutput{
 						Result: middlewareResults[target](family),
 					}, middleware.Metadata{}, nil
@@ -50,3 +155,93 @@ func SDKTestingMiddleware(family string) func(*middleware.Stack) error {
 		)
 	}
 }
+
+// describeTaskDefinitionResult returns the task definition of the revision 36-45 of the family in the mock.
+func describeTaskDefinitionResult(family string, req *smithyhttp.Request) (any, error) {
+	var in struct {
+		TaskDefinition string `json:"taskDefinition"`
+	}
+	if err := json.NewDecoder(req.GetStream()).Decode(&in); err != nil {
+		return nil, err
+	}
+	name := strings.TrimPrefix(in.TaskDefinition, "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/")
+	f, rev, _ := strings.Cut(name, ":")
+	if r, err := strconv.Atoi(rev); err != nil || f != family || r < 36 || r > 45 {
+		return nil, &types.ClientException{Message: ptr("Unable to describe task definition.")}
+	}
+	return &ecs.DescribeTaskDefinitionOutput{
+		TaskDefinition: &types.TaskDefinition{
+			TaskDefinitionArn: ptr("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/" + name),
+			Family:            ptr(family),
+			ContainerDefinitions: []types.ContainerDefinition{
+				{Name: ptr("app")},
+			},
+		},
+	}, nil
+}
+
+// validateListTasksInput rejects startedBy with the other filters as ECS does.
+func validateListTasksInput(req *smithyhttp.Request) error {
+	var in struct {
+		StartedBy         string `json:"startedBy"`
+		Family            string `json:"family"`
+		ServiceName       string `json:"serviceName"`
+		ContainerInstance string `json:"containerInstance"`
+		DesiredStatus     string `json:"desiredStatus"`
+		LaunchType        string `json:"launchType"`
+	}
+	if err := json.NewDecoder(req.GetStream()).Decode(&in); err != nil {
+		return err
+	}
+	if in.StartedBy != "" && (in.Family != "" || in.ServiceName != "" || in.ContainerInstance != "" || in.DesiredStatus != "" || in.LaunchType != "") {
+		return &types.InvalidParameterException{Message: ptr("startedBy may not be specified with the other filters.")}
+	}
+	return nil
+}
+
+// describeTasksResult returns the tasks requested in the mock, in the order of the request.
+func describeTasksResult(family string, req *smithyhttp.Request) (any, error) {
+	var in struct {
+		Tasks []string `json:"tasks"`
+	}
+	if err := json.NewDecoder(req.GetStream()).Decode(&in); err != nil {
+		return nil, err
+	}
+	out := middlewareResults["DescribeTasks"](family).(*ecs.DescribeTasksOutput)
+	var tasks []types.Task
+	for _, id := range in.Tasks {
+		for _, t := range out.Tasks {
+			if strings.HasSuffix(*t.TaskArn, "/"+id) || *t.TaskArn == id {
+				tasks = append(tasks, t)
+			}
+		}
+	}
+	if len(tasks) > 0 {
+		out.Tasks = tasks
+	}
+	return out, nil
+}
+
+// listTaskDefinitionsResult returns the revisions of the family in the mock.
+// All the revisions of the family "deregistered" are INACTIVE.
+func listTaskDefinitionsResult(family string, req *smithyhttp.Request) (any, error) {
+	var in struct {
+		FamilyPrefix string `json:"familyPrefix"`
+		Status       string `json:"status"`
+	}
+	if err := json.NewDecoder(req.GetStream()).Decode(&in); err != nil {
+		return nil, err
+	}
+	if in.FamilyPrefix == "deregistered" {
+		if in.Status != string(types.TaskDefinitionStatusInactive) {
+			return &ecs.ListTaskDefinitionsOutput{}, nil
+		}
+		return &ecs.ListTaskDefinitionsOutput{
+			TaskDefinitionArns: []string{"arn:aws:ecs:ap-northeast-1:123456789012:task-definition/deregistered:3"},
+		}, nil
+	}
+	if in.Status == string(types.TaskDefinitionStatusInactive) {
+		return &ecs.ListTaskDefinitionsOutput{}, nil
+	}
+	return middlewareResults["ListTaskDefinitions"](family), nil
+}
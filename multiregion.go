@@ -0,0 +1,72 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/samber/lo"
+)
+
+// withRegion returns a copy of the App which uses the AWS clients for the region.
+func (d *App) withRegion(region string) *App {
+	cfg := d.config.awsv2Config.Copy()
+	cfg.Region = region
+	nd := d.withAWSConfig(cfg)
+	nd.config.Region = region
+	nd.logPrefix = region + " "
+	return nd
+}
+
+type regionRunResult struct {
+	region string
+	result *RunResult
+	err    error
+}
+
+// runMultiRegion runs the task in each region concurrently.
+// A failure in a region does not block the others, and the returned error enumerates the failed regions.
+func (d *App) runMultiRegion(ctx context.Context, opt RunOption) error {
+	regions := lo.Uniq(opt.Regions)
+	if opt.RunID == nil {
+		// the run ID is shared by all the regions
+		opt.RunID = aws.String(newRunID())
+	}
+	d.Log("Running task in %d regions: %s", len(regions), strings.Join(regions, ", "))
+
+	results := make([]regionRunResult, len(regions))
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		i, region := i, region
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ropt := opt
+			ropt.Regions = nil
+			result, err := d.withRegion(region).run(ctx, ropt)
+			results[i] = regionRunResult{region: region, result: result, err: err}
+		}()
+	}
+	wg.Wait()
+
+	var failures []string
+	for _, r := range results {
+		task := "-"
+		if r.result != nil {
+			task = arnToName(r.result.TaskArn)
+		}
+		if r.err != nil {
+			d.Log("[WARNING] region %s: task %s failed: %s", r.region, task, r.err)
+			failures = append(failures, fmt.Sprintf("%s: %s", r.region, r.err))
+			continue
+		}
+		d.Log("region %s: task %s succeeded", r.region, task)
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("run task failed in %d of %d regions. %s", len(failures), len(regions), strings.Join(failures, "; "))
+	}
+	d.Log("Run task completed in all %d regions", len(regions))
+	return nil
+}
@@ -0,0 +1,125 @@
+package ecspresso
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/samber/lo"
+)
+
+// mergeTaskOverride deep-merges src over dst.
+// Container overrides are merged by the container name, and the environment variables are merged by the name.
+// The fields set in src take precedence.
+func mergeTaskOverride(dst *types.TaskOverride, src types.TaskOverride) {
+	if src.Cpu != nil {
+		dst.Cpu = src.Cpu
+	}
+	if src.Memory != nil {
+		dst.Memory = src.Memory
+	}
+	if src.TaskRoleArn != nil {
+		dst.TaskRoleArn = src.TaskRoleArn
+	}
+	if src.ExecutionRoleArn != nil {
+		dst.ExecutionRoleArn = src.ExecutionRoleArn
+	}
+	if src.EphemeralStorage != nil {
+		dst.EphemeralStorage = src.EphemeralStorage
+	}
+	if len(src.InferenceAcceleratorOverrides) > 0 {
+		dst.InferenceAcceleratorOverrides = src.InferenceAcceleratorOverrides
+	}
+	for _, sco := range src.ContainerOverrides {
+		mergeContainerOverride(containerOverrideOf(dst, aws.ToString(sco.Name)), sco)
+	}
+}
+
+func mergeContainerOverride(dst *types.ContainerOverride, src types.ContainerOverride) {
+	if src.Command != nil {
+		dst.Command = src.Command
+	}
+	if src.Cpu != nil {
+		dst.Cpu = src.Cpu
+	}
+	if src.Memory != nil {
+		dst.Memory = src.Memory
+	}
+	if src.MemoryReservation != nil {
+		dst.MemoryReservation = src.MemoryReservation
+	}
+	if src.EnvironmentFiles != nil {
+		dst.EnvironmentFiles = src.EnvironmentFiles
+	}
+	if src.ResourceRequirements != nil {
+		dst.ResourceRequirements = src.ResourceRequirements
+	}
+	for _, kv := range src.Environment {
+		replaced := false
+		for i := range dst.Environment {
+			if aws.ToString(dst.Environment[i].Name) == aws.ToString(kv.Name) {
+				dst.Environment[i] = kv
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			dst.Environment = append(dst.Environment, kv)
+		}
+	}
+}
+
+// overridesProfile returns the overrides profile defined in overrides_profiles of the config.
+func (d *App) overridesProfile(name string) (*types.TaskOverride, error) {
+	if p := d.config.OverridesProfiles[name]; p != nil {
+		return p, nil
+	}
+	names := make([]string, 0, len(d.config.OverridesProfiles))
+	for n := range d.config.OverridesProfiles {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, ErrNotFound(fmt.Sprintf("overrides profile %s is not found. no overrides_profiles are defined in the config", name))
+	}
+	return nil, ErrNotFound(fmt.Sprintf("overrides profile %s is not found. available profiles: %s", name, strings.Join(names, ", ")))
+}
+
+// validateContainerOverrides validates that the containers in the overrides exist in the task definition.
+// ECS ignores the overrides for unknown containers silently.
+func validateContainerOverrides(td *TaskDefinitionInput, ov *types.TaskOverride, extra ...string) error {
+	known := make([]string, 0, len(td.ContainerDefinitions)+len(extra))
+	for _, c := range td.ContainerDefinitions {
+		known = append(known, aws.ToString(c.Name))
+	}
+	known = append(known, extra...)
+	var unknown []string
+	for _, co := range ov.ContainerOverrides {
+		name := aws.ToString(co.Name)
+		if !lo.Contains(known, name) {
+			unknown = append(unknown, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(unknown) > 0 {
+		return fmt.Errorf("unknown containers in the overrides: %s. the containers in task definition %s are %s",
+			strings.Join(unknown, ", "), aws.ToString(td.Family), strings.Join(known, ", "))
+	}
+	return nil
+}
+
+const (
+	minEphemeralStorageGiB = 21
+	maxEphemeralStorageGiB = 200
+)
+
+// setEphemeralStorage sets the ephemeral storage size to the overrides (--ephemeral-storage).
+// Fargate allows 21-200 GiB.
+func setEphemeralStorage(ov *types.TaskOverride, sizeInGiB int32) error {
+	if sizeInGiB < minEphemeralStorageGiB || sizeInGiB > maxEphemeralStorageGiB {
+		return fmt.Errorf("invalid ephemeral-storage %d GiB. it must be %d-%d GiB", sizeInGiB, minEphemeralStorageGiB, maxEphemeralStorageGiB)
+	}
+	ov.EphemeralStorage = &types.EphemeralStorage{SizeInGiB: sizeInGiB}
+	return nil
+}
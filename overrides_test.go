@@ -0,0 +1,198 @@
+package ecspresso_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestMergeTaskOverride(t *testing.T) {
+	base := types.TaskOverride{
+		Cpu:    aws.String("256"),
+		Memory: aws.String("512"),
+		ContainerOverrides: []types.ContainerOverride{
+			{
+				Name:    aws.String("app"),
+				Command: []string{"run", "batch"},
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("ENV"), Value: aws.String("staging")},
+					{Name: aws.String("DEBUG"), Value: aws.String("false")},
+				},
+			},
+			{
+				Name:   aws.String("sidecar"),
+				Memory: aws.Int32(128),
+			},
+		},
+	}
+	inline := types.TaskOverride{
+		Memory: aws.String("1024"),
+		ContainerOverrides: []types.ContainerOverride{
+			{
+				Name: aws.String("app"),
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("DEBUG"), Value: aws.String("true")},
+					{Name: aws.String("TRACE"), Value: aws.String("1")},
+				},
+			},
+			{
+				Name:    aws.String("worker"),
+				Command: []string{"work"},
+			},
+		},
+	}
+	ecspresso.MergeTaskOverride(&base, inline)
+
+	expected := types.TaskOverride{
+		Cpu:    aws.String("256"),
+		Memory: aws.String("1024"),
+		ContainerOverrides: []types.ContainerOverride{
+			{
+				Name:    aws.String("app"),
+				Command: []string{"run", "batch"},
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("ENV"), Value: aws.String("staging")},
+					{Name: aws.String("DEBUG"), Value: aws.String("true")},
+					{Name: aws.String("TRACE"), Value: aws.String("1")},
+				},
+			},
+			{
+				Name:   aws.String("sidecar"),
+				Memory: aws.Int32(128),
+			},
+			{
+				Name:    aws.String("worker"),
+				Command: []string{"work"},
+			},
+		},
+	}
+	opt := cmpopts.IgnoreUnexported(types.TaskOverride{}, types.ContainerOverride{}, types.KeyValuePair{})
+	if diff := cmp.Diff(expected, base, opt); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestOverridesProfile(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/overrides-profiles.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ov, err := app.TaskOverrideForRun(ecspresso.RunOption{
+		OverridesProfile: aws.String("large"),
+		TaskOverrideStr:  `{"containerOverrides":[{"name":"app","environment":[{"name":"DEBUG","value":"true"}]}]}`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := types.TaskOverride{
+		Memory: aws.String("4096"),
+		ContainerOverrides: []types.ContainerOverride{
+			{
+				Name: aws.String("app"),
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("TIER"), Value: aws.String("large")},
+					{Name: aws.String("DEBUG"), Value: aws.String("true")},
+				},
+			},
+		},
+	}
+	opt := cmpopts.IgnoreUnexported(types.TaskOverride{}, types.ContainerOverride{}, types.KeyValuePair{})
+	if diff := cmp.Diff(expected, ov, opt); diff != "" {
+		t.Error(diff)
+	}
+
+	_, err = app.TaskOverrideForRun(ecspresso.RunOption{OverridesProfile: aws.String("medium")})
+	if err == nil || !strings.Contains(err.Error(), "available profiles: large, small") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateContainerOverrides(t *testing.T) {
+	td := &ecspresso.TaskDefinitionInput{
+		Family: aws.String("app"),
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("app")},
+			{Name: aws.String("nginx")},
+		},
+	}
+	ov := &types.TaskOverride{
+		ContainerOverrides: []types.ContainerOverride{
+			{Name: aws.String("app")},
+			{Name: aws.String("nginx")},
+		},
+	}
+	if err := ecspresso.ValidateContainerOverrides(td, ov); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	ov.ContainerOverrides = append(ov.ContainerOverrides,
+		types.ContainerOverride{Name: aws.String("ap")},
+		types.ContainerOverride{Name: aws.String("debug-sidecar")},
+	)
+	err := ecspresso.ValidateContainerOverrides(td, ov)
+	if err == nil {
+		t.Fatal("expected error for unknown containers")
+	}
+	expected := `unknown containers in the overrides: "ap", "debug-sidecar". the containers in task definition app are app, nginx`
+	if err.Error() != expected {
+		t.Errorf("unexpected error message: %s", err)
+	}
+
+	err = ecspresso.ValidateContainerOverrides(td, ov, "debug-sidecar")
+	if err == nil || !strings.Contains(err.Error(), `"ap"`) || strings.Contains(err.Error(), `"debug-sidecar"`) {
+		t.Errorf("extra containers should be allowed: %v", err)
+	}
+}
+
+func TestOverridesFileURL(t *testing.T) {
+	ctx := context.Background()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/overrides.json" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"containerOverrides":[{"name":"app","command":["echo","{{ env "OVERRIDES_FILE_URL_TEST" "default" }}"]}]}`))
+	}))
+	defer ts.Close()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/overrides-profiles.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ov, err := app.TaskOverrideForRun(ecspresso.RunOption{TaskOverrideFile: ts.URL + "/overrides.json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ov.ContainerOverrides) != 1 || strings.Join(ov.ContainerOverrides[0].Command, " ") != "echo default" {
+		t.Errorf("unexpected overrides %#v", ov.ContainerOverrides)
+	}
+
+	missing := ts.URL + "/missing.json"
+	_, err = app.TaskOverrideForRun(ecspresso.RunOption{TaskOverrideFile: missing})
+	if err == nil || !strings.Contains(err.Error(), missing) || !strings.Contains(err.Error(), "404") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestSetEphemeralStorage(t *testing.T) {
+	ov := types.TaskOverride{EphemeralStorage: &types.EphemeralStorage{SizeInGiB: 30}}
+	if err := ecspresso.SetEphemeralStorage(&ov, 100); err != nil {
+		t.Fatal(err)
+	}
+	if ov.EphemeralStorage.SizeInGiB != 100 {
+		t.Errorf("unexpected size %d", ov.EphemeralStorage.SizeInGiB)
+	}
+	for _, size := range []int32{-1, 20, 201} {
+		if err := ecspresso.SetEphemeralStorage(&ov, size); err == nil || !strings.Contains(err.Error(), "21-200 GiB") {
+			t.Errorf("%d: unexpected error: %v", size, err)
+		}
+	}
+}
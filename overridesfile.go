@@ -0,0 +1,74 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// readOverridesFile reads the overrides file from the local path, s3://BUCKET/KEY or http(s)://URL.
+// The remote content is expanded by the template functions as well as the local file.
+func (d *App) readOverridesFile(ctx context.Context, path string) ([]byte, error) {
+	var src []byte
+	var err error
+	switch {
+	case strings.HasPrefix(path, "s3://"):
+		src, err = d.fetchS3Object(ctx, path)
+	case strings.HasPrefix(path, "http://"), strings.HasPrefix(path, "https://"):
+		src, err = fetchURL(ctx, path)
+	default:
+		return d.readDefinitionFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return d.renderTemplate(src)
+}
+
+func (d *App) fetchS3Object(ctx context.Context, s string) ([]byte, error) {
+	u, err := url.Parse(s)
+	if err != nil || u.Host == "" || strings.TrimPrefix(u.Path, "/") == "" {
+		return nil, fmt.Errorf("invalid s3 url %s. s3://BUCKET/KEY is required", s)
+	}
+	client := s3.NewFromConfig(d.config.awsv2Config)
+	out, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(u.Host),
+		Key:    aws.String(strings.TrimPrefix(u.Path, "/")),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object from s3: %w", err)
+	}
+	defer out.Body.Close()
+	b, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object from s3: %w", err)
+	}
+	return b, nil
+}
+
+func fetchURL(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "ecspresso/"+Version)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch from the url: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("failed to fetch from the url: %s", resp.Status)
+	}
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the response: %w", err)
+	}
+	return b, nil
+}
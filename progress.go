@@ -0,0 +1,37 @@
+package ecspresso
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// progressInterval is the default interval of the progress of waiting for the task.
+var progressInterval = 30 * time.Second
+
+// reportTaskProgress logs the elapsed time and the last status of the task every interval until ctx is done,
+// not to look hung while waiting for the task without the logs.
+func (d *App) reportTaskProgress(ctx context.Context, task *types.Task, startedAt time.Time, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	id := arnToName(aws.ToString(task.TaskArn))
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		elapsed := time.Since(startedAt).Round(time.Second)
+		out, err := d.ecs.DescribeTasks(ctx, d.DescribeTasksInput(task))
+		if err != nil || len(out.Tasks) == 0 {
+			if ctx.Err() == nil {
+				d.Log("[DEBUG] failed to describe task %s: %v", id, err)
+			}
+			d.Log("Waiting for task ID %s (elapsed %s)", id, elapsed)
+			continue
+		}
+		d.Log("Task ID %s is %s (elapsed %s)", id, aws.ToString(out.Tasks[0].LastStatus), elapsed)
+	}
+}
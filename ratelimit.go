@@ -0,0 +1,147 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/smithy-go"
+	"github.com/shogo82148/go-retry"
+)
+
+// runTaskThrottlePolicy is the retry policy for RunTask API throttled by ECS.
+// go-retry adds jitter to the delay.
+var runTaskThrottlePolicy = retry.Policy{
+	MinDelay: time.Second,
+	MaxDelay: 30 * time.Second,
+	MaxCount: 8,
+}
+
+// pacer paces successive calls at the rate (calls per second).
+type pacer struct {
+	mu   sync.Mutex
+	next time.Time
+}
+
+// runTaskPacer is shared by all the RunTask calls in the process.
+var runTaskPacer = &pacer{}
+
+// wait waits until the next call is allowed by the rate. rate <= 0 means unlimited.
+// The wait is logged by logf.
+func (p *pacer) wait(ctx context.Context, rate float64, logf func(string, ...interface{})) error {
+	if rate <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	now := time.Now()
+	at := p.next
+	if at.Before(now) {
+		at = now
+	}
+	p.next = at.Add(time.Duration(float64(time.Second) / rate))
+	p.mu.Unlock()
+
+	d := time.Until(at)
+	if d <= 0 {
+		return nil
+	}
+	logf("[DEBUG] pacing RunTask at %.2f calls/sec. waiting %s", rate, d.Round(time.Millisecond))
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// tokenBucket limits the calls to the rate (calls per second) allowing the burst.
+// A nil tokenBucket or rate <= 0 means unlimited.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// wait waits until a token is available and takes it.
+func (b *tokenBucket) wait(ctx context.Context) error {
+	if b == nil || b.rate <= 0 {
+		return nil
+	}
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+		d := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		t := time.NewTimer(d)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func isThrottlingError(err error) bool {
+	var ae smithy.APIError
+	if !errors.As(err, &ae) {
+		return false
+	}
+	switch ae.ErrorCode() {
+	case "ThrottlingException", "Throttling", "TooManyRequestsException", "RequestLimitExceeded":
+		return true
+	}
+	return false
+}
+
+// callRunTask calls RunTask API paced by the rate, and retries with backoff on throttling.
+func (d *App) callRunTask(ctx context.Context, in *ecs.RunTaskInput, rate float64) (*ecs.RunTaskOutput, error) {
+	var out *ecs.RunTaskOutput
+	attempt := 0
+	err := runTaskThrottlePolicy.Do(ctx, func() error {
+		attempt++
+		if err := runTaskPacer.wait(ctx, rate, d.Log); err != nil {
+			return retry.MarkPermanent(err)
+		}
+		var err error
+		out, err = d.ecs.RunTask(ctx, in)
+		if err == nil {
+			return nil
+		}
+		if !isThrottlingError(err) {
+			return retry.MarkPermanent(err)
+		}
+		d.Log("[WARNING] RunTask is throttled (attempt %d/%d). retrying with backoff: %s", attempt, runTaskThrottlePolicy.MaxCount, err)
+		return err
+	})
+	return out, err
+}
@@ -0,0 +1,112 @@
+package ecspresso_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestPacer(t *testing.T) {
+	ctx := context.Background()
+	var logs []string
+	wait := ecspresso.NewPacer(func(f string, v ...interface{}) {
+		logs = append(logs, fmt.Sprintf(f, v...))
+	})
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := wait(ctx, 10); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the first call is not paced
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("calls are not paced: %s", elapsed)
+	}
+	if len(logs) != 2 {
+		t.Errorf("the waits must be logged: %v", logs)
+	}
+
+	start = time.Now()
+	for i := 0; i < 3; i++ {
+		if err := wait(ctx, 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("unlimited calls must not be paced: %s", elapsed)
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	ctx := context.Background()
+	wait := ecspresso.NewTokenBucket(10, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the burst is not limited, and the rest are limited to 10 calls/sec
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("calls are not limited: %s", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	wait = ecspresso.NewTokenBucket(0.1, 1)
+	if err := wait(ctx); err != nil {
+		t.Errorf("the first call must not be limited: %s", err)
+	}
+	if err := wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	wait = ecspresso.NewTokenBucket(0, 0)
+	start = time.Now()
+	for i := 0; i < 10; i++ {
+		if err := wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+		t.Errorf("unlimited calls must not be limited: %s", elapsed)
+	}
+}
+
+func TestNextLogPollInterval(t *testing.T) {
+	base := 5 * time.Second
+	for _, c := range []struct {
+		current   time.Duration
+		throttled bool
+		expected  time.Duration
+	}{
+		{base, true, 10 * time.Second},
+		{40 * time.Second, true, time.Minute},
+		{time.Minute, true, time.Minute},
+		{time.Minute, false, 30 * time.Second},
+		{8 * time.Second, false, base},
+		{base, false, base},
+	} {
+		if got := ecspresso.NextLogPollInterval(c.current, base, c.throttled); got != c.expected {
+			t.Errorf("current %s throttled %v: expected %s, got %s", c.current, c.throttled, c.expected, got)
+		}
+	}
+}
+
+func TestIsThrottlingError(t *testing.T) {
+	throttled := fmt.Errorf("wrapped: %w", &smithy.GenericAPIError{Code: "ThrottlingException"})
+	if !ecspresso.IsThrottlingError(throttled) {
+		t.Error("ThrottlingException must be a throttling error")
+	}
+	if ecspresso.IsThrottlingError(&smithy.GenericAPIError{Code: "InvalidParameterException"}) {
+		t.Error("InvalidParameterException must not be a throttling error")
+	}
+	if ecspresso.IsThrottlingError(errors.New("throttled")) {
+		t.Error("non API error must not be a throttling error")
+	}
+}
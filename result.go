@@ -0,0 +1,308 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+const (
+	SeveritySuccess = "success"
+	SeverityFailure = "failure"
+)
+
+// RunResult represents a summary of the task run by ecspresso.
+type RunResult struct {
+	TaskArn           string            `json:"task_arn"`
+	TaskDefinitionArn string            `json:"task_definition_arn"`
+	RunID             string            `json:"run_id,omitempty"`
+	Container         string            `json:"container"`
+	ExitCode          *int32            `json:"exit_code,omitempty"`
+	Reason            string            `json:"reason,omitempty"`
+	StoppedReason     string            `json:"stopped_reason,omitempty"`
+	StopCode          string            `json:"stop_code,omitempty"`
+	Status            string            `json:"status,omitempty"`
+	ExitCodes         map[string]int32  `json:"exit_codes,omitempty"`
+	Containers        []ContainerResult `json:"containers,omitempty"`
+	Tags              map[string]string `json:"tags,omitempty"`
+	Severity          string            `json:"severity"`
+	Error             string            `json:"error,omitempty"`
+	StartedAt         *time.Time        `json:"started_at,omitempty"`
+	StoppedAt         *time.Time        `json:"stopped_at,omitempty"`
+	Timings           []RunPhaseTiming  `json:"timings,omitempty"`
+	Metrics           *RunMetrics       `json:"metrics,omitempty"`
+
+	// Task is the task described after the run. It is not serialized.
+	Task *types.Task `json:"-"`
+
+	processExitCode *int
+}
+
+// ContainerResult represents the status of a container of the stopped task.
+type ContainerResult struct {
+	Name       string `json:"name"`
+	LastStatus string `json:"last_status,omitempty"`
+	ExitCode   *int32 `json:"exit_code,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// failed reports whether the container exited with non-zero or stopped with a reason.
+func (c ContainerResult) failed() bool {
+	return (c.ExitCode != nil && *c.ExitCode != 0) || c.Reason != ""
+}
+
+func (c ContainerResult) String() string {
+	exitCode := "-"
+	if c.ExitCode != nil {
+		exitCode = strconv.FormatInt(int64(*c.ExitCode), 10)
+	}
+	s := fmt.Sprintf("container: %s, status: %s, exit code: %s", c.Name, c.LastStatus, exitCode)
+	if c.Reason != "" {
+		s += ", reason: " + c.Reason
+	}
+	return s
+}
+
+// Duration returns the time from the task started to stopped. It returns 0 if the task has not started or stopped.
+func (r *RunResult) Duration() time.Duration {
+	if r.StartedAt == nil || r.StoppedAt == nil {
+		return 0
+	}
+	return r.StoppedAt.Sub(*r.StartedAt)
+}
+
+// ConfigExitCodeSeverity represents a severity for a range of exit codes of the watch container.
+type ConfigExitCodeSeverity struct {
+	// ExitCodes is a single exit code ("137"), a range ("1-9") or an open range ("10-").
+	ExitCodes string `yaml:"exit_codes" json:"exit_codes"`
+	Severity  string `yaml:"severity" json:"severity"`
+	// ProcessExitCode is the exit code of ecspresso when the task exited with the severity.
+	ProcessExitCode *int `yaml:"process_exit_code,omitempty" json:"process_exit_code,omitempty"`
+
+	min, max int64
+}
+
+func (s *ConfigExitCodeSeverity) Restrict() error {
+	if s.Severity == "" {
+		return fmt.Errorf("severity is required for exit_codes %q", s.ExitCodes)
+	}
+	from, to, isRange := strings.Cut(s.ExitCodes, "-")
+	min, err := strconv.ParseInt(strings.TrimSpace(from), 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid exit_codes %q: %w", s.ExitCodes, err)
+	}
+	max := min
+	if isRange {
+		if to = strings.TrimSpace(to); to == "" {
+			max = 1<<31 - 1
+		} else if max, err = strconv.ParseInt(to, 10, 32); err != nil {
+			return fmt.Errorf("invalid exit_codes %q: %w", s.ExitCodes, err)
+		}
+	}
+	if min > max {
+		return fmt.Errorf("invalid exit_codes %q: %d is greater than %d", s.ExitCodes, min, max)
+	}
+	s.min, s.max = min, max
+	return nil
+}
+
+func (s *ConfigExitCodeSeverity) match(code int32) bool {
+	return s.min <= int64(code) && int64(code) <= s.max
+}
+
+// exitCodeSeverity returns the severity of the exit code.
+// The first matched exit_code_severities wins.
+// By default, 0 is success and others are failure.
+func (c *Config) exitCodeSeverity(code int32) (string, *int) {
+	for _, s := range c.ExitCodeSeverities {
+		if s.match(code) {
+			return s.Severity, s.ProcessExitCode
+		}
+	}
+	if code == 0 {
+		return SeveritySuccess, nil
+	}
+	return SeverityFailure, nil
+}
+
+// aggregateRunResults describes the rest of the tasks launched at once (--count)
+// and aggregates their failures with statusErr, which is the error of the first task.
+func (d *App) aggregateRunResults(ctx context.Context, tasks []types.Task, watchContainer *types.ContainerDefinition, statusErr error) error {
+	failed := 0
+	firstErr := statusErr
+	if statusErr != nil {
+		failed++
+	}
+	for i := 1; i < len(tasks); i++ {
+		_, err := d.describeRunResult(ctx, &tasks[i], watchContainer)
+		if err == nil {
+			continue
+		}
+		failed++
+		if firstErr == nil {
+			firstErr = err
+		}
+		d.Log("[WARNING] task %s failed: %s", arnToName(aws.ToString(tasks[i].TaskArn)), err)
+	}
+	if failed == 0 {
+		return nil
+	}
+	return fmt.Errorf("%d of %d tasks failed: %w", failed, len(tasks), firstErr)
+}
+
+// describeRunResult describes the stopped task and returns the result of the watch container.
+// The returned error is not nil when the task has failed.
+func (d *App) describeRunResult(ctx context.Context, task *types.Task, watchContainer *types.ContainerDefinition) (*RunResult, error) {
+	in := d.DescribeTasksInput(task)
+	in.Include = []types.TaskField{types.TaskFieldTags}
+	out, err := d.ecs.DescribeTasks(ctx, in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe tasks: %w", err)
+	}
+	if len(out.Failures) > 0 {
+		f := out.Failures[0]
+		d.Log("Task ARN: " + *f.Arn)
+		return nil, fmt.Errorf(*f.Reason)
+	}
+
+	ts := out.Tasks[0]
+	result := &RunResult{
+		TaskArn:           aws.ToString(ts.TaskArn),
+		TaskDefinitionArn: aws.ToString(ts.TaskDefinitionArn),
+		StoppedReason:     aws.ToString(ts.StoppedReason),
+		StopCode:          string(ts.StopCode),
+		Severity:          SeverityFailure,
+		StartedAt:         ts.StartedAt,
+		StoppedAt:         ts.StoppedAt,
+		Status:            aws.ToString(ts.LastStatus),
+	}
+	for _, c := range ts.Containers {
+		result.Containers = append(result.Containers, ContainerResult{
+			Name:       aws.ToString(c.Name),
+			LastStatus: aws.ToString(c.LastStatus),
+			ExitCode:   c.ExitCode,
+			Reason:     aws.ToString(c.Reason),
+		})
+		if c.ExitCode == nil {
+			continue
+		}
+		if result.ExitCodes == nil {
+			result.ExitCodes = map[string]int32{}
+		}
+		result.ExitCodes[aws.ToString(c.Name)] = *c.ExitCode
+	}
+	for _, t := range ts.Tags {
+		if result.Tags == nil {
+			result.Tags = map[string]string{}
+		}
+		result.Tags[aws.ToString(t.Key)] = aws.ToString(t.Value)
+	}
+	if ts.StopCode == types.TaskStopCodeTaskFailedToStart {
+		return result, fmt.Errorf("task failed to start: %s", aws.ToString(ts.StoppedReason))
+	}
+
+	var container *types.Container
+	for _, c := range ts.Containers {
+		if *c.Name == *watchContainer.Name {
+			container = &c
+			break
+		}
+	}
+	if container == nil {
+		container = &(ts.Containers[0])
+	}
+	result.Container = aws.ToString(container.Name)
+	result.ExitCode = container.ExitCode
+	result.Reason = aws.ToString(container.Reason)
+	if container.ExitCode != nil {
+		result.Severity, result.processExitCode = d.config.exitCodeSeverity(*container.ExitCode)
+	} else {
+		result.Severity = SeveritySuccess
+	}
+
+	if container.ExitCode != nil && *container.ExitCode != 0 {
+		msg := fmt.Sprintf("container: %s, exit code: %s", *container.Name, strconv.FormatInt(int64(*container.ExitCode), 10))
+		if container.Reason != nil {
+			msg += ", reason: " + *container.Reason
+		}
+		return result, fmt.Errorf(msg)
+	} else if container.Reason != nil {
+		result.Severity = SeverityFailure
+		return result, fmt.Errorf("container: %s, reason: %s", *container.Name, *container.Reason)
+	}
+	return result, nil
+}
+
+// runResultError returns the error of the failed run with the exit code of the process.
+// process_exit_code in exit_code_severities takes precedence over the exit code of the watch container propagated by propagate.
+// When the task stopped without the exit code (e.g. failed to start), statusErr is returned as is.
+func runResultError(result *RunResult, statusErr error, propagate bool) error {
+	if result == nil {
+		return statusErr
+	}
+	if result.processExitCode != nil {
+		return &ErrExitCode{Code: *result.processExitCode, Err: statusErr}
+	}
+	if propagate && result.ExitCode != nil && *result.ExitCode != 0 {
+		return &ErrExitCode{Code: int(*result.ExitCode), Err: statusErr}
+	}
+	return statusErr
+}
+
+// logRunResult logs the outcome of the run and tags the task with the result if required.
+func (d *App) logRunResult(ctx context.Context, result *RunResult, opt RunOption) {
+	exitCode := "-"
+	if result.ExitCode != nil {
+		exitCode = strconv.FormatInt(int64(*result.ExitCode), 10)
+	}
+	d.Log("Task %s exited. container: %s, exit code: %s, severity: %s",
+		arnToName(result.TaskArn), result.Container, exitCode, result.Severity)
+	d.logStoppedDetails(result)
+
+	var tags []types.Tag
+	if opt.TagSeverity {
+		tags = append(tags, types.Tag{Key: aws.String("ecspresso:severity"), Value: aws.String(result.Severity)})
+	}
+	if opt.TagExitCode && result.ExitCode != nil {
+		tags = append(tags, types.Tag{Key: aws.String("ecspresso:exit-code"), Value: aws.String(exitCode)})
+	}
+	if len(tags) == 0 {
+		return
+	}
+	d.Log("Tagging the task %s with %s", arnToName(result.TaskArn), tagsToString(tags))
+	if _, err := d.ecs.TagResource(ctx, &ecs.TagResourceInput{
+		ResourceArn: aws.String(result.TaskArn),
+		Tags:        tags,
+	}); err != nil {
+		// the stopped task may be already cleaned up by ECS
+		d.Log("[WARNING] failed to tag the stopped task: %s", err)
+	}
+}
+
+// logStoppedDetails logs the stopped reason of the task and the status of all the containers
+// when the task or any container has failed, because the root cause may be another container than the watch container.
+func (d *App) logStoppedDetails(result *RunResult) {
+	failed := result.Severity == SeverityFailure
+	for _, c := range result.Containers {
+		failed = failed || c.failed()
+	}
+	if !failed {
+		return
+	}
+	if result.StoppedReason != "" {
+		d.Log("Task %s stopped. stop code: %s, reason: %s", arnToName(result.TaskArn), result.StopCode, result.StoppedReason)
+	}
+	for _, c := range result.Containers {
+		if c.failed() {
+			d.Log("[WARNING] %s", c)
+		} else {
+			d.Log("%s", c)
+		}
+	}
+}
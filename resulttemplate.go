@@ -0,0 +1,76 @@
+package ecspresso
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/template"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+var resultTemplateFuncs = template.FuncMap{
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// parseResultTemplate parses the template for the result of the run.
+func parseResultTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("result").Funcs(resultTemplateFuncs).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse result-template: %w", err)
+	}
+	return tmpl, nil
+}
+
+// writeRunResult writes the result of the run rendered by the template.
+func writeRunResult(w io.Writer, tmpl *template.Template, result *RunResult) error {
+	if err := tmpl.Execute(w, result); err != nil {
+		return fmt.Errorf("failed to execute result-template: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// runStatus is the status of the task printed by --format json.
+// The fields are always present to keep the schema stable.
+type runStatus struct {
+	TaskArn       string            `json:"task_arn"`
+	LastStatus    string            `json:"last_status"`
+	StoppedReason string            `json:"stopped_reason"`
+	StopCode      string            `json:"stop_code"`
+	ExitCodes     map[string]int32  `json:"exit_codes"`
+	Containers    []containerStatus `json:"containers"`
+}
+
+type containerStatus struct {
+	Name       string `json:"name"`
+	LastStatus string `json:"last_status"`
+	ExitCode   *int32 `json:"exit_code"`
+	Reason     string `json:"reason"`
+}
+
+// writeRunStatusJSON writes the status of the task in the result as a single JSON object.
+func writeRunStatusJSON(w io.Writer, result *RunResult) error {
+	st := runStatus{
+		TaskArn:       result.TaskArn,
+		LastStatus:    result.Status,
+		StoppedReason: result.StoppedReason,
+		StopCode:      result.StopCode,
+		ExitCodes:     result.ExitCodes,
+		Containers:    []containerStatus{},
+	}
+	for _, c := range result.Containers {
+		st.Containers = append(st.Containers, containerStatus(c))
+	}
+	if st.ExitCodes == nil {
+		st.ExitCodes = map[string]int32{}
+	}
+	if st.LastStatus == "" && result.Task != nil {
+		// --no-wait
+		st.LastStatus = aws.ToString(result.Task.LastStatus)
+	}
+	return json.NewEncoder(w).Encode(st)
+}
@@ -0,0 +1,94 @@
+package ecspresso_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestRenderRunResult(t *testing.T) {
+	started := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	result := &ecspresso.RunResult{
+		TaskArn:       "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001",
+		Container:     "app",
+		ExitCode:      aws.Int32(1),
+		StoppedReason: "Essential container in task exited",
+		Status:        "STOPPED",
+		ExitCodes:     map[string]int32{"app": 1, "sidecar": 0},
+		Tags:          map[string]string{"env": "dev"},
+		StartedAt:     aws.Time(started),
+		StoppedAt:     aws.Time(started.Add(90 * time.Second)),
+	}
+	for text, expected := range map[string]string{
+		`{{ .Status }} {{ .ExitCode }} {{ .Duration }}`:              "STOPPED 1 1m30s\n",
+		`{{ index .ExitCodes "sidecar" }} {{ .Tags.env }}`:           "0 dev\n",
+		`{"task":{{ json .TaskArn }},"codes":{{ json .ExitCodes }}}`: `{"task":"arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001","codes":{"app":1,"sidecar":0}}` + "\n",
+	} {
+		got, err := ecspresso.RenderRunResult(text, result)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", text, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("%s: expected %q, got %q", text, expected, got)
+		}
+	}
+}
+
+func TestRenderRunResultErrors(t *testing.T) {
+	result := &ecspresso.RunResult{}
+	for _, text := range []string{
+		`{{ .TaskArn `,
+		`{{ .NoSuchField }}`,
+		`{{ .Tags.missing }}`,
+	} {
+		if _, err := ecspresso.RenderRunResult(text, result); err == nil {
+			t.Errorf("%s: expected error", text)
+		}
+	}
+}
+
+func TestRunStatusJSON(t *testing.T) {
+	for name, c := range map[string]struct {
+		result   *ecspresso.RunResult
+		expected string
+	}{
+		"stopped": {
+			result: &ecspresso.RunResult{
+				TaskArn:       "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001",
+				Container:     "app",
+				ExitCode:      aws.Int32(1),
+				StoppedReason: "Essential container in task exited",
+				Status:        "STOPPED",
+				StopCode:      "EssentialContainerExited",
+				ExitCodes:     map[string]int32{"app": 1, "sidecar": 0},
+				Containers: []ecspresso.ContainerResult{
+					{Name: "app", LastStatus: "STOPPED", ExitCode: aws.Int32(1)},
+					{Name: "sidecar", LastStatus: "STOPPED", ExitCode: aws.Int32(0)},
+				},
+				Severity: ecspresso.SeverityFailure,
+			},
+			expected: `{"task_arn":"arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001","last_status":"STOPPED","stopped_reason":"Essential container in task exited","stop_code":"EssentialContainerExited","exit_codes":{"app":1,"sidecar":0},` +
+				`"containers":[{"name":"app","last_status":"STOPPED","exit_code":1,"reason":""},{"name":"sidecar","last_status":"STOPPED","exit_code":0,"reason":""}]}`,
+		},
+		"no-wait": {
+			result: &ecspresso.RunResult{
+				TaskArn: "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0002",
+				Task:    &types.Task{LastStatus: aws.String("PROVISIONING")},
+			},
+			expected: `{"task_arn":"arn:aws:ecs:ap-northeast-1:123456789012:task/default/0002","last_status":"PROVISIONING","stopped_reason":"","stop_code":"","exit_codes":{},"containers":[]}`,
+		},
+	} {
+		got, err := ecspresso.RunStatusJSON(c.result)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+			continue
+		}
+		if got != c.expected+"\n" {
+			t.Errorf("%s: unexpected JSON\nexpected %s\ngot      %s", name, c.expected, got)
+		}
+	}
+}
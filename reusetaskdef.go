@@ -0,0 +1,65 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// maxReuseRevisions is the number of the latest ACTIVE revisions to compare
+// when looking up an identical task definition.
+var maxReuseRevisions = 10
+
+// findIdenticalTaskDefinitionArn returns the ARN of the latest ACTIVE revision which is
+// identical to td after normalizing as the diff command does.
+// It returns an empty string when no identical revision is found.
+func (d *App) findIdenticalTaskDefinitionArn(ctx context.Context, td *TaskDefinitionInput) (string, error) {
+	// copy td not to sort the definition to be registered
+	b, err := MarshalJSONForAPI(td)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal task definition: %w", err)
+	}
+	var local TaskDefinitionInput
+	if err := UnmarshalJSONForStruct(b, &local, ""); err != nil {
+		return "", fmt.Errorf("failed to unmarshal task definition: %w", err)
+	}
+
+	family := aws.ToString(td.Family)
+	p := ecs.NewListTaskDefinitionsPaginator(d.ecs, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Status:       types.TaskDefinitionStatusActive,
+		Sort:         types.SortOrderDesc,
+	})
+	compared := 0
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list taskdefinitions: %w", err)
+		}
+		for _, arn := range out.TaskDefinitionArns {
+			if familyOfTaskDefinitionArn(arn) != family {
+				continue
+			}
+			if compared >= maxReuseRevisions {
+				return "", nil
+			}
+			compared++
+			remote, err := d.DescribeTaskDefinition(ctx, arn)
+			if err != nil {
+				return "", err
+			}
+			ds, err := diffTaskDefs(&local, remote, "", arn, false)
+			if err != nil {
+				return "", err
+			}
+			if ds == "" {
+				return arn, nil
+			}
+			d.Log("[DEBUG] %s differs from the local task definition", arnToName(arn))
+		}
+	}
+	return "", nil
+}
@@ -3,31 +3,134 @@ package ecspresso
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"regexp"
 	"strings"
+	"text/template"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/samber/lo"
 )
 
 type RunOption struct {
-	DryRun                 bool    `help:"dry run" default:"false"`
-	TaskDefinition         string  `name:"task-def" help:"task definition file for run task" default:""`
-	Wait                   bool    `help:"wait for task to complete" default:"true" negatable:""`
-	TaskOverrideStr        string  `name:"overrides" help:"task override JSON string" default:""`
-	TaskOverrideFile       string  `name:"overrides-file" help:"task override JSON file path" default:""`
-	SkipTaskDefinition     bool    `help:"skip register a new task definition" default:"false"`
-	Count                  int32   `help:"number of tasks to run (max 10)" default:"1"`
-	WatchContainer         string  `help:"container name for watching exit code" default:""`
-	LatestTaskDefinition   bool    `help:"use the latest task definition without registering a new task definition" default:"false"`
-	PropagateTags          string  `help:"propagate the tags for the task (SERVICE or TASK_DEFINITION)" default:""`
-	Tags                   string  `help:"tags for the task: format is KeyFoo=ValueFoo,KeyBar=ValueBar" default:""`
-	WaitUntil              string  `help:"wait until invoked tasks status reached to (running or stopped)" default:"stopped" enum:"running,stopped"`
-	Revision               *int64  `help:"revision of the task definition to run when --skip-task-definition" default:"0"`
-	ClientToken            *string `help:"unique token that identifies a request, useful for idempotency"`
-	EBSDeleteOnTermination *bool   `help:"whether to delete the EBS volume when the task is stopped" default:"true" negatable:""`
+	DryRun                    bool              `help:"dry run" default:"false"`
+	TaskDefinition            string            `name:"task-def" help:"task definition file for run task" default:""`
+	Wait                      bool              `help:"wait for task to complete" default:"true" negatable:""`
+	TaskOverrideStr           string            `name:"overrides" help:"task override JSON string" default:""`
+	TaskOverrideFile          string            `name:"overrides-file" help:"task override JSON file path, s3://BUCKET/KEY or http(s)://URL" default:""`
+	OverridesProfile          *string           `help:"name of the overrides profile in overrides_profiles of the config. --overrides-file and --overrides are merged over it"`
+	SkipTaskDefinition        bool              `help:"skip register a new task definition" default:"false"`
+	Count                     int32             `help:"number of tasks to run (max 10)" default:"1"`
+	WatchAll                  bool              `help:"tail the logs of all the containers configured with awslogs, prefixed with the container name" default:"false"`
+	WatchContainer            string            `help:"container name or index for watching exit code" default:""`
+	LatestTaskDefinition      bool              `help:"use the latest task definition without registering a new task definition" default:"false"`
+	ReuseTaskDefinition       bool              `help:"reuse the latest ACTIVE revision identical to the local task definition instead of registering a new one. registers a new one when no identical revision is found" default:"false"`
+	NoRegister                bool              `help:"run the latest ACTIVE revision identical to the local task definition. fails when no identical revision is found instead of registering" default:"false"`
+	PropagateTags             string            `help:"propagate the tags for the task (SERVICE, TASK_DEFINITION, SERVICE,TASK_DEFINITION or NONE)" default:""`
+	Cluster                   *string           `help:"cluster to run the task instead of the cluster in the config. the service definition is still used for the network configuration"`
+	IgnoreMissingService      bool              `help:"skip propagating the tags from the service with a warning when the service is not found" default:"false"`
+	ForcePropagateServiceTags bool              `help:"propagate the tags of the service even if the task definition family differs from the one of the service" default:"false"`
+	StopOnCancel              bool              `help:"stop the task when ecspresso is interrupted (e.g. Ctrl-C) while waiting for the task" default:"false"`
+	Tags                      string            `help:"tags for the task: format is KeyFoo=ValueFoo,KeyBar=ValueBar" default:""`
+	TagsFile                  string            `help:"JSON or YAML file of the tags for the task (a map of the key to the value). --tags take precedence" default:""`
+	EphemeralStorage          int32             `help:"ephemeral storage size of the task in GiB (21-200, Fargate). takes precedence over the overrides" default:"0"`
+	Cpu                       string            `help:"task level cpu of the task (e.g. 1024). takes precedence over the overrides" default:""`
+	Memory                    string            `help:"task level memory of the task (e.g. 2048). takes precedence over the overrides" default:""`
+	DefaultTags               map[string]string `name:"default-tag" help:"default tag for the task merged under --tags and the propagated tags. format: KEY=VALUE (repeatable). merged over default_tags in the config"`
+	WaitUntil                 string            `help:"wait until invoked tasks status reached to (running or stopped)" default:"stopped" enum:"running,stopped"`
+	Revision                  *int64            `help:"revision of the task definition to run when --skip-task-definition" default:"0"`
+	ArnFile                   *string           `help:"file to write the ARNs of the launched tasks (one per line) as soon as they are launched"`
+	ClientToken               *string           `help:"unique token that identifies a request, useful for idempotency"`
+	EBSDeleteOnTermination    *bool             `help:"whether to delete the EBS volume when the task is stopped" default:"true" negatable:""`
+	DebugSidecar              *string           `help:"container image of a debugging sidecar added to a transient task definition (enables ECS Exec)"`
+	CustomWaiter              bool              `help:"wait for the task by polling DescribeTasks instead of the SDK waiter" default:"false"`
+	PollInterval              time.Duration     `help:"interval of polling DescribeTasks with --custom-waiter" default:"5s"`
+	PollBackoff               string            `help:"backoff strategy of polling DescribeTasks with --custom-waiter (constant or exponential with jitter)" default:"constant" enum:"constant,exponential"`
+	PollMaxDelay              time.Duration     `help:"max interval of polling with --poll-backoff=exponential" default:"1m"`
+	TaskEvents                bool              `help:"log the lifecycle events of the task (image pull, started, stopped, etc.) while waiting. polls DescribeTasks at --poll-interval" default:"false"`
+	Params                    map[string]string `name:"param" help:"parameter for command_templates in the config. format: KEY=VALUE (repeatable)"`
+	OverridesSchema           *string           `help:"JSON schema file to validate the overrides"`
+	At                        *time.Time        `help:"schedule the task at the time (RFC3339) with EventBridge Scheduler instead of running it now. requires --no-wait"`
+	ScheduleRoleArn           string            `help:"IAM role ARN for EventBridge Scheduler to run the task with --at" default:""`
+	RunTaskRate               float64           `help:"max rate of RunTask API calls per second (0 means unlimited). throttled calls are retried with backoff" default:"0"`
+	MaxCpu                    int               `help:"refuse to run when the task-level cpu (units) after overrides exceeds this value (0 means unlimited)" default:"0"`
+	MaxMemory                 int               `help:"refuse to run when the task-level memory (MiB) after overrides exceeds this value (0 means unlimited)" default:"0"`
+	MaxCost                   float64           `help:"stop the Fargate task when the estimated cost (USD, on-demand price) exceeds this value while waiting (0 means unlimited)" default:"0"`
+	WatchAlarm                *string           `help:"CloudWatch alarm name to watch after the task completed. the run fails when the alarm goes into ALARM state"`
+	WatchAlarmWindow          time.Duration     `help:"time window to watch the alarm" default:"1m"`
+	ApprovalGate              *string           `help:"URL (responds 2xx to approve) or command (exits with 0 to approve) to ask approval before running. the run request is sent as JSON"`
+	ApprovalTimeout           time.Duration     `help:"timeout for waiting for the approval" default:"10m"`
+	CaptureMetrics            bool              `help:"capture the peak CPU and memory utilization of the task from Container Insights into the result" default:"false"`
+	Format                    string            `help:"output format of the status of the task. json prints a single JSON object to stdout, and the logs of the task are written to stderr unless --log-sink" default:"text" enum:"text,json"`
+	ResultTemplate            *string           `help:"Go template to output the result of the run to stdout. e.g. '{{ .TaskArn }} {{ .ExitCode }}'"`
+	Timings                   bool              `help:"print the time spent in each phase of the run" default:"false"`
+	FIPS                      bool              `help:"use FIPS endpoints for ECS and CloudWatch Logs" default:"false"`
+	Regions                   []string          `help:"run the task in each region concurrently (repeatable)"`
+	AllowUnknownOverrides     bool              `help:"allow the overrides for the containers which do not exist in the task definition (warns only)" default:"false"`
+	StrictOverrides           bool              `help:"parse overrides as strict JSON. disallow comments and trailing commas" default:"false"`
+	PruneKeep                 int               `help:"number of task definition revisions to keep in the family after run. older revisions except in-use are deregistered (0 means no pruning)" default:"0"`
+	TargetGroupArn            *string           `help:"ARN of the target group to wait for the task to be registered and healthy"`
+	TargetGroupTimeout        time.Duration     `help:"timeout for waiting for the task to be healthy in the target group" default:"5m"`
+	DockerLabel               []string          `help:"docker label to set in a transient task definition. format: [container:]key=value (repeatable)"`
+	Image                     *string           `help:"container image to run in a transient task definition. format: [container=]image (default container: watch container)"`
+	KeepTransient             bool              `help:"keep the transient task definition after the run instead of deregistering it" default:"false"`
+	EntryPoint                []string          `help:"entryPoint of the container in a transient task definition. each flag is an element (repeatable). e.g. --entry-point=sleep --entry-point=infinity" sep:"none"`
+	EntryPointContainer       string            `help:"container name to override entryPoint (default: watch container)" default:""`
+	Ulimit                    []string          `help:"ulimit of the container in a transient task definition. format is [container:]name=soft:hard (repeatable)"`
+	ForceAwslogs              bool              `help:"(experimental) configure awslogs for the watch container in a transient task definition to tail its logs" default:"false"`
+	RequireLogging            bool              `help:"refuse to run when the watch container has no log configuration" default:"false"`
+	RequireLoggingAll         bool              `help:"with --require-logging, require log configuration for all essential containers" default:"false"`
+	ValidateSecrets           bool              `help:"validate the secrets of the containers exist and are accessible before running" default:"false"`
+	ValidateResources         bool              `help:"validate cpu and memory of the task definition fit the launch type before running" default:"false"`
+	FromStart                 bool              `help:"tail logs from the head of the log stream instead of the time the task started" default:"false"`
+	NoLogTail                 bool              `help:"do not tail the logs of the task while waiting. the final status of the task is still reported" default:"false"`
+	LogFilterPattern          *string           `help:"CloudWatch Logs filter pattern to tail only the matched log events (uses FilterLogEvents)"`
+	LogSinks                  []LogSink         `name:"log-sink" help:"destination of the logs of the task: stdout, file:PATH, s3://BUCKET/KEY or http(s)://URL (webhook). the logs are written to all the sinks (repeatable, default: stdout)"`
+	LogPollInterval           time.Duration     `help:"interval of polling the logs of the task" default:"5s"`
+	LogStreamWait             time.Duration     `help:"max time to wait for the log streams to be created before tailing the logs" default:"3s"`
+	ProgressInterval          time.Duration     `help:"interval of logging the progress of waiting for the task without the logs of the task (0 disables)" default:"30s"`
+	LogPollConcurrency        int               `help:"max number of concurrent GetLogEvents calls for tailing logs (0 means unbounded)" default:"0"`
+	GoldenLog                 *string           `help:"golden file to compare with the logs of the watch container after the task stopped"`
+	GoldenLogNormalize        []string          `help:"regular expression to remove from each log line before comparing with the golden file (repeatable)"`
+	PropagateExitCode         bool              `help:"exit with the exit code of the watch container when it exited with non-zero" default:"false"`
+	TagExitCode               bool              `help:"tag the stopped task with ecspresso:exit-code" default:"false"`
+	TagSeverity               bool              `help:"tag the stopped task with ecspresso:severity" default:"false"`
+	EnvFile                   []string          `help:"envfile to set the environment variables of the container. format of each line: KEY=VALUE[@container]. takes precedence over the environment in overrides (repeatable)"`
+	EnvFileContainer          string            `help:"container name to set the environment variables from --env-file (default: watch container)" default:""`
+	OnCompleteLambda          *string           `help:"Lambda function to invoke with the result of the run as the payload"`
+	OnCompleteLambdaAsync     bool              `help:"invoke the Lambda function asynchronously (Event invocation type)" default:"false"`
+	AuditTable                *string           `help:"DynamoDB table name to write the final state of the task for audit (the hash key must be task_arn)"`
+	Profile                   *string           `help:"AWS shared config profile to run the task with"`
+	OnSuccessScale            *int32            `help:"desired count of the service to scale to after the task succeeded"`
+	MaxClusterTasks           int               `help:"refuse to run when the running and pending tasks in the cluster exceed this number (0 means unlimited)" default:"0"`
+	CheckEndpoints            bool              `help:"check the subnets can reach the internet or the VPC endpoints required to launch the task before running" default:"false"`
+	CheckCluster              bool              `help:"check the cluster is ACTIVE and has capacity for the launch type before running" default:"false"`
+	RequireExplicitRevision   bool              `help:"refuse to run unless the revision of the task definition is pinned by --revision (or --from-schedule)" default:"false"`
+	BySemver                  *string           `help:"run the revision of the highest semantic version in the tag which satisfies the constraint. e.g. '>=1.2.0 <2.0.0'"`
+	SemverTag                 string            `help:"tag key of the semantic version of the task definition for --by-semver" default:"version"`
+	LastGood                  bool              `help:"run the revision of the task definition which ran successfully at last by ecspresso (the tasks of the same startedBy)" default:"false"`
+	MaxRetries                int               `help:"max number of times to retry RunTask with backoff when it failed by a retryable reason (e.g. RESOURCE:MEMORY, Capacity is unavailable)" default:"0"`
+	RetryRun                  int               `help:"max number of times to rerun the task when it stopped by a transient failure (e.g. CannotPullContainerError, ResourceInitializationError)" default:"0"`
+	Subnets                   []string          `help:"subnets of the awsvpc network configuration of the task instead of the service definition (comma separated)"`
+	SecurityGroups            []string          `help:"security groups of the awsvpc network configuration of the task instead of the service definition (comma separated)"`
+	AssignPublicIp            string            `help:"assign a public IP address to the task (ENABLED or DISABLED) instead of the service definition" default:"" enum:",ENABLED,DISABLED"`
+	LaunchType                string            `help:"launch type of the task (EC2, FARGATE or EXTERNAL) instead of the service definition" default:""`
+	PlatformVersion           string            `help:"platform version of the task (e.g. 1.4.0 or LATEST) instead of the service definition" default:""`
+	CapacityProviderStrategy  string            `help:"capacity provider strategy JSON of the task instead of the service definition. e.g. '[{\"capacityProvider\":\"FARGATE_SPOT\",\"weight\":1}]'. exclusive with --launch-type" default:""`
+	CapacityProviderCascade   []string          `help:"capacity providers to try in order on placement failure. e.g. FARGATE_SPOT,FARGATE"`
+	CloneTask                 *string           `help:"ID or ARN of the task to clone the overrides (command, environment, cpu, memory, etc.) from"`
+	FromSchedule              *string           `help:"run the task definition and the overrides of the ECS target of the EventBridge rule. format: rule[/target-id]"`
+	StartedBy                 *string           `help:"startedBy of the task (default: ecspresso-run). characters not allowed by ECS are replaced with '-' and truncated to 128 characters"`
+	StartedByTemplate         string            `help:"template of startedBy for the task. e.g. '{{ env \"CI_JOB_URL\" }}'. truncated to 128 characters" default:""`
+	RunID                     *string           `help:"ID of the run to set to ECSPRESSO_RUN_ID of the watch container and the ecspresso:run-id tag of the task, and to prefix the logs (default: generated UUID)"`
+	WaitTimeout               time.Duration     `help:"timeout for waiting for the task instead of the timeout in the config (0 means the timeout in the config)" default:"0"`
+	UseDefinitionTimeout      bool              `help:"use ecspresso.expected-duration in the docker label of the watch container or the tag of the task definition as the timeout for waiting for the task" default:"false"`
 }
 
 func (opt RunOption) waitUntilRunning() bool {
@@ -42,87 +145,877 @@ func (opt RunOption) DryRunString() string {
 }
 
 func (d *App) Run(ctx context.Context, opt RunOption) error {
-	ctx, cancel := d.Start(ctx)
+	if len(opt.Regions) > 0 {
+		if opt.ResultTemplate != nil {
+			return ErrConflictOptions("result-template is incompatible with --regions")
+		}
+		if opt.Format == "json" {
+			return ErrConflictOptions("format json is incompatible with --regions")
+		}
+		ctx, cancel, d, err := d.prepareRun(ctx, opt)
+		if err != nil {
+			return err
+		}
+		defer cancel()
+		return d.runMultiRegion(ctx, opt)
+	}
+	if opt.Format == "json" {
+		if opt.ResultTemplate != nil {
+			return ErrConflictOptions("format json is incompatible with --result-template")
+		}
+		if len(opt.LogSinks) == 0 {
+			// stdout is for the JSON only
+			nd := *d
+			nd.logWriter = os.Stderr
+			d = &nd
+		}
+	}
+	var resultTmpl *template.Template
+	if opt.ResultTemplate != nil {
+		tmpl, err := parseResultTemplate(*opt.ResultTemplate)
+		if err != nil {
+			return err
+		}
+		resultTmpl = tmpl
+	}
+	result, err := d.RunResult(ctx, opt)
+	if resultTmpl != nil && result != nil {
+		if tmplErr := writeRunResult(os.Stdout, resultTmpl, result); tmplErr != nil {
+			if err != nil {
+				d.Log("[WARNING] %s", tmplErr)
+				return err
+			}
+			return tmplErr
+		}
+	}
+	if opt.Format == "json" && result != nil {
+		if jsonErr := writeRunStatusJSON(os.Stdout, result); jsonErr != nil {
+			if err != nil {
+				d.Log("[WARNING] failed to write the status of the task: %s", jsonErr)
+				return err
+			}
+			return jsonErr
+		}
+	}
+	return err
+}
+
+// RunResult runs the task as Run does, and returns the result of the run for embedding ecspresso as a library.
+// The result is available even if the task has failed after launched (e.g. a non-zero exit code).
+// The result is nil when the task has not been launched, including --dry-run.
+// With --no-wait, the result has only the ARNs of the task and the task definition.
+func (d *App) RunResult(ctx context.Context, opt RunOption) (*RunResult, error) {
+	if len(opt.Regions) > 0 {
+		return nil, ErrConflictOptions("RunResult does not support --regions. use Run")
+	}
+	ctx, cancel, d, err := d.prepareRun(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
 	defer cancel()
+	return d.run(ctx, opt)
+}
+
+// prepareRun returns the context with the timeout and the App for the profile to run the task.
+func (d *App) prepareRun(ctx context.Context, opt RunOption) (context.Context, context.CancelFunc, *App, error) {
+	var cancel context.CancelFunc
+	if opt.UseDefinitionTimeout || opt.WaitTimeout > 0 {
+		// the timeout is applied to the wait after the task definition is resolved
+		ctx, cancel = context.WithCancel(ctx)
+	} else {
+		ctx, cancel = d.Start(ctx)
+	}
+	if opt.Profile != nil {
+		pd, err := d.withProfile(ctx, *opt.Profile)
+		if err != nil {
+			cancel()
+			return nil, nil, nil, err
+		}
+		d = pd
+	}
+	return ctx, cancel, d, nil
+}
 
+// run runs the task and returns the result.
+// The result is available even if the task has failed after launched.
+func (d *App) run(ctx context.Context, opt RunOption) (*RunResult, error) {
 	d.Log("Running task %s", opt.DryRunString())
+	if opt.FIPS {
+		fd, err := d.withFIPSEndpoints()
+		if err != nil {
+			return nil, err
+		}
+		d = fd
+	}
+	if opt.FromSchedule != nil {
+		if opt.TaskOverrideStr != "" || opt.TaskOverrideFile != "" || opt.OverridesProfile != nil {
+			return nil, ErrConflictOptions("from-schedule is incompatible with --overrides, --overrides-file and --overrides-profile")
+		}
+		if *opt.Revision > 0 || opt.LatestTaskDefinition || opt.LastGood || opt.BySemver != nil {
+			return nil, ErrConflictOptions("from-schedule is incompatible with --revision, --latest-task-definition, --last-good and --by-semver")
+		}
+	}
+	// the scheduled task is resolved first to run in its cluster with its launch settings
+	var scheduled *scheduledTask
+	if name := aws.ToString(opt.FromSchedule); name != "" {
+		st, err := d.resolveScheduledTask(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		if err := d.applyScheduledTask(st, &opt); err != nil {
+			return nil, err
+		}
+		scheduled = st
+	}
+	if c := aws.ToString(opt.Cluster); c != "" && c != d.Cluster {
+		d = d.withRunCluster(c)
+		d.Log("Running task in cluster %s instead of %s", c, d.clusterOfService())
+	}
+	if opt.RunID == nil {
+		opt.RunID = aws.String(newRunID())
+	} else if err := validateRunID(*opt.RunID); err != nil {
+		return nil, err
+	}
+	d = d.withRunID(*opt.RunID)
+	d.Log("Run ID: %s", *opt.RunID)
+	if opt.OnSuccessScale != nil && d.config.Service == "" {
+		return nil, ErrConflictOptions("on-success-scale requires service in the configuration")
+	}
+	if opt.At != nil {
+		if opt.Wait {
+			return nil, ErrConflictOptions("at is incompatible with --wait. use --no-wait")
+		}
+		if opt.ScheduleRoleArn == "" {
+			return nil, ErrConflictOptions("at requires --schedule-role-arn")
+		}
+	}
+	if len(opt.CapacityProviderCascade) > 0 {
+		if opt.At != nil {
+			return nil, ErrConflictOptions("capacity-provider-cascade is incompatible with --at")
+		}
+		if opt.ClientToken != nil {
+			return nil, ErrConflictOptions("capacity-provider-cascade is incompatible with --client-token")
+		}
+	}
+	if opt.LaunchType != "" || opt.CapacityProviderStrategy != "" {
+		if opt.LaunchType != "" && opt.CapacityProviderStrategy != "" {
+			return nil, ErrConflictOptions("launch-type and capacity-provider-strategy are exclusive")
+		}
+		if len(opt.CapacityProviderCascade) > 0 {
+			return nil, ErrConflictOptions("launch-type and capacity-provider-strategy are incompatible with --capacity-provider-cascade")
+		}
+		if _, _, err := launchSettingsForRun(&Service{}, &opt); err != nil {
+			return nil, err
+		}
+	}
+	if opt.MaxRetries > 0 {
+		if opt.ClientToken != nil {
+			return nil, ErrConflictOptions("max-retries is incompatible with --client-token")
+		}
+		if len(opt.CapacityProviderCascade) > 0 {
+			return nil, ErrConflictOptions("max-retries is incompatible with --capacity-provider-cascade")
+		}
+	}
+	if opt.RetryRun > 0 {
+		if !opt.Wait {
+			return nil, ErrConflictOptions("retry-run requires --wait")
+		}
+		if opt.ClientToken != nil {
+			return nil, ErrConflictOptions("retry-run is incompatible with --client-token")
+		}
+		if opt.Count > 1 {
+			return nil, ErrConflictOptions("retry-run is incompatible with --count greater than 1")
+		}
+	}
+	if opt.NoLogTail && (opt.WatchAll || opt.FromStart || opt.LogFilterPattern != nil || len(opt.LogSinks) > 0) {
+		return nil, ErrConflictOptions("no-log-tail is incompatible with --watch-all, --from-start, --log-filter-pattern and --log-sink")
+	}
+	if opt.MaxCost > 0 && !opt.Wait {
+		return nil, ErrConflictOptions("max-cost requires --wait")
+	}
+	if opt.WatchAlarm != nil && !opt.Wait {
+		return nil, ErrConflictOptions("watch-alarm requires --wait")
+	}
+	if opt.OnCompleteLambda != nil && !opt.Wait {
+		return nil, ErrConflictOptions("on-complete-lambda requires --wait")
+	}
+	if opt.BySemver != nil && (*opt.Revision > 0 || opt.LatestTaskDefinition || opt.LastGood) {
+		return nil, ErrConflictOptions("by-semver is incompatible with --revision, --latest-task-definition and --last-good")
+	}
+	if (opt.ReuseTaskDefinition || opt.NoRegister) && (!opt.registersTaskDefinition() || opt.FromSchedule != nil) {
+		return nil, ErrConflictOptions("reuse-task-definition and no-register are incompatible with the options which do not register a task definition " +
+			"(--skip-task-definition, --latest-task-definition, --revision, --last-good, --by-semver and --from-schedule)")
+	}
+	if opt.RequireExplicitRevision && *opt.Revision <= 0 && opt.FromSchedule == nil {
+		return nil, ErrConflictOptions("require-explicit-revision refuses to register a new revision or to use the latest revision. " +
+			"pin the revision to run by --skip-task-definition --revision N. `ecspresso revisions` lists the revisions")
+	}
+	if opt.StartedBy != nil && opt.StartedByTemplate != "" {
+		return nil, ErrConflictOptions("started-by and started-by-template are exclusive")
+	}
+	if opt.CloneTask != nil {
+		if opt.TaskOverrideStr != "" || opt.TaskOverrideFile != "" || opt.OverridesProfile != nil || opt.FromSchedule != nil {
+			return nil, ErrConflictOptions("clone-task is incompatible with --overrides, --overrides-file, --overrides-profile and --from-schedule")
+		}
+	}
+	var goldenLogNormalizers []*regexp.Regexp
+	if opt.GoldenLog != nil {
+		if !opt.Wait {
+			return nil, ErrConflictOptions("golden-log requires --wait")
+		}
+		ns, err := compileLogNormalizers(opt.GoldenLogNormalize)
+		if err != nil {
+			return nil, err
+		}
+		goldenLogNormalizers = ns
+	}
+	tm := &runTimings{}
+	if opt.Timings {
+		defer d.printTimings(tm)
+	}
+	ov, err := d.taskOverrideForRun(ctx, opt)
+	if err != nil {
+		return nil, err
+	}
+	if scheduled != nil {
+		ov = scheduled.overrides
+	}
+	if taskID := aws.ToString(opt.CloneTask); taskID != "" {
+		if ov, err = d.taskOverrideOfTask(ctx, taskID); err != nil {
+			return nil, err
+		}
+	}
+	if err := d.applyCommandTemplates(&ov, opt.Params); err != nil {
+		return nil, err
+	}
+	if opt.EphemeralStorage != 0 {
+		if err := setEphemeralStorage(&ov, opt.EphemeralStorage); err != nil {
+			return nil, err
+		}
+	}
+	if opt.Cpu != "" {
+		ov.Cpu = aws.String(opt.Cpu)
+	}
+	if opt.Memory != "" {
+		ov.Memory = aws.String(opt.Memory)
+	}
+	d.Log("[DEBUG] Overrides")
+	d.LogJSON(ov)
+
+	phaseStart := time.Now()
+	var tdArn string
+	if scheduled != nil {
+		tdArn = scheduled.taskDefinitionArn
+	} else if tdArn, err = d.taskDefinitionArnForRun(ctx, opt); err != nil {
+		return nil, err
+	}
+	tm.add(phaseRegister, phaseStart)
+	d.Log("Task definition ARN: %s", tdArn)
+	if opt.DryRun {
+		if gate := aws.ToString(opt.ApprovalGate); gate != "" {
+			d.Log("Approval gate %s will be asked before running. skipped in dry run", gate)
+		}
+		if opt.At == nil {
+			startedBy, err := d.startedByForRun(&opt)
+			if err != nil {
+				return nil, err
+			}
+			d.Log("Task will be started by %s", startedBy)
+		}
+		// an identical revision may be reused instead of registering
+		registers := scheduled == nil && opt.registersTaskDefinition() && !arn.IsARN(tdArn)
+		if err := d.outputDryRunTaskInput(ctx, tdArn, ov, opt, registers); err != nil {
+			return nil, err
+		}
+		d.Log("DRY RUN OK")
+		return nil, nil
+	}
+	phaseStart = time.Now()
+	td, err := d.DescribeTaskDefinition(ctx, tdArn)
+	if err != nil {
+		return nil, err
+	}
+	tm.add(phaseDescribe, phaseStart)
+	watchContainer, err := watchContainerOf(td, opt.WatchContainer)
+	if err != nil {
+		return nil, err
+	}
+	// the following steps refer to the watch container by the name, not by the index
+	opt.WatchContainer = *watchContainer.Name
+	var extraContainers []string
+	if opt.DebugSidecar != nil {
+		extraContainers = append(extraContainers, debugSidecarName)
+	}
+	if err := validateContainerOverrides(td, &ov, extraContainers...); err != nil {
+		if !opt.AllowUnknownOverrides {
+			return nil, fmt.Errorf("%w. --allow-unknown-overrides allows them", err)
+		}
+		d.Log("[WARNING] %s", err)
+	}
+	if opt.Cpu != "" || opt.Memory != "" {
+		if err := d.validateResourceOverridesForRun(td, &ov, &opt); err != nil {
+			return nil, err
+		}
+	}
+	if opt.CheckCluster {
+		if err := d.checkClusterForRun(ctx, &opt); err != nil {
+			return nil, err
+		}
+	}
+	if opt.MaxClusterTasks > 0 {
+		if err := d.checkClusterTasksForRun(ctx, opt.MaxClusterTasks, opt.Count); err != nil {
+			return nil, err
+		}
+	}
+	if opt.ValidateResources {
+		if err := d.validateResourcesForRun(td, &opt); err != nil {
+			return nil, err
+		}
+	}
+	if opt.CheckEndpoints {
+		if err := d.checkEndpointsForRun(ctx, td, &opt); err != nil {
+			return nil, err
+		}
+	}
+	if opt.ValidateSecrets {
+		if err := d.validateSecretsForRun(ctx, td); err != nil {
+			return nil, err
+		}
+	}
+	if opt.RequireLogging {
+		if err := d.validateLoggingForRun(td, opt); err != nil {
+			return nil, err
+		}
+	}
+	mods, err := d.transientModifiers(opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.At != nil && len(mods) > 0 {
+		return nil, ErrConflictOptions("at is incompatible with the options which require a transient task definition")
+	}
+	// runTd is the task definition to run, which may be a transient one
+	runTd := td
+	if len(mods) > 0 {
+		phaseStart = time.Now()
+		transientTdArn, transientTd, deregister, err := d.registerTransientTaskDefinition(ctx, td, mods)
+		if err != nil {
+			return nil, err
+		}
+		if opt.KeepTransient {
+			d.Log("Transient task definition %s is kept after the run", arnToName(transientTdArn))
+		} else {
+			d.Log("Transient task definition %s will be deregistered after the run", arnToName(transientTdArn))
+			defer deregister()
+		}
+		tdArn = transientTdArn
+		d.Log("Transient task definition ARN: %s", tdArn)
+		tm.add(phaseRegister, phaseStart)
+		// the containers may be modified (e.g. --force-awslogs, --debug-sidecar)
+		runTd = transientTd
+		watchContainer = containerOf(runTd, watchContainer.Name)
+	}
+	d.Log("Watch container: %s", *watchContainer.Name)
+
+	if len(opt.LogSinks) > 0 && opt.Wait {
+		sinks, err := d.openLogSinks(opt.LogSinks)
+		if err != nil {
+			return nil, err
+		}
+		defer sinks.Close()
+		nd := *d
+		nd.logWriter = sinks
+		d = &nd
+	}
+
+	if opt.WaitTimeout > 0 && opt.Wait {
+		d.Log("Timeout for waiting for the task: %s", opt.WaitTimeout)
+		d = d.withWaitTimeout(opt.WaitTimeout)
+	}
+	if opt.UseDefinitionTimeout && opt.Wait {
+		expected, source, err := expectedDuration(td, watchContainer)
+		if err != nil {
+			return nil, err
+		}
+		if expected > 0 {
+			d.Log("Timeout for waiting for the task: %s (%s in the %s)", expected, expectedDurationKey, source)
+			d = d.withWaitTimeout(expected)
+		} else {
+			d.Log("[INFO] %s is not found in the task definition. timeout for waiting for the task: %s", expectedDurationKey, d.waitTimeout())
+		}
+	}
+
+	if len(opt.EnvFile) > 0 {
+		container := opt.EnvFileContainer
+		if container == "" {
+			container = *watchContainer.Name
+		}
+		if err := d.applyEnvFiles(&ov, opt.EnvFile, container, td); err != nil {
+			return nil, err
+		}
+	}
+	setRunIDEnv(&ov, *watchContainer.Name, *opt.RunID)
+
+	if opt.MaxCpu > 0 || opt.MaxMemory > 0 {
+		if err := validateMaxResources(td, &ov, opt.MaxCpu, opt.MaxMemory); err != nil {
+			return nil, err
+		}
+	}
+
+	var hourlyCost float64
+	if opt.MaxCost > 0 {
+		if hourlyCost, err = d.hourlyCostForRun(td, &ov, &opt); err != nil {
+			return nil, err
+		}
+	}
+
+	if gate := aws.ToString(opt.ApprovalGate); gate != "" {
+		if err := d.waitForApproval(ctx, gate, opt.ApprovalTimeout, tdArn, &ov, opt.Count); err != nil {
+			return nil, err
+		}
+	}
+
+	if opt.At != nil {
+		result, err := d.scheduleRunForRun(ctx, tdArn, &ov, opt)
+		if result != nil {
+			result.RunID = *opt.RunID
+		}
+		return result, err
+	}
+
+	var (
+		task        *types.Task
+		tasks       []types.Task
+		result      *RunResult
+		statusErr   error
+		submittedAt time.Time
+	)
+	for attempt := 0; ; attempt++ {
+		startedAt := time.Now()
+		tasks, err = d.runTasks(ctx, tdArn, &ov, &opt)
+		if err != nil {
+			return nil, err
+		}
+		task = &tasks[0]
+		d = d.withLogTaskArn(aws.ToString(task.TaskArn))
+		tm.add(phaseRunSubmit, startedAt)
+		submittedAt = time.Now()
+		if attempt == 0 && opt.PruneKeep > 0 {
+			defer d.pruneTaskDefinitions(ctx, aws.ToString(td.Family), opt.PruneKeep)
+		}
+		if !opt.Wait {
+			d.Log("Run task invoked")
+			d.logLogsConsoleURL(task, watchContainer)
+			result := &RunResult{
+				TaskArn:           aws.ToString(task.TaskArn),
+				TaskDefinitionArn: aws.ToString(task.TaskDefinitionArn),
+				RunID:             *opt.RunID,
+				Task:              task,
+			}
+			if opt.Timings {
+				result.Timings = tm.phases
+			}
+			return result, nil
+		}
+		if tgArn := aws.ToString(opt.TargetGroupArn); tgArn != "" {
+			if err := d.waitTaskTargetHealthy(ctx, task, tgArn, opt.TargetGroupTimeout); err != nil {
+				return nil, err
+			}
+		}
+		stopWatchCost := func() {}
+		if hourlyCost > 0 {
+			var costCtx context.Context
+			costCtx, stopWatchCost = context.WithCancel(ctx)
+			go d.watchCost(costCtx, tasks, hourlyCost, opt.MaxCost)
+		}
+		err := d.waitRunTask(ctx, tasks, logContainers(runTd, watchContainer, opt.WatchAll), startedAt, opt)
+		stopWatchCost()
+		if err != nil {
+			if opt.StopOnCancel {
+				d.stopTasksOnCancel(ctx, tasks)
+			}
+			if timeout := d.waitTimeoutOverride; timeout > 0 && time.Since(startedAt) >= timeout {
+				d.Log("[WARNING] task %s exceeded the timeout for waiting %s", arnToName(aws.ToString(task.TaskArn)), timeout)
+			}
+			return nil, err
+		}
+		result, statusErr = d.describeRunResult(ctx, task, watchContainer)
+		if len(tasks) > 1 {
+			statusErr = d.aggregateRunResults(ctx, tasks, watchContainer, statusErr)
+		}
+		if statusErr == nil || result == nil || attempt >= opt.RetryRun || !isTransientTaskFailure(result.StopCode, result.StoppedReason) {
+			break
+		}
+		d.Log("[WARNING] task %s stopped by a transient failure: %s", arnToName(result.TaskArn), result.StoppedReason)
+		d.Log("Retrying the run (%d/%d)", attempt+1, opt.RetryRun)
+	}
+	if result != nil {
+		result.RunID = *opt.RunID
+		if opt.CaptureMetrics {
+			result.Metrics = d.captureTaskMetrics(ctx, result)
+		}
+		tm.addWait(submittedAt, result.StartedAt)
+		if opt.Timings {
+			result.Timings = tm.phases
+		}
+		d.logRunResult(ctx, result, opt)
+	}
+	if table := aws.ToString(opt.AuditTable); table != "" {
+		d.putRunAudit(ctx, table, task, statusErr)
+	}
+	if fn := aws.ToString(opt.OnCompleteLambda); fn != "" && result != nil {
+		if statusErr != nil {
+			result.Error = statusErr.Error()
+		}
+		d.invokeOnCompleteLambda(ctx, fn, opt.OnCompleteLambdaAsync, result)
+	}
+	if statusErr != nil {
+		return result, runResultError(result, statusErr, opt.PropagateExitCode)
+	}
+	if opt.GoldenLog != nil {
+		if err := d.checkGoldenLog(ctx, task, watchContainer, *opt.GoldenLog, goldenLogNormalizers); err != nil {
+			return result, err
+		}
+	}
+	if name := aws.ToString(opt.WatchAlarm); name != "" {
+		if err := d.watchAlarm(ctx, name, opt.WatchAlarmWindow); err != nil {
+			return result, err
+		}
+	}
+	d.Log("Run task completed!")
+
+	if opt.OnSuccessScale != nil {
+		if err := d.scaleServiceAfterRun(ctx, *opt.OnSuccessScale); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+func (d *App) scaleServiceAfterRun(ctx context.Context, count int32) error {
+	d.Log("Scaling service %s to desired count %d", d.Service, count)
+	if _, err := d.ecs.UpdateService(ctx, &ecs.UpdateServiceInput{
+		Service:      aws.String(d.Service),
+		Cluster:      aws.String(d.clusterOfService()),
+		DesiredCount: aws.Int32(count),
+	}); err != nil {
+		return fmt.Errorf("failed to scale service: %w", err)
+	}
+	d.Log("Service %s is scaled to desired count %d", d.Service, count)
+	return nil
+}
+
+func (d *App) checkClusterForRun(ctx context.Context, opt *RunOption) error {
+	sv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
+	if err != nil {
+		return err
+	}
+	launchType, strategy, err := launchSettingsForRun(sv, opt)
+	if err != nil {
+		return err
+	}
+	out, err := d.ecs.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{d.Cluster},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe cluster %s: %w", d.Cluster, err)
+	}
+	if len(out.Clusters) == 0 {
+		return ErrNotFound(fmt.Sprintf("cluster %s is not found", d.Cluster))
+	}
+	// capacity providers (except Fargate) may scale container instances from zero
+	requireInstances := !isFargate(launchType, strategy) && len(strategy) == 0
+	c := out.Clusters[0]
+	d.Log("[DEBUG] cluster %s is %s, %d container instances", aws.ToString(c.ClusterName), aws.ToString(c.Status), c.RegisteredContainerInstancesCount)
+	return validateCluster(c, requireInstances)
+}
+
+// checkClusterTasksForRun checks that running count tasks does not exceed max tasks in the cluster.
+func (d *App) checkClusterTasksForRun(ctx context.Context, max int, count int32) error {
+	n := 0
+	// DesiredStatus RUNNING includes PENDING tasks
+	p := ecs.NewListTasksPaginator(d.ecs, &ecs.ListTasksInput{
+		Cluster:       aws.String(d.Cluster),
+		DesiredStatus: types.DesiredStatusRunning,
+	})
+	for p.HasMorePages() {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list tasks: %w", err)
+		}
+		n += len(out.TaskArns)
+	}
+	d.Log("[DEBUG] %d running and pending tasks in cluster %s, max %d", n, d.Cluster, max)
+	if n+int(count) > max {
+		return fmt.Errorf("running %d tasks exceeds the max cluster tasks: %d tasks are running or pending in cluster %s, max %d", count, n, d.Cluster, max)
+	}
+	return nil
+}
+
+func (d *App) validateResourcesForRun(td *TaskDefinitionInput, opt *RunOption) error {
+	sv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
+	if err != nil {
+		return err
+	}
+	launchType, strategy, err := launchSettingsForRun(sv, opt)
+	if err != nil {
+		return err
+	}
+	fargate := isFargate(launchType, strategy)
+	d.Log("[DEBUG] validating resources of task definition for Fargate:%t", fargate)
+	if err := validateTaskResources(td, fargate); err != nil {
+		return fmt.Errorf("invalid resources of task definition %s: %w", aws.ToString(td.Family), err)
+	}
+	return nil
+}
+
+// validateSecretsForRun validates that the secrets referenced by the containers exist
+// and are accessible with the task execution role.
+func (d *App) validateSecretsForRun(ctx context.Context, td *TaskDefinitionInput) error {
+	v, err := d.newAssumedVerifier(ctx, d.config.awsv2Config, td.ExecutionRoleArn, &VerifyOption{GetSecrets: true})
+	if err != nil {
+		return err
+	}
+	var invalid []string
+	for _, c := range td.ContainerDefinitions {
+		for _, secret := range c.Secrets {
+			valueFrom := aws.ToString(secret.ValueFrom)
+			d.Log("[DEBUG] validating secret %s of container %s: %s", aws.ToString(secret.Name), aws.ToString(c.Name), valueFrom)
+			if err := v.existsSecretValue(ctx, valueFrom); err != nil {
+				invalid = append(invalid, fmt.Sprintf("%s.%s: %s", aws.ToString(c.Name), aws.ToString(secret.Name), err))
+			}
+		}
+	}
+	if len(invalid) > 0 {
+		return fmt.Errorf("secrets of task definition %s do not exist or are not accessible:\n%s", aws.ToString(td.Family), strings.Join(invalid, "\n"))
+	}
+	return nil
+}
+
+func (d *App) validateLoggingForRun(td *TaskDefinitionInput, opt RunOption) error {
+	if opt.ForceAwslogs && !opt.RequireLoggingAll {
+		return nil // the watch container logs to awslogs by --force-awslogs
+	}
+	if err := validateLogging(td, opt.WatchContainer, opt.RequireLoggingAll); err != nil {
+		return fmt.Errorf("task definition %s does not satisfy --require-logging: %w", aws.ToString(td.Family), err)
+	}
+	return nil
+}
+
+// pruneTaskDefinitions deregisters the revisions of the family except the newest keeps and in-use revisions.
+func (d *App) pruneTaskDefinitions(ctx context.Context, family string, keeps int) {
+	d.Log("Pruning task definitions of family %s. keeps %d revisions", family, keeps)
+	inUse, err := d.inUseRevisions(ctx)
+	if err != nil {
+		d.Log("[WARNING] failed to prune task definitions: %s", err)
+		return
+	}
+	opt := DeregisterOption{Keeps: &keeps, Force: true}
+	if err := d.deregisterKeepsFamily(ctx, family, opt, inUse); err != nil {
+		d.Log("[WARNING] failed to prune task definitions: %s", err)
+	}
+}
+
+// taskOverrideForRun reads the overrides from the overrides profile in the config, --overrides-file and --overrides.
+// They are deep-merged in this order, so --overrides takes precedence.
+func (d *App) taskOverrideForRun(ctx context.Context, opt RunOption) (types.TaskOverride, error) {
 	ov := types.TaskOverride{}
-	if opt.TaskOverrideStr != "" {
-		if err := json.Unmarshal([]byte(opt.TaskOverrideStr), &ov); err != nil {
-			return fmt.Errorf("invalid overrides: %w", err)
+	if name := aws.ToString(opt.OverridesProfile); name != "" {
+		profile, err := d.overridesProfile(name)
+		if err != nil {
+			return ov, err
 		}
-	} else if ovFile := opt.TaskOverrideFile; ovFile != "" {
-		src, err := d.readDefinitionFile(ovFile)
+		d.Log("Using overrides profile %s", name)
+		mergeTaskOverride(&ov, *profile)
+	}
+	if ovFile := opt.TaskOverrideFile; ovFile != "" {
+		src, err := d.readOverridesFile(ctx, ovFile)
 		if err != nil {
-			return fmt.Errorf("failed to read overrides-file %s: %w", ovFile, err)
+			return ov, fmt.Errorf("failed to read overrides-file %s: %w", ovFile, err)
 		}
-		if err := unmarshalJSON(src, &ov, ovFile); err != nil {
-			return fmt.Errorf("failed to read overrides-file %s: %w", ovFile, err)
+		if err := d.validateOverridesSchema(opt, src); err != nil {
+			return ov, fmt.Errorf("invalid overrides-file %s: %w", ovFile, err)
+		}
+		fromFile := types.TaskOverride{}
+		decode := func(b []byte) error { return unmarshalJSON(b, &fromFile, ovFile) }
+		if err := decodeOverrides(src, decode, opt.StrictOverrides); err != nil {
+			return ov, fmt.Errorf("failed to read overrides-file %s: %w", ovFile, err)
+		}
+		mergeTaskOverride(&ov, fromFile)
+	}
+	if opt.TaskOverrideStr != "" {
+		if err := d.validateOverridesSchema(opt, []byte(opt.TaskOverrideStr)); err != nil {
+			return ov, fmt.Errorf("invalid overrides: %w", err)
+		}
+		inline := types.TaskOverride{}
+		decode := func(b []byte) error { return json.Unmarshal(b, &inline) }
+		if err := decodeOverrides([]byte(opt.TaskOverrideStr), decode, opt.StrictOverrides); err != nil {
+			return ov, fmt.Errorf("invalid overrides: %w", err)
+		}
+		if opt.TaskOverrideFile != "" || opt.OverridesProfile != nil {
+			d.Log("[DEBUG] merging overrides over the overrides profile or overrides-file")
+		}
+		mergeTaskOverride(&ov, inline)
+	}
+	return ov, nil
+}
+
+func (d *App) validateOverridesSchema(opt RunOption, src []byte) error {
+	if opt.OverridesSchema == nil {
+		return nil
+	}
+	d.Log("[DEBUG] validating overrides with JSON schema %s", *opt.OverridesSchema)
+	return validateJSONSchema(*opt.OverridesSchema, src)
+}
+
+// decodeOverrides decodes src as JSON. If it fails and strict is false,
+// decodes src again as JSON with comments and trailing commas.
+func decodeOverrides(src []byte, decode func([]byte) error, strict bool) error {
+	err := decode(src)
+	if err == nil || strict {
+		return err
+	}
+	if relaxedErr := decode(relaxJSON(src)); relaxedErr != nil {
+		return err
+	}
+	return nil
+}
+
+func (d *App) RunTask(ctx context.Context, tdArn string, ov *types.TaskOverride, opt *RunOption) (*types.Task, error) {
+	tasks, err := d.runTasks(ctx, tdArn, ov, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
+}
+
+// runTasks runs the tasks and returns all the launched tasks (--count).
+func (d *App) runTasks(ctx context.Context, tdArn string, ov *types.TaskOverride, opt *RunOption) ([]types.Task, error) {
+	d.Log("Running task with %s", tdArn)
+
+	in, err := d.runTaskInput(ctx, tdArn, ov, opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.RunTaskRate > 0 {
+		d.Log("[DEBUG] RunTask is paced at %.2f calls/sec", opt.RunTaskRate)
+	}
+	var tasks []types.Task
+	if len(opt.CapacityProviderCascade) > 0 {
+		tasks, err = d.runTaskWithCascade(ctx, in, opt)
+	} else {
+		tasks, err = d.submitRunTaskWithRetry(ctx, in, opt.RunTaskRate, opt.MaxRetries)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if path := aws.ToString(opt.ArnFile); path != "" {
+		if err := writeTaskArnFile(path, tasks); err != nil {
+			return nil, err
 		}
+		d.Log("Task ARN is written to %s", path)
 	}
-	d.Log("[DEBUG] Overrides")
-	d.LogJSON(ov)
+	return tasks, nil
+}
 
-	tdArn, err := d.taskDefinitionArnForRun(ctx, opt)
-	if err != nil {
-		return err
-	}
-	d.Log("Task definition ARN: %s", tdArn)
-	if opt.DryRun {
-		d.Log("DRY RUN OK")
-		return nil
+// writeTaskArnFile writes the ARNs of the tasks to the file, one per line.
+func writeTaskArnFile(path string, tasks []types.Task) error {
+	var b strings.Builder
+	for _, t := range tasks {
+		b.WriteString(aws.ToString(t.TaskArn) + "\n")
 	}
-	td, err := d.DescribeTaskDefinition(ctx, tdArn)
-	if err != nil {
-		return err
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write task ARN to %s: %w", path, err)
 	}
-	watchContainer := containerOf(td, &opt.WatchContainer)
-	d.Log("Watch container: %s", *watchContainer.Name)
+	return nil
+}
 
-	task, err := d.RunTask(ctx, tdArn, &ov, &opt)
+// submitRunTask calls RunTask API and returns the tasks.
+func (d *App) submitRunTask(ctx context.Context, in *ecs.RunTaskInput, rate float64) ([]types.Task, error) {
+	out, err := d.callRunTask(ctx, in, rate)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("failed to run task: %w", err)
 	}
-	if !opt.Wait {
-		d.Log("Run task invoked")
-		return nil
+	if len(out.Failures) > 0 {
+		f := out.Failures[0]
+		if f.Arn != nil {
+			d.Log("Task ARN: %s", *f.Arn)
+		}
+		if isPlacementFailure(aws.ToString(f.Reason)) {
+			d.logClusterCapacity(ctx)
+			return nil, &ErrPlacementFailure{
+				Reason:               aws.ToString(f.Reason),
+				Detail:               aws.ToString(f.Detail),
+				PlacementConstraints: in.PlacementConstraints,
+				PlacementStrategy:    in.PlacementStrategy,
+			}
+		}
+		return nil, fmt.Errorf("failed to run task: %s %s", aws.ToString(f.Reason), aws.ToString(f.Detail))
 	}
-	if err := d.WaitRunTask(ctx, task, watchContainer, time.Now(), opt.waitUntilRunning()); err != nil {
-		return err
+
+	if len(out.Tasks) == 0 {
+		return nil, fmt.Errorf("failed to run task: no tasks run")
 	}
-	if err := d.DescribeTaskStatus(ctx, task, watchContainer); err != nil {
-		return err
+	for _, task := range out.Tasks {
+		d.Log("Task ARN: %s", aws.ToString(task.TaskArn))
 	}
-	d.Log("Run task completed!")
-
-	return nil
+	return out.Tasks, nil
 }
 
-func (d *App) RunTask(ctx context.Context, tdArn string, ov *types.TaskOverride, opt *RunOption) (*types.Task, error) {
-	d.Log("Running task with %s", tdArn)
+func (d *App) scheduleRunForRun(ctx context.Context, tdArn string, ov *types.TaskOverride, opt RunOption) (*RunResult, error) {
+	d.Log("Scheduling task with %s at %s", tdArn, opt.At.Format(time.RFC3339))
+	in, err := d.runTaskInput(ctx, tdArn, ov, &opt)
+	if err != nil {
+		return nil, err
+	}
+	if opt.StartedBy != nil || opt.StartedByTemplate != "" || len(in.VolumeConfigurations) > 0 || in.ClientToken != nil {
+		d.Log("[WARNING] startedBy, volume configurations and client token are not supported by EventBridge Scheduler. ignored")
+	}
+	arn, err := d.scheduleRunTask(ctx, in, *opt.At, opt.ScheduleRoleArn)
+	if err != nil {
+		return nil, err
+	}
+	d.Log("Schedule %s is created. The task will run at %s", arn, opt.At.Format(time.RFC3339))
+	return &RunResult{TaskDefinitionArn: tdArn}, nil
+}
 
+// runTaskInput builds the input of RunTask API from the service definition and the options.
+func (d *App) runTaskInput(ctx context.Context, tdArn string, ov *types.TaskOverride, opt *RunOption) (*ecs.RunTaskInput, error) {
 	sv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
 	if err != nil {
 		return nil, err
 	}
 
-	tags, err := parseTags(opt.Tags)
+	tags, err := d.tagsForRun(opt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run task. %w", err)
+	}
+	// the run ID takes precedence over --tags
+	tags = mergeTags(tags, runIDTags(aws.ToString(opt.RunID)))
+	defaultTags := d.defaultTagsForRun(opt)
+	launchType, strategy, err := launchSettingsForRun(sv, opt)
+	if err != nil {
+		return nil, err
+	}
+	fargate := isFargate(launchType, strategy)
+	networkConfiguration, err := networkConfigurationForRun(sv, opt, fargate)
 	if err != nil {
-		return nil, fmt.Errorf("failed to run task. invalid tags: %w", err)
+		return nil, err
 	}
 
 	in := &ecs.RunTaskInput{
 		Cluster:                  aws.String(d.Cluster),
 		TaskDefinition:           aws.String(tdArn),
-		NetworkConfiguration:     sv.NetworkConfiguration,
-		LaunchType:               sv.LaunchType,
+		NetworkConfiguration:     networkConfiguration,
+		LaunchType:               launchType,
 		Overrides:                ov,
 		Count:                    &opt.Count,
-		CapacityProviderStrategy: sv.CapacityProviderStrategy,
+		CapacityProviderStrategy: strategy,
 		PlacementConstraints:     sv.PlacementConstraints,
 		PlacementStrategy:        sv.PlacementStrategy,
 		PlatformVersion:          sv.PlatformVersion,
-		Tags:                     tags,
+		Tags:                     mergeTags(defaultTags, tags),
 		EnableECSManagedTags:     sv.EnableECSManagedTags,
 		EnableExecuteCommand:     sv.EnableExecuteCommand,
 		ClientToken:              opt.ClientToken,
@@ -132,92 +1025,472 @@ func (d *App) RunTask(ctx context.Context, tdArn string, ov *types.TaskOverride,
 		),
 	}
 
-	switch opt.PropagateTags {
-	case "SERVICE":
-		out, err := d.ecs.ListTagsForResource(ctx, &ecs.ListTagsForResourceInput{
-			ResourceArn: sv.ServiceArn,
-		})
+	if fargate != isFargate(sv.LaunchType, sv.CapacityProviderStrategy) {
+		// --launch-type or --capacity-provider-strategy switches between Fargate and EC2.
+		// RunTask rejects the placement for Fargate and the platform version for EC2.
+		if fargate {
+			d.Log("[DEBUG] the placement constraints and strategy of the service definition are not applied to Fargate")
+			in.PlacementConstraints = nil
+			in.PlacementStrategy = nil
+		} else {
+			d.Log("[DEBUG] the platform version of the service definition is not applied to EC2")
+			in.PlatformVersion = nil
+		}
+	}
+	if opt.PlatformVersion != "" {
+		in.PlatformVersion = aws.String(opt.PlatformVersion)
+	}
+
+	startedBy, err := d.startedByForRun(opt)
+	if err != nil {
+		return nil, err
+	}
+	in.StartedBy = aws.String(startedBy)
+
+	if opt.DebugSidecar != nil {
+		d.Log("[DEBUG] enable execute command for the debug sidecar %s", debugSidecarName)
+		in.EnableExecuteCommand = true
+	}
+
+	propagate, err := parsePropagateTags(opt.PropagateTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run task. %w", err)
+	}
+	d.Log("[DEBUG] propagate tags: %s", propagate)
+	switch {
+	case propagate.none:
+		// do not propagate any tags explicitly. only --tags are set
+		in.PropagateTags = ""
+	case propagate.service && propagate.taskDefinition:
+		svTags, err := d.serviceTagsForRun(ctx, sv, tdArn, opt)
+		if err != nil {
+			return nil, err
+		}
+		td, err := d.DescribeTaskDefinition(ctx, tdArn)
+		if err != nil {
+			return nil, err
+		}
+		d.Log("[DEBUG] propagate tags from task definition %s", tdArn)
+		// precedence: --tags > service > task definition > default tags
+		in.Tags = mergeTags(defaultTags, td.Tags, svTags, tags)
+		d.Log("[DEBUG] merged tags: %s", tagsToString(in.Tags))
+	case propagate.service:
+		svTags, err := d.serviceTagsForRun(ctx, sv, tdArn, opt)
 		if err != nil {
-			return nil, fmt.Errorf("failed to list tags for service: %w", err)
+			return nil, err
 		}
-		d.Log("[DEBUG] propagate tags from service %s", *sv.ServiceArn)
-		d.LogJSON(out)
-		in.Tags = append(in.Tags, out.Tags...)
-	case "", "NONE":
+		in.Tags = mergeTags(defaultTags, svTags, tags)
+		d.Log("[DEBUG] merged tags: %s", tagsToString(in.Tags))
+	case propagate.taskDefinition:
+		in.PropagateTags = types.PropagateTagsTaskDefinition
+	default:
 		// XXX ECS says > InvalidParameterException: Invalid value for propagateTags
 		// in.PropagateTags = types.PropagateTagsNone
 		in.PropagateTags = ""
-	default:
-		in.PropagateTags = types.PropagateTagsTaskDefinition
 	}
 	d.Log("[DEBUG] run task input")
 	d.LogJSON(in)
+	return in, nil
+}
+
+// launchSettingsForRun returns the launch type and the capacity provider strategy of the service definition
+// overridden by --launch-type or --capacity-provider-strategy.
+// The launch type must be empty when the capacity provider strategy is set in RunTask API, and vice versa.
+func launchSettingsForRun(sv *Service, opt *RunOption) (types.LaunchType, []types.CapacityProviderStrategyItem, error) {
+	switch {
+	case opt.LaunchType != "":
+		lt := types.LaunchType(strings.ToUpper(opt.LaunchType))
+		if !lo.Contains(lt.Values(), lt) {
+			return "", nil, fmt.Errorf("invalid launch-type %s. %v is required", opt.LaunchType, lt.Values())
+		}
+		return lt, nil, nil
+	case opt.CapacityProviderStrategy != "":
+		var strategy []types.CapacityProviderStrategyItem
+		if err := json.Unmarshal([]byte(opt.CapacityProviderStrategy), &strategy); err != nil {
+			return "", nil, fmt.Errorf("invalid capacity-provider-strategy: %w", err)
+		}
+		if len(strategy) == 0 {
+			return "", nil, fmt.Errorf("invalid capacity-provider-strategy: at least one capacity provider is required")
+		}
+		for _, item := range strategy {
+			if aws.ToString(item.CapacityProvider) == "" {
+				return "", nil, fmt.Errorf("invalid capacity-provider-strategy: capacityProvider is required")
+			}
+		}
+		return "", strategy, nil
+	}
+	return sv.LaunchType, sv.CapacityProviderStrategy, nil
+}
+
+// networkConfigurationForRun returns the network configuration of the service definition
+// patched by --subnets, --security-groups and --assign-public-ip.
+// Fargate requires the awsvpc network mode, so the subnets are required.
+func networkConfigurationForRun(sv *Service, opt *RunOption, fargate bool) (*types.NetworkConfiguration, error) {
+	nc := sv.NetworkConfiguration
+	if len(opt.Subnets) > 0 || len(opt.SecurityGroups) > 0 || opt.AssignPublicIp != "" {
+		// do not modify the service definition
+		vpc := types.AwsVpcConfiguration{}
+		if nc != nil && nc.AwsvpcConfiguration != nil {
+			vpc = *nc.AwsvpcConfiguration
+		}
+		if len(opt.Subnets) > 0 {
+			vpc.Subnets = opt.Subnets
+		}
+		if len(opt.SecurityGroups) > 0 {
+			vpc.SecurityGroups = opt.SecurityGroups
+		}
+		if opt.AssignPublicIp != "" {
+			vpc.AssignPublicIp = types.AssignPublicIp(opt.AssignPublicIp)
+		}
+		nc = &types.NetworkConfiguration{AwsvpcConfiguration: &vpc}
+	}
+	if fargate && (nc == nil || nc.AwsvpcConfiguration == nil || len(nc.AwsvpcConfiguration.Subnets) == 0) {
+		return nil, fmt.Errorf("subnets are required for the awsvpc network configuration of Fargate. specify --subnets or networkConfiguration in the service definition")
+	}
+	return nc, nil
+}
+
+// defaultTagsForRun returns the default tags of the config overridden by --default-tag.
+func (d *App) defaultTagsForRun(opt *RunOption) []types.Tag {
+	return mergeTags(mapToTags(d.config.DefaultTags), mapToTags(opt.DefaultTags))
+}
 
-	out, err := d.ecs.RunTask(ctx, in)
+// isPlacementFailure reports whether the reason of RunTask failure is related to task placement.
+// See https://docs.aws.amazon.com/AmazonECS/latest/developerguide/api_failures_messages.html
+func isPlacementFailure(reason string) bool {
+	switch {
+	case strings.HasPrefix(reason, "RESOURCE:"),
+		strings.HasPrefix(reason, "ATTRIBUTE"),
+		strings.HasPrefix(reason, "AGENT"),
+		strings.HasPrefix(reason, "LOCATION"),
+		strings.HasPrefix(reason, "MemberOf"),
+		strings.HasPrefix(reason, "DistinctInstance"),
+		strings.HasPrefix(reason, "Capacity is unavailable"):
+		return true
+	}
+	return false
+}
+
+func (d *App) logClusterCapacity(ctx context.Context) {
+	out, err := d.ecs.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{d.Cluster},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to run task: %w", err)
+		d.Log("[DEBUG] failed to describe cluster %s: %s", d.Cluster, err)
+		return
 	}
-	if len(out.Failures) > 0 {
-		f := out.Failures[0]
-		if f.Arn != nil {
-			d.Log("Task ARN: %s", *f.Arn)
+	for _, c := range out.Clusters {
+		d.Log("[DEBUG] cluster %s has %d registered container instances", aws.ToString(c.ClusterName), c.RegisteredContainerInstancesCount)
+	}
+}
+
+const maxStartedByLength = 128
+
+var invalidStartedByChars = regexp.MustCompile(`[^a-zA-Z0-9_/-]+`)
+
+// defaultStartedBy is startedBy of the task run by ecspresso to distinguish it from the tasks of the service.
+const defaultStartedBy = "ecspresso-run"
+
+// startedByForRun returns startedBy of the task from --started-by-template or --started-by.
+// The default is used when the value is empty.
+func (d *App) startedByForRun(opt *RunOption) (string, error) {
+	var startedBy string
+	switch {
+	case opt.StartedByTemplate != "":
+		s, err := d.renderStartedBy(opt.StartedByTemplate)
+		if err != nil {
+			return "", err
 		}
-		return nil, fmt.Errorf("failed to run task: %s %s", aws.ToString(f.Reason), aws.ToString(f.Detail))
+		startedBy = s
+	case opt.StartedBy != nil:
+		startedBy = d.sanitizeStartedBy(*opt.StartedBy)
 	}
+	if startedBy == "" {
+		return defaultStartedBy, nil
+	}
+	return startedBy, nil
+}
 
-	if len(out.Tasks) == 0 {
-		return nil, fmt.Errorf("failed to run task: no tasks run")
+// renderStartedBy renders the template of startedBy.
+func (d *App) renderStartedBy(tmpl string) (string, error) {
+	b, err := d.loader.ReadWithEnvBytes([]byte(tmpl))
+	if err != nil {
+		return "", fmt.Errorf("failed to render started-by-template: %w", err)
+	}
+	return d.sanitizeStartedBy(string(b)), nil
+}
+
+// sanitizeStartedBy replaces characters not allowed by ECS with "-", and truncates the value to 128 characters.
+func (d *App) sanitizeStartedBy(s string) string {
+	full := strings.TrimSpace(s)
+	d.Log("[DEBUG] startedBy: %s", full)
+	startedBy := invalidStartedByChars.ReplaceAllString(full, "-")
+	if len(startedBy) > maxStartedByLength {
+		d.Log("[WARNING] startedBy is truncated to %d characters: %s", maxStartedByLength, full)
+		startedBy = startedBy[:maxStartedByLength]
+	}
+	return startedBy
+}
+
+type propagateTagsSources struct {
+	none           bool
+	service        bool
+	taskDefinition bool
+}
+
+// String returns the propagation mode for logging.
+func (p propagateTagsSources) String() string {
+	var modes []string
+	if p.none {
+		modes = append(modes, "NONE")
+	}
+	if p.service {
+		modes = append(modes, "SERVICE")
+	}
+	if p.taskDefinition {
+		modes = append(modes, "TASK_DEFINITION")
+	}
+	if len(modes) == 0 {
+		return "(not set)"
+	}
+	return strings.Join(modes, ",")
+}
+
+func parsePropagateTags(s string) (propagateTagsSources, error) {
+	var p propagateTagsSources
+	for _, src := range strings.Split(s, ",") {
+		switch strings.ToUpper(strings.TrimSpace(src)) {
+		case "SERVICE":
+			p.service = true
+		case "TASK_DEFINITION":
+			p.taskDefinition = true
+		case "NONE":
+			p.none = true
+		case "":
+		default:
+			return p, fmt.Errorf("invalid propagate-tags: %s", src)
+		}
+	}
+	if p.none && (p.service || p.taskDefinition) {
+		return p, fmt.Errorf("invalid propagate-tags: NONE cannot be combined with other sources: %s", s)
+	}
+	return p, nil
+}
+
+func (d *App) serviceTagsForRun(ctx context.Context, sv *Service, tdArn string, opt *RunOption) ([]types.Tag, error) {
+	// the tags of the service may be meaningless for the task of an unrelated family
+	if svFamily, family := d.serviceFamilyForRun(ctx, sv), familyOfTaskDefinitionArn(tdArn); svFamily != "" && svFamily != family {
+		if !opt.ForcePropagateServiceTags {
+			d.Log("[WARNING] the task definition family %s differs from the family %s of the service. tags are not propagated from the service. --force-propagate-service-tags propagates them", family, svFamily)
+			return nil, nil
+		}
+		d.Log("[WARNING] the task definition family %s differs from the family %s of the service. tags are propagated from the service by --force-propagate-service-tags", family, svFamily)
+	}
+	svArn, err := d.serviceArnForRun(ctx, sv)
+	if err != nil {
+		if opt.IgnoreMissingService {
+			d.Log("[WARNING] %s. tags are not propagated from the service", err)
+			return nil, nil
+		}
+		return nil, err
+	}
+	out, err := d.ecs.ListTagsForResource(ctx, &ecs.ListTagsForResourceInput{
+		ResourceArn: aws.String(svArn),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tags for service: %w", err)
+	}
+	d.Log("[DEBUG] propagate tags from service %s", svArn)
+	d.LogJSON(out)
+	return out.Tags, nil
+}
+
+// serviceFamilyForRun returns the task definition family of the service.
+// An empty string is returned when the family is unknown (e.g. the service is not yet created).
+func (d *App) serviceFamilyForRun(ctx context.Context, sv *Service) string {
+	if td := aws.ToString(sv.TaskDefinition); td != "" {
+		return familyOfTaskDefinitionArn(td)
+	}
+	if d.Service == "" {
+		return ""
+	}
+	out, err := d.ecs.DescribeServices(ctx, d.DescribeServicesInput())
+	if err != nil {
+		d.Log("[DEBUG] failed to describe service: %s", err)
+		return ""
+	}
+	for _, s := range out.Services {
+		if td := aws.ToString(s.TaskDefinition); td != "" && aws.ToString(s.Status) != "INACTIVE" {
+			return familyOfTaskDefinitionArn(td)
+		}
+	}
+	return ""
+}
+
+// serviceArnForRun returns the ARN of the service to propagate the tags.
+// The service definition usually has no ARN, so the service is described when the ARN is empty.
+func (d *App) serviceArnForRun(ctx context.Context, sv *Service) (string, error) {
+	if svArn := aws.ToString(sv.ServiceArn); svArn != "" {
+		return svArn, nil
+	}
+	if d.Service == "" {
+		return "", ErrNotFound("cannot propagate tags from service: service is not defined in the config")
+	}
+	out, err := d.ecs.DescribeServices(ctx, d.DescribeServicesInput())
+	if err != nil {
+		return "", fmt.Errorf("failed to describe service: %w", err)
+	}
+	for _, s := range out.Services {
+		if svArn := aws.ToString(s.ServiceArn); svArn != "" && aws.ToString(s.Status) != "INACTIVE" {
+			return svArn, nil
+		}
+	}
+	return "", ErrNotFound(fmt.Sprintf("cannot propagate tags from service: service %s is not found or not yet created", d.Service))
+}
+
+func tagsToString(tags []types.Tag) string {
+	p := make([]string, 0, len(tags))
+	for _, t := range tags {
+		p = append(p, aws.ToString(t.Key)+"="+aws.ToString(t.Value))
+	}
+	return strings.Join(p, ",")
+}
+
+// WaitTask waits for the tasks until stopped, or until running when untilRunning is true.
+// It does not tail the logs of the tasks. WaitRunTask also tails the logs.
+func (d *App) WaitTask(ctx context.Context, tasks []types.Task, untilRunning bool) error {
+	if len(tasks) == 0 {
+		return fmt.Errorf("no tasks to wait")
+	}
+	opt := RunOption{WaitUntil: "stopped"}
+	if untilRunning {
+		opt.WaitUntil = "running"
 	}
-	task := out.Tasks[0]
-	d.Log("Task ARN: %s", aws.ToString(task.TaskArn))
-	return &task, nil
+	return d.waitTasks(ctx, tasks, opt)
 }
 
+// WaitRunTask waits for the task and tails the logs of the watch container.
 func (d *App) WaitRunTask(ctx context.Context, task *types.Task, watchContainer *types.ContainerDefinition, startedAt time.Time, untilRunning bool) error {
+	opt := RunOption{
+		WaitUntil:        "stopped",
+		LogPollInterval:  logPollInterval,
+		LogStreamWait:    logStreamWait,
+		ProgressInterval: progressInterval,
+	}
+	if untilRunning {
+		opt.WaitUntil = "running"
+	}
+	return d.waitRunTask(ctx, []types.Task{*task}, []*types.ContainerDefinition{watchContainer}, startedAt, opt)
+}
+
+// waitRunTask waits for the tasks and tails the logs of the containers of the first task configured with awslogs.
+func (d *App) waitRunTask(ctx context.Context, tasks []types.Task, containers []*types.ContainerDefinition, startedAt time.Time, opt RunOption) error {
 	d.Log("Waiting for run task...(it may take a while)")
+	task := &tasks[0]
 	waitCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	lc := watchContainer.LogConfiguration
-	if lc == nil || lc.LogDriver != types.LogDriverAwslogs || lc.Options["awslogs-stream-prefix"] == "" {
-		d.Log("awslogs not configured")
-		if err := d.waitTask(ctx, task, untilRunning); err != nil {
-			return err
-		}
-		return nil
+	if opt.TaskEvents {
+		r := newTaskEventReporter()
+		go d.watchTaskEvents(waitCtx, task, opt.PollInterval, r)
+		// report the last events after the wait
+		defer d.describeTaskEvents(ctx, task, r)
 	}
 
-	d.Log("Watching container: %s", *watchContainer.Name)
-	logGroup, logStream := d.GetLogInfo(task, watchContainer)
-	time.Sleep(3 * time.Second) // wait for log stream
-
-	go func() {
-		ticker := time.NewTicker(5 * time.Second)
-		var nextToken *string
-		for {
-			select {
-			case <-waitCtx.Done():
-				return
-			case <-ticker.C:
-				nextToken, _ = d.GetLogEvents(waitCtx, logGroup, logStream, startedAt, nextToken)
-			}
+	if opt.NoLogTail {
+		d.Log("Log tailing is disabled by --no-log-tail")
+		containers = nil
+	}
+	var streams []*tailStream
+	for _, c := range containers {
+		lc := c.LogConfiguration
+		if lc == nil || lc.LogDriver != types.LogDriverAwslogs || lc.Options["awslogs-stream-prefix"] == "" {
+			d.Log("awslogs not configured for container %s", aws.ToString(c.Name))
+			continue
+		}
+		d.Log("Watching container: %s", *c.Name)
+		logGroup, logStream := d.GetLogInfo(task, c)
+		s := &tailStream{
+			group:         logGroup,
+			stream:        logStream,
+			fromHead:      opt.FromStart,
+			filterPattern: aws.ToString(opt.LogFilterPattern),
+		}
+		if len(containers) > 1 {
+			s.prefix = "[" + aws.ToString(c.Name) + "] "
 		}
-	}()
+		streams = append(streams, s)
+	}
+	if len(streams) > 0 {
+		if opt.LogStreamWait > 0 {
+			d.waitLogStreams(waitCtx, task, streams, opt.LogStreamWait)
+		}
+		go d.tailLogs(waitCtx, streams, startedAt, opt.LogPollInterval, opt.LogPollConcurrency)
+	} else if opt.ProgressInterval > 0 {
+		// the progress is quiet while tailing the logs not to interleave them
+		go d.reportTaskProgress(waitCtx, task, startedAt, opt.ProgressInterval)
+	}
 
-	if err := d.waitTask(ctx, task, untilRunning); err != nil {
+	if err := d.waitTasks(ctx, tasks, opt); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (d *App) waitTask(ctx context.Context, task *types.Task, untilRunning bool) error {
-	id := arnToName(*task.TaskArn)
-	if untilRunning {
+// logContainers returns the containers to tail the logs.
+// With --watch-all, all the containers configured with awslogs are returned with the watch container first.
+func logContainers(td *TaskDefinitionInput, watchContainer *types.ContainerDefinition, all bool) []*types.ContainerDefinition {
+	containers := []*types.ContainerDefinition{watchContainer}
+	if !all {
+		return containers
+	}
+	for i := range td.ContainerDefinitions {
+		c := &td.ContainerDefinitions[i]
+		if aws.ToString(c.Name) == aws.ToString(watchContainer.Name) {
+			continue
+		}
+		if lc := c.LogConfiguration; lc != nil && lc.LogDriver == types.LogDriverAwslogs {
+			containers = append(containers, c)
+		}
+	}
+	return containers
+}
+
+func (d *App) waitTask(ctx context.Context, task *types.Task, opt RunOption) error {
+	return d.waitTasks(ctx, []types.Task{*task}, opt)
+}
+
+// waitTasks waits for all the tasks launched at once (--count).
+func (d *App) waitTasks(ctx context.Context, tasks []types.Task, opt RunOption) error {
+	if opt.CustomWaiter {
+		if len(tasks) > 1 {
+			// the timeout is shared by all the tasks
+			if timeout := d.waitTimeout(); timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+		}
+		for i := range tasks {
+			if err := d.pollTask(ctx, &tasks[i], opt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	ids := make([]string, 0, len(tasks))
+	for _, t := range tasks {
+		ids = append(ids, arnToName(aws.ToString(t.TaskArn)))
+	}
+	id := strings.Join(ids, ", ")
+	in := d.DescribeTasksInput(&tasks[0])
+	in.Tasks = lo.Map(tasks, func(t types.Task, _ int) string { return aws.ToString(t.TaskArn) })
+	if opt.waitUntilRunning() {
 		d.Log("Waiting for task ID %s until running", id)
 		waiter := ecs.NewTasksRunningWaiter(d.ecs, func(o *ecs.TasksRunningWaiterOptions) {
 			o.MaxDelay = waiterMaxDelay
 		})
-		if err := waiter.Wait(ctx, d.DescribeTasksInput(task), d.Timeout()); err != nil {
+		if err := waiter.Wait(ctx, in, d.waitTimeout()); err != nil {
 			return err
 		}
 		d.Log("Task ID %s is running", id)
@@ -228,12 +1501,96 @@ func (d *App) waitTask(ctx context.Context, task *types.Task, untilRunning bool)
 	waiter := ecs.NewTasksStoppedWaiter(d.ecs, func(o *ecs.TasksStoppedWaiterOptions) {
 		o.MaxDelay = waiterMaxDelay
 	})
-	if err := waiter.Wait(ctx, d.DescribeTasksInput(task), d.Timeout()); err != nil {
+	if err := waiter.Wait(ctx, in, d.waitTimeout()); err != nil {
 		return fmt.Errorf("failed to wait task: %w", err)
 	}
 	return nil
 }
 
+// pollTask waits for the task by polling DescribeTasks at opt.PollInterval.
+// With --poll-backoff=exponential, the interval doubles up to opt.PollMaxDelay.
+func (d *App) pollTask(ctx context.Context, task *types.Task, opt RunOption) error {
+	id := arnToName(*task.TaskArn)
+	interval := opt.PollInterval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	backoff := pollBackoff{
+		exponential: opt.PollBackoff == "exponential",
+		base:        interval,
+		max:         opt.PollMaxDelay,
+	}
+	timeout := d.waitTimeout()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	until := "STOPPED"
+	if opt.waitUntilRunning() {
+		until = "RUNNING"
+	}
+	if backoff.exponential {
+		d.Log("Waiting for task ID %s until %s (polling with exponential backoff from %s up to %s)", id, strings.ToLower(until), interval, backoff.max)
+	} else {
+		d.Log("Waiting for task ID %s until %s (polling every %s)", id, strings.ToLower(until), interval)
+	}
+	if timeout > 0 {
+		d.Log("[DEBUG] polls at most %d times in %s", backoff.maxPolls(timeout), timeout)
+	}
+
+	var lastStatus string
+	for attempt := 0; ; attempt++ {
+		out, err := d.ecs.DescribeTasks(ctx, d.DescribeTasksInput(task))
+		if err != nil {
+			return fmt.Errorf("failed to wait task: %w", err)
+		}
+		if len(out.Failures) > 0 {
+			f := out.Failures[0]
+			return fmt.Errorf("failed to wait task: %s %s", aws.ToString(f.Reason), aws.ToString(f.Detail))
+		}
+		if len(out.Tasks) == 0 {
+			return fmt.Errorf("failed to wait task: task ID %s is not found", id)
+		}
+		t := out.Tasks[0]
+		status := aws.ToString(t.LastStatus)
+		if status != lastStatus {
+			d.Log("Task ID %s is %s", id, status)
+			lastStatus = status
+		}
+		switch {
+		case status == until:
+			return nil
+		case status == "STOPPED":
+			// stopped before running
+			return fmt.Errorf("failed to wait task: task ID %s stopped: %s", id, aws.ToString(t.StoppedReason))
+		}
+		timer := time.NewTimer(backoff.jitter(backoff.delay(attempt)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("failed to wait task: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// taskDefinitionRevision returns family:revision after verifying the revision exists,
+// to fail fast with a clear message instead of an opaque RunTask failure.
+func (d *App) taskDefinitionRevision(ctx context.Context, family string, revision int64) (string, error) {
+	name := fmt.Sprintf("%s:%d", family, revision)
+	if _, err := d.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+		TaskDefinition: aws.String(name),
+	}); err != nil {
+		var ce *types.ClientException
+		if errors.As(err, &ce) {
+			return "", ErrNotFound(fmt.Sprintf("revision %d of family %s is not found: %s", revision, family, ce.ErrorMessage()))
+		}
+		return "", fmt.Errorf("failed to describe task definition %s: %w", name, err)
+	}
+	return name, nil
+}
+
 func (d *App) taskDefinitionArnForRun(ctx context.Context, opt RunOption) (string, error) {
 	switch {
 	case *opt.Revision > 0:
@@ -246,7 +1603,19 @@ func (d *App) taskDefinitionArnForRun(ctx context.Context, opt RunOption) (strin
 		if err != nil {
 			return "", err
 		}
-		return fmt.Sprintf("%s:%d", family, *opt.Revision), nil
+		return d.taskDefinitionRevision(ctx, family, *opt.Revision)
+	case opt.BySemver != nil:
+		family, _, err := d.resolveTaskdefinition(ctx)
+		if err != nil {
+			return "", err
+		}
+		return d.findTaskDefinitionArnBySemver(ctx, family, *opt.BySemver, opt.SemverTag)
+	case opt.LastGood:
+		family, _, err := d.resolveTaskdefinition(ctx)
+		if err != nil {
+			return "", err
+		}
+		return d.findLastGoodTaskDefinitionArn(ctx, family, opt)
 	case opt.LatestTaskDefinition:
 		family, _, err := d.resolveTaskdefinition(ctx)
 		if err != nil {
@@ -281,10 +1650,29 @@ func (d *App) taskDefinitionArnForRun(ctx context.Context, opt RunOption) (strin
 		if err != nil {
 			return "", err
 		}
+		if d.taskDefinitionHook != nil {
+			if err := d.taskDefinitionHook(in); err != nil {
+				return "", fmt.Errorf("task definition hook failed: %w", err)
+			}
+		}
 		{
 			b, _ := MarshalJSONForAPI(in)
 			d.Log("[DEBUG] task definition: %s", string(b))
 		}
+		if opt.ReuseTaskDefinition || opt.NoRegister {
+			tdArn, err := d.findIdenticalTaskDefinitionArn(ctx, in)
+			if err != nil {
+				return "", err
+			}
+			if tdArn != "" {
+				d.Log("Reusing the identical task definition %s", arnToName(tdArn))
+				return tdArn, nil
+			}
+			if opt.NoRegister {
+				return "", ErrNotFound(fmt.Sprintf("no ACTIVE revision of family %s is identical to %s. register it by `ecspresso register` or run without --no-register", *in.Family, tdPath))
+			}
+			d.Log("No identical task definition is found")
+		}
 		if opt.DryRun {
 			return fmt.Sprintf("family %s will be registered", *in.Family), nil
 		}
@@ -296,6 +1684,48 @@ func (d *App) taskDefinitionArnForRun(ctx context.Context, opt RunOption) (strin
 	}
 }
 
+// findLastGoodTaskDefinitionArn finds the task definition of the latest stopped task whose watch container exited with 0.
+// Only the tasks of startedBy of the run are searched.
+func (d *App) findLastGoodTaskDefinitionArn(ctx context.Context, family string, opt RunOption) (string, error) {
+	// the tasks of the service must not be taken as the last good task
+	startedBy, err := d.startedByForRun(&opt)
+	if err != nil {
+		return "", err
+	}
+	tasks, err := d.stoppedTasks(ctx, family, &startedBy)
+	if err != nil {
+		return "", err
+	}
+	// the index of the watch container is resolved by the task definition of each task
+	names := map[string]string{}
+	for _, task := range tasks {
+		name := opt.WatchContainer
+		if isContainerIndex(name) {
+			tdArn := aws.ToString(task.TaskDefinitionArn)
+			if _, ok := names[tdArn]; !ok {
+				names[tdArn] = ""
+				td, err := d.DescribeTaskDefinition(ctx, tdArn)
+				if err != nil {
+					return "", err
+				}
+				if c, err := watchContainerOf(td, name); err != nil {
+					d.Log("[DEBUG] %s: %s", arnToName(tdArn), err)
+				} else {
+					names[tdArn] = *c.Name
+				}
+			}
+			if name = names[tdArn]; name == "" {
+				continue
+			}
+		}
+		if isSucceededTask(task, name) {
+			d.Log("Use the task definition of the last good task %s", arnToName(aws.ToString(task.TaskArn)))
+			return aws.ToString(task.TaskDefinitionArn), nil
+		}
+	}
+	return "", ErrNotFound(fmt.Sprintf("no succeeded tasks of family %s are found", family))
+}
+
 func (d *App) resolveTaskdefinition(ctx context.Context) (family string, revision string, err error) {
 	if d.config.Service != "" {
 		d.Log("[DEBUG] loading service")
@@ -1,11 +1,21 @@
 package ecspresso_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/aws/smithy-go/middleware"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/kayac/ecspresso/v2"
 )
 
@@ -15,6 +25,7 @@ type taskDefinitionArnForRunSuite struct {
 	opts     []string
 	td       string
 	raiseErr bool
+	notFound bool
 }
 
 var testTaskDefinitionArnForRunSuite = map[string][]taskDefinitionArnForRunSuite{
@@ -27,6 +38,10 @@ var testTaskDefinitionArnForRunSuite = map[string][]taskDefinitionArnForRunSuite
 			opts: []string{"--skip-task-definition", "--revision=42"},
 			td:   "katsubushi:42",
 		},
+		{
+			opts:     []string{"--skip-task-definition", "--revision=99"},
+			notFound: true,
+		},
 		{
 			opts: []string{"--latest-task-definition"},
 			td:   "katsubushi:45",
@@ -53,6 +68,10 @@ var testTaskDefinitionArnForRunSuite = map[string][]taskDefinitionArnForRunSuite
 			opts: []string{"--task-def=tests/run-test-td.json"},
 			td:   "family test will be registered",
 		},
+		{
+			opts: []string{"--last-good"},
+			td:   "katsubushi:40",
+		},
 	},
 	"tests/run-without-sv.yaml": {
 		{
@@ -63,6 +82,10 @@ var testTaskDefinitionArnForRunSuite = map[string][]taskDefinitionArnForRunSuite
 			opts: []string{"--skip-task-definition", "--revision=42"},
 			td:   "katsubushi:42",
 		},
+		{
+			opts:     []string{"--skip-task-definition", "--revision=99"},
+			notFound: true,
+		},
 		{
 			opts: []string{"--latest-task-definition"},
 			td:   "katsubushi:45",
@@ -84,6 +107,10 @@ var testTaskDefinitionArnForRunSuite = map[string][]taskDefinitionArnForRunSuite
 			opts: []string{"--task-def=tests/run-test-td.json"},
 			td:   "family test will be registered",
 		},
+		{
+			opts: []string{"--last-good", "--watch-container=app"},
+			td:   "katsubushi:40",
+		},
 	},
 }
 
@@ -113,6 +140,13 @@ func TestTaskDefinitionArnForRun(t *testing.T) {
 			}
 			opts := *cliopts.Run
 			tdArn, err := app.TaskDefinitionArnForRun(ctx, opts)
+			if s.notFound {
+				var nf ecspresso.ErrNotFound
+				if !errors.As(err, &nf) {
+					t.Errorf("%s %s expected not found error, got %v", config, args, err)
+				}
+				continue
+			}
 			if v2_1_OrLater && s.raiseErr {
 				if err == nil {
 					t.Errorf("%s %s expected error, but got nil", config, args)
@@ -129,3 +163,988 @@ func TestTaskDefinitionArnForRun(t *testing.T) {
 		}
 	}
 }
+
+func TestIsPlacementFailure(t *testing.T) {
+	for reason, expected := range map[string]bool{
+		"RESOURCE:MEMORY": true,
+		"RESOURCE:CPU":    true,
+		"ATTRIBUTE":       true,
+		"AGENT":           true,
+		"Capacity is unavailable at this time. Please try again later or in a different availability zone": true,
+		"MISSING":          false,
+		"InvalidParameter": false,
+		"":                 false,
+	} {
+		if got := ecspresso.IsPlacementFailure(reason); got != expected {
+			t.Errorf("%s expected %t, got %t", reason, expected, got)
+		}
+	}
+}
+
+// newRunTestApp returns the App of tests/run-with-sv.yaml with the mocked AWS SDK.
+func newRunTestApp(t *testing.T, opts ...ecspresso.AppOption) *ecspresso.App {
+	t.Helper()
+	ecspresso.SetAWSV2ConfigLoadOptionsFunc([]func(*config.LoadOptions) error{
+		config.WithRegion("ap-northeast-1"),
+		config.WithAPIOptions([]func(*middleware.Stack) error{
+			SDKTestingMiddleware("katsubushi"),
+		}),
+	})
+	t.Cleanup(ecspresso.ResetAWSV2ConfigLoadOptionsFunc)
+	app, err := ecspresso.New(context.TODO(), &ecspresso.CLIOptions{ConfigFilePath: "tests/run-with-sv.yaml"}, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestCheckClusterTasksForRun(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	// 3 tasks are running in the mock
+	if err := app.CheckClusterTasksForRun(ctx, 5, 2); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err := app.CheckClusterTasksForRun(ctx, 5, 3)
+	if err == nil {
+		t.Fatal("expected error, got nil")
+	}
+	if !strings.Contains(err.Error(), "3 tasks are running or pending") || !strings.Contains(err.Error(), "max 5") {
+		t.Errorf("unexpected error message: %s", err)
+	}
+}
+
+func TestParsePropagateTags(t *testing.T) {
+	for _, s := range []struct {
+		src  string
+		mode string
+		ok   bool
+	}{
+		{src: "", mode: "(not set)", ok: true},
+		{src: "NONE", mode: "NONE", ok: true},
+		{src: "service", mode: "SERVICE", ok: true},
+		{src: "TASK_DEFINITION", mode: "TASK_DEFINITION", ok: true},
+		{src: "SERVICE, TASK_DEFINITION", mode: "SERVICE,TASK_DEFINITION", ok: true},
+		{src: "NONE,SERVICE"},
+		{src: "FOO"},
+	} {
+		mode, err := ecspresso.ParsePropagateTags(s.src)
+		if !s.ok {
+			if err == nil {
+				t.Errorf("%q: expected error, got nil", s.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error %s", s.src, err)
+			continue
+		}
+		if mode != s.mode {
+			t.Errorf("%q: expected %s, got %s", s.src, s.mode, mode)
+		}
+	}
+}
+
+func TestRunMultiRegionDryRun(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	_, cliopts, _, err := ecspresso.ParseCLIv2([]string{
+		"run", "--dry-run", "--latest-task-definition",
+		"--regions=ap-northeast-1", "--regions=us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.Run(ctx, *cliopts.Run); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	_, cliopts, _, err = ecspresso.ParseCLIv2([]string{
+		"run", "--dry-run", "--latest-task-definition", "--overrides={invalid",
+		"--regions=ap-northeast-1", "--regions=us-east-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = app.Run(ctx, *cliopts.Run)
+	if err == nil || !strings.Contains(err.Error(), "failed in 2 of 2 regions") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateFIPSRegion(t *testing.T) {
+	for region, ok := range map[string]bool{
+		"us-east-1":      true,
+		"us-gov-west-1":  true,
+		"ap-northeast-1": false,
+		"":               false,
+	} {
+		err := ecspresso.ValidateFIPSRegion(region)
+		if ok && err != nil {
+			t.Errorf("%s must support FIPS: %s", region, err)
+		}
+		if !ok && err == nil {
+			t.Errorf("%s must not support FIPS", region)
+		}
+	}
+}
+
+func TestTaskOverrideOfTask(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	ov, err := app.TaskOverrideOfTask(ctx, "0001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ov.ContainerOverrides) != 1 || strings.Join(ov.ContainerOverrides[0].Command, " ") != "echo 0001" {
+		t.Errorf("unexpected overrides %#v", ov.ContainerOverrides)
+	}
+}
+
+func TestRunRequireExplicitRevision(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	for _, c := range []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{"run", "--dry-run", "--require-explicit-revision"}, false},
+		{[]string{"run", "--dry-run", "--require-explicit-revision", "--latest-task-definition"}, false},
+		{[]string{"run", "--dry-run", "--require-explicit-revision", "--skip-task-definition", "--revision=39"}, true},
+	} {
+		_, cliopts, _, err := ecspresso.ParseCLIv2(c.args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = app.Run(ctx, *cliopts.Run)
+		if c.ok && err != nil {
+			t.Errorf("%v: unexpected error: %s", c.args, err)
+		}
+		if !c.ok && err == nil {
+			t.Errorf("%v: expected error", c.args)
+		}
+	}
+}
+
+func TestRunResult(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	_, cliopts, _, err := ecspresso.ParseCLIv2([]string{"run", "--dry-run", "--latest-task-definition"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := app.RunResult(ctx, *cliopts.Run)
+	if err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if result != nil {
+		t.Errorf("result of dry run must be nil: %#v", result)
+	}
+
+	_, cliopts, _, err = ecspresso.ParseCLIv2([]string{"run", "--dry-run", "--latest-task-definition", "--regions=us-east-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := app.RunResult(ctx, *cliopts.Run); err == nil {
+		t.Error("expected error for --regions")
+	}
+}
+
+func TestRunResultError(t *testing.T) {
+	statusErr := errors.New("container: app, exit code: 3")
+	for _, c := range []struct {
+		name            string
+		exitCode        *int32
+		processExitCode *int
+		propagate       bool
+		expected        int // 0 means not ErrExitCode
+	}{
+		{name: "not propagated", exitCode: aws.Int32(3)},
+		{name: "propagated", exitCode: aws.Int32(3), propagate: true, expected: 3},
+		{name: "process_exit_code takes precedence", exitCode: aws.Int32(3), processExitCode: aws.Int(75), propagate: true, expected: 75},
+		{name: "no exit code", propagate: true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			err := ecspresso.RunResultError(c.exitCode, c.processExitCode, statusErr, c.propagate)
+			if !errors.Is(err, statusErr) {
+				t.Errorf("error must wrap the status error: %v", err)
+			}
+			var ee *ecspresso.ErrExitCode
+			if !errors.As(err, &ee) {
+				if c.expected != 0 {
+					t.Errorf("expected exit code %d, got %v", c.expected, err)
+				}
+				return
+			}
+			if ee.Code != c.expected {
+				t.Errorf("expected exit code %d, got %d", c.expected, ee.Code)
+			}
+		})
+	}
+}
+
+func TestWriteTaskArnFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "task-arn")
+	if err := os.WriteFile(path, []byte("stale\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tasks := []types.Task{
+		{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001")},
+		{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0002")},
+	}
+	if err := ecspresso.WriteTaskArnFile(path, tasks); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001\narn:aws:ecs:ap-northeast-1:123456789012:task/default/0002\n"
+	if string(b) != expected {
+		t.Errorf("unexpected content %q", string(b))
+	}
+}
+
+func TestRunTaskInputDefaultTags(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/default-tags.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err := app.RunTaskInput(ctx, ecspresso.RunOption{
+		Tags:        "Owner=team-b,Env=dev",
+		DefaultTags: map[string]string{"CostCenter": "data", "Project": "batch"},
+		RunID:       aws.String("run-1"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for _, tag := range in.Tags {
+		if _, ok := got[aws.ToString(tag.Key)]; ok {
+			t.Errorf("duplicated tag %s", aws.ToString(tag.Key))
+		}
+		got[aws.ToString(tag.Key)] = aws.ToString(tag.Value)
+	}
+	expected := map[string]string{
+		"CostCenter":       "data",
+		"Project":          "batch",
+		"Owner":            "team-b",
+		"Env":              "dev",
+		"ecspresso:run-id": "run-1",
+	}
+	if diff := cmp.Diff(expected, got); diff != "" {
+		t.Error(diff)
+	}
+}
+
+func TestWithRunCluster(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/default-tags.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rapp := app.WithRunCluster("maintenance")
+	if rapp.Cluster != "maintenance" {
+		t.Errorf("unexpected cluster %s", rapp.Cluster)
+	}
+	if c := aws.ToString(rapp.DescribeServicesInput().Cluster); c != "default" {
+		t.Errorf("service should be described in the cluster of the config: %s", c)
+	}
+	if c := aws.ToString(rapp.DescribeTasksInput(&types.Task{TaskArn: aws.String("0001")}).Cluster); c != "maintenance" {
+		t.Errorf("task should be described in the run cluster: %s", c)
+	}
+	in, err := rapp.RunTaskInput(ctx, ecspresso.RunOption{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := aws.ToString(in.Cluster); c != "maintenance" {
+		t.Errorf("task should run in the run cluster: %s", c)
+	}
+	if in.NetworkConfiguration == nil {
+		t.Error("network configuration of the service definition should be used")
+	}
+	if app.Cluster != "default" {
+		t.Errorf("original app should not be modified: %s", app.Cluster)
+	}
+}
+
+func TestLaunchSettingsForRun(t *testing.T) {
+	sv := &ecspresso.Service{}
+	sv.LaunchType = types.LaunchTypeEc2
+
+	lt, strategy, err := ecspresso.LaunchSettingsForRun(sv, &ecspresso.RunOption{})
+	if err != nil || lt != types.LaunchTypeEc2 || strategy != nil {
+		t.Errorf("service definition should be used: %s %v %v", lt, strategy, err)
+	}
+
+	lt, strategy, err = ecspresso.LaunchSettingsForRun(sv, &ecspresso.RunOption{LaunchType: "fargate"})
+	if err != nil || lt != types.LaunchTypeFargate || strategy != nil {
+		t.Errorf("launch type should be overridden: %s %v %v", lt, strategy, err)
+	}
+
+	lt, strategy, err = ecspresso.LaunchSettingsForRun(sv, &ecspresso.RunOption{
+		CapacityProviderStrategy: `[{"capacityProvider":"FARGATE_SPOT","weight":3},{"capacityProvider":"FARGATE","weight":1,"base":1}]`,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lt != "" {
+		t.Errorf("launch type should be cleared with capacity provider strategy: %s", lt)
+	}
+	expected := []types.CapacityProviderStrategyItem{
+		{CapacityProvider: aws.String("FARGATE_SPOT"), Weight: 3},
+		{CapacityProvider: aws.String("FARGATE"), Weight: 1, Base: 1},
+	}
+	if diff := cmp.Diff(expected, strategy, cmpopts.IgnoreUnexported(types.CapacityProviderStrategyItem{})); diff != "" {
+		t.Error(diff)
+	}
+
+	for _, opt := range []ecspresso.RunOption{
+		{LaunchType: "SPOT"},
+		{CapacityProviderStrategy: `{"capacityProvider":"FARGATE_SPOT"}`},
+		{CapacityProviderStrategy: `[]`},
+		{CapacityProviderStrategy: `[{"weight":1}]`},
+	} {
+		if _, _, err := ecspresso.LaunchSettingsForRun(sv, &opt); err == nil {
+			t.Errorf("expected error for %#v", opt)
+		}
+	}
+}
+
+func TestNetworkConfigurationForRun(t *testing.T) {
+	sv := &ecspresso.Service{}
+	sv.NetworkConfiguration = &types.NetworkConfiguration{
+		AwsvpcConfiguration: &types.AwsVpcConfiguration{
+			Subnets:        []string{"subnet-a", "subnet-b"},
+			SecurityGroups: []string{"sg-app"},
+			AssignPublicIp: types.AssignPublicIpDisabled,
+		},
+	}
+	opts := cmpopts.IgnoreUnexported(types.AwsVpcConfiguration{})
+
+	nc, err := ecspresso.NetworkConfigurationForRun(sv, &ecspresso.RunOption{}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nc != sv.NetworkConfiguration {
+		t.Error("network configuration of the service definition should be used as is")
+	}
+
+	nc, err = ecspresso.NetworkConfigurationForRun(sv, &ecspresso.RunOption{
+		Subnets:        []string{"subnet-debug"},
+		AssignPublicIp: "ENABLED",
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &types.AwsVpcConfiguration{
+		Subnets:        []string{"subnet-debug"},
+		SecurityGroups: []string{"sg-app"},
+		AssignPublicIp: types.AssignPublicIpEnabled,
+	}
+	if diff := cmp.Diff(expected, nc.AwsvpcConfiguration, opts); diff != "" {
+		t.Error(diff)
+	}
+	if s := sv.NetworkConfiguration.AwsvpcConfiguration.Subnets; len(s) != 2 {
+		t.Errorf("service definition should not be modified: %v", s)
+	}
+
+	// without the network configuration in the service definition
+	nc, err = ecspresso.NetworkConfigurationForRun(&ecspresso.Service{}, &ecspresso.RunOption{
+		Subnets:        []string{"subnet-debug"},
+		SecurityGroups: []string{"sg-debug"},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = &types.AwsVpcConfiguration{
+		Subnets:        []string{"subnet-debug"},
+		SecurityGroups: []string{"sg-debug"},
+	}
+	if diff := cmp.Diff(expected, nc.AwsvpcConfiguration, opts); diff != "" {
+		t.Error(diff)
+	}
+
+	if _, err := ecspresso.NetworkConfigurationForRun(&ecspresso.Service{}, &ecspresso.RunOption{SecurityGroups: []string{"sg-debug"}}, true); err == nil {
+		t.Error("subnets should be required for Fargate")
+	}
+	if _, err := ecspresso.NetworkConfigurationForRun(&ecspresso.Service{}, &ecspresso.RunOption{}, false); err != nil {
+		t.Errorf("network configuration is not required for EC2: %s", err)
+	}
+}
+
+func TestStartedByForRun(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/default-tags.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CI_JOB_ID", "123")
+	for name, c := range map[string]struct {
+		opt      ecspresso.RunOption
+		expected string
+	}{
+		"default":  {opt: ecspresso.RunOption{}, expected: "ecspresso-run"},
+		"explicit": {opt: ecspresso.RunOption{StartedBy: aws.String("nightly-batch")}, expected: "nightly-batch"},
+		"invalid":  {opt: ecspresso.RunOption{StartedBy: aws.String("ci job #1")}, expected: "ci-job-1"},
+		"empty":    {opt: ecspresso.RunOption{StartedBy: aws.String(" ")}, expected: "ecspresso-run"},
+		"long":     {opt: ecspresso.RunOption{StartedBy: aws.String(strings.Repeat("x", 200))}, expected: strings.Repeat("x", 128)},
+		"template": {opt: ecspresso.RunOption{StartedByTemplate: `ci/{{ env "CI_JOB_ID" }}`}, expected: "ci/123"},
+	} {
+		got, err := app.StartedByForRun(c.opt)
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", name, err)
+			continue
+		}
+		if got != c.expected {
+			t.Errorf("%s: expected %s, got %s", name, c.expected, got)
+		}
+	}
+}
+
+func TestRunTaskInputPlatformVersion(t *testing.T) {
+	app := newRunTestApp(t)
+	in, err := app.RunTaskInput(context.TODO(), ecspresso.RunOption{PlatformVersion: "1.4.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := aws.ToString(in.PlatformVersion); v != "1.4.0" {
+		t.Errorf("unexpected platform version %s", v)
+	}
+}
+
+func TestRunTaskInputSwitchFargateAndEC2(t *testing.T) {
+	ctx := context.Background()
+	for _, tc := range []struct {
+		config          string
+		opt             ecspresso.RunOption
+		placement       bool
+		platformVersion string
+	}{
+		{config: "tests/run-ec2-placement.yml", opt: ecspresso.RunOption{}, placement: true},
+		{config: "tests/run-ec2-placement.yml", opt: ecspresso.RunOption{CapacityProviderStrategy: `[{"capacityProvider":"FARGATE_SPOT","weight":1}]`}},
+		{config: "tests/run-ec2-placement.yml", opt: ecspresso.RunOption{LaunchType: "FARGATE", PlatformVersion: "LATEST"}, platformVersion: "LATEST"},
+		{config: "tests/run-fargate.yml", opt: ecspresso.RunOption{}, platformVersion: "1.4.0"},
+		{config: "tests/run-fargate.yml", opt: ecspresso.RunOption{CapacityProviderStrategy: `[{"capacityProvider":"FARGATE_SPOT","weight":1}]`}, platformVersion: "1.4.0"},
+		{config: "tests/run-fargate.yml", opt: ecspresso.RunOption{LaunchType: "EC2"}},
+	} {
+		app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: tc.config})
+		if err != nil {
+			t.Fatal(err)
+		}
+		in, err := app.RunTaskInput(ctx, tc.opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if placement := len(in.PlacementConstraints) > 0 && len(in.PlacementStrategy) > 0; placement != tc.placement {
+			t.Errorf("%s %s%s: unexpected placement %#v %#v", tc.config, tc.opt.LaunchType, tc.opt.CapacityProviderStrategy, in.PlacementConstraints, in.PlacementStrategy)
+		}
+		if v := aws.ToString(in.PlatformVersion); v != tc.platformVersion {
+			t.Errorf("%s %s%s: unexpected platform version %s", tc.config, tc.opt.LaunchType, tc.opt.CapacityProviderStrategy, v)
+		}
+	}
+}
+
+func TestRunTaskInputPropagateServiceTags(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	in, err := app.RunTaskInput(ctx, ecspresso.RunOption{PropagateTags: "SERVICE"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := ecspresso.TagsToString(in.Tags); !strings.Contains(s, "Team=service-team") {
+		t.Errorf("tags should be propagated from the service: %s", s)
+	}
+
+	// the service is not defined
+	app, err = ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/run-without-sv.yaml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = app.RunTaskInput(ctx, ecspresso.RunOption{PropagateTags: "SERVICE"})
+	var nf ecspresso.ErrNotFound
+	if !errors.As(err, &nf) || !strings.Contains(err.Error(), "cannot propagate tags from service") {
+		t.Errorf("expected not found error, got %v", err)
+	}
+	in, err = app.RunTaskInput(ctx, ecspresso.RunOption{PropagateTags: "SERVICE", Tags: "Env=dev", IgnoreMissingService: true})
+	if err != nil {
+		t.Fatalf("missing service should be ignored: %s", err)
+	}
+	if s := ecspresso.TagsToString(in.Tags); s != "Env=dev" {
+		t.Errorf("only --tags should be set: %s", s)
+	}
+}
+
+func TestRunTaskInputDeduplicateTags(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	for _, propagate := range []string{"", "SERVICE"} {
+		// the service has Env=prod and Team=service-team in the mock
+		in, err := app.RunTaskInput(ctx, ecspresso.RunOption{
+			PropagateTags: propagate,
+			Tags:          "Env=staging,Owner=me,Env=dev",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := map[string]int{}
+		for _, tag := range in.Tags {
+			keys[aws.ToString(tag.Key)]++
+			if aws.ToString(tag.Key) == "Env" && aws.ToString(tag.Value) != "dev" {
+				t.Errorf("propagate=%s: explicit tag should win: Env=%s", propagate, aws.ToString(tag.Value))
+			}
+		}
+		for k, n := range keys {
+			if n > 1 {
+				t.Errorf("propagate=%s: tag %s is duplicated %d times: %s", propagate, k, n, ecspresso.TagsToString(in.Tags))
+			}
+		}
+		if keys["Env"] != 1 || keys["Owner"] != 1 {
+			t.Errorf("propagate=%s: unexpected tags %s", propagate, ecspresso.TagsToString(in.Tags))
+		}
+	}
+}
+
+func TestIsRetryableRunTaskFailure(t *testing.T) {
+	for reason, expected := range map[string]bool{
+		"RESOURCE:MEMORY": true,
+		"RESOURCE:CPU":    true,
+		"AGENT":           true,
+		"Capacity is unavailable at this time. Please try again later or in a different availability zone": true,
+		"ATTRIBUTE":        false,
+		"MemberOf":         false,
+		"InvalidParameter": false,
+	} {
+		if got := ecspresso.IsRetryableRunTaskFailure(reason); got != expected {
+			t.Errorf("%s expected %t, got %t", reason, expected, got)
+		}
+	}
+}
+
+func TestSubmitRunTaskWithRetry(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	for _, maxRetries := range []int{0, 2} {
+		runTaskCalls = 0
+		err := app.SubmitRunTaskWithRetry(ctx, maxRetries)
+		var pf *ecspresso.ErrPlacementFailure
+		if !errors.As(err, &pf) {
+			t.Errorf("max-retries=%d: expected placement failure, got %v", maxRetries, err)
+		}
+		if runTaskCalls != maxRetries+1 {
+			t.Errorf("max-retries=%d: expected %d calls, got %d", maxRetries, maxRetries+1, runTaskCalls)
+		}
+	}
+}
+
+func TestAggregateRunResults(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	arn := func(id string) string {
+		return "arn:aws:ecs:ap-northeast-1:123456789012:task/default/" + id
+	}
+
+	// 0001 and 0002 exited with 0, 0003 exited with 1
+	if err := app.AggregateRunResults(ctx, []string{arn("0001"), arn("0002")}, "app", nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	err := app.AggregateRunResults(ctx, []string{arn("0001"), arn("0002"), arn("0003")}, "app", nil)
+	if err == nil || !strings.HasPrefix(err.Error(), "1 of 3 tasks failed: ") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	first := errors.New("first task failed")
+	err = app.AggregateRunResults(ctx, []string{arn("0003"), arn("0003")}, "app", first)
+	if !errors.Is(err, first) || !strings.HasPrefix(err.Error(), "2 of 2 tasks failed: ") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestLogStoppedDetails(t *testing.T) {
+	for name, c := range map[string]struct {
+		result   *ecspresso.RunResult
+		expected string
+	}{
+		"other container failed": {
+			result: &ecspresso.RunResult{
+				TaskArn:       "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001",
+				StoppedReason: "Essential container in task exited",
+				StopCode:      "EssentialContainerExited",
+				Severity:      ecspresso.SeveritySuccess,
+				Containers: []ecspresso.ContainerResult{
+					{Name: "app", LastStatus: "STOPPED", ExitCode: aws.Int32(0)},
+					{Name: "sidecar", LastStatus: "STOPPED", ExitCode: aws.Int32(137), Reason: "OutOfMemoryError: Container killed due to memory usage"},
+				},
+			},
+			expected: "Task 0001 stopped. stop code: EssentialContainerExited, reason: Essential container in task exited\n" +
+				"container: app, status: STOPPED, exit code: 0\n" +
+				"[WARNING] container: sidecar, status: STOPPED, exit code: 137, reason: OutOfMemoryError: Container killed due to memory usage\n",
+		},
+		"failed to pull": {
+			result: &ecspresso.RunResult{
+				TaskArn:       "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0002",
+				StoppedReason: "CannotPullContainerError: pull image manifest has been retried 5 time(s)",
+				StopCode:      "TaskFailedToStart",
+				Severity:      ecspresso.SeverityFailure,
+				Containers: []ecspresso.ContainerResult{
+					{Name: "app", LastStatus: "STOPPED"},
+				},
+			},
+			expected: "Task 0002 stopped. stop code: TaskFailedToStart, reason: CannotPullContainerError: pull image manifest has been retried 5 time(s)\n" +
+				"container: app, status: STOPPED, exit code: -\n",
+		},
+		"success": {
+			result: &ecspresso.RunResult{
+				TaskArn:       "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0003",
+				StoppedReason: "Essential container in task exited",
+				Severity:      ecspresso.SeveritySuccess,
+				Containers: []ecspresso.ContainerResult{
+					{Name: "app", LastStatus: "STOPPED", ExitCode: aws.Int32(0)},
+				},
+			},
+			expected: "",
+		},
+	} {
+		b := new(bytes.Buffer)
+		logger := ecspresso.NewLogger()
+		logger.SetFlags(0)
+		logger.SetOutput(b)
+		app := &ecspresso.App{}
+		app.SetLogger(logger)
+		app.LogStoppedDetails(c.result)
+		if diff := cmp.Diff(c.expected, strings.ReplaceAll(b.String(), "/ ", "")); diff != "" {
+			t.Errorf("%s: %s", name, diff)
+		}
+	}
+}
+
+func TestDryRunTaskInput(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	tdArn := "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:40"
+	in, err := app.DryRunTaskInput(ctx, tdArn, ecspresso.RunOption{
+		RunID: aws.String("dry-run-id"),
+		Tags:  "Owner=me",
+		Count: 2,
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aws.ToString(in.TaskDefinition) != tdArn {
+		t.Errorf("unexpected task definition %s", aws.ToString(in.TaskDefinition))
+	}
+	if aws.ToInt32(in.Count) != 2 {
+		t.Errorf("unexpected count %d", aws.ToInt32(in.Count))
+	}
+	if s := ecspresso.TagsToString(in.Tags); !strings.Contains(s, "Owner=me") || !strings.Contains(s, "ecspresso:run-id=dry-run-id") {
+		t.Errorf("unexpected tags %s", s)
+	}
+	if len(in.Overrides.ContainerOverrides) != 1 {
+		t.Fatalf("unexpected container overrides %#v", in.Overrides.ContainerOverrides)
+	}
+	co := in.Overrides.ContainerOverrides[0]
+	if aws.ToString(co.Name) != "app" || len(co.Environment) != 1 || aws.ToString(co.Environment[0].Value) != "dry-run-id" {
+		t.Errorf("unexpected container override %#v", co)
+	}
+}
+
+func TestFindLatestTaskDefinitionArn(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	arn, err := app.FindLatestTaskDefinitionArn(ctx, "katsubushi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if arn != "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:45" {
+		t.Errorf("unexpected arn %s", arn)
+	}
+
+	_, err = app.FindLatestTaskDefinitionArn(ctx, "deregistered")
+	var nf ecspresso.ErrNotFound
+	if !errors.As(err, &nf) || !strings.Contains(err.Error(), "are inactive") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestFamilyOfTaskDefinitionArn(t *testing.T) {
+	for s, expected := range map[string]string{
+		"arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:45":       "katsubushi",
+		"arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi-batch:50": "katsubushi-batch",
+		"katsubushi:3": "katsubushi",
+	} {
+		if got := ecspresso.FamilyOfTaskDefinitionArn(s); got != expected {
+			t.Errorf("%s expected %s, got %s", s, expected, got)
+		}
+	}
+}
+
+func TestTaskDefinitionHook(t *testing.T) {
+	ctx := context.TODO()
+	opt := ecspresso.RunOption{Revision: aws.Int64(0), DryRun: true}
+
+	app := newRunTestApp(t, ecspresso.WithTaskDefinitionHook(func(td *ecspresso.TaskDefinitionInput) error {
+		td.Family = aws.String("hooked")
+		return nil
+	}))
+	if arn, err := app.TaskDefinitionArnForRun(ctx, opt); err != nil {
+		t.Fatal(err)
+	} else if arn != "family hooked will be registered" {
+		t.Errorf("unexpected result %s", arn)
+	}
+
+	hookErr := errors.New("refused")
+	app = newRunTestApp(t, ecspresso.WithTaskDefinitionHook(func(td *ecspresso.TaskDefinitionInput) error {
+		return hookErr
+	}))
+	if _, err := app.TaskDefinitionArnForRun(ctx, opt); !errors.Is(err, hookErr) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestTaskDefinitionArnForRunReuse(t *testing.T) {
+	ctx := context.TODO()
+	newApp := func(container string) *ecspresso.App {
+		return newRunTestApp(t, ecspresso.WithTaskDefinitionHook(func(td *ecspresso.TaskDefinitionInput) error {
+			*td = ecspresso.TaskDefinitionInput{
+				Family:               aws.String("katsubushi"),
+				ContainerDefinitions: []types.ContainerDefinition{{Name: aws.String(container)}},
+			}
+			return nil
+		}))
+	}
+
+	identical := newApp("app")
+	for _, opt := range []ecspresso.RunOption{
+		{Revision: aws.Int64(0), ReuseTaskDefinition: true},
+		{Revision: aws.Int64(0), NoRegister: true},
+	} {
+		if arn, err := identical.TaskDefinitionArnForRun(ctx, opt); err != nil {
+			t.Fatal(err)
+		} else if arn != "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:45" {
+			t.Errorf("unexpected arn %s", arn)
+		}
+	}
+
+	different := newApp("web")
+	opt := ecspresso.RunOption{Revision: aws.Int64(0), ReuseTaskDefinition: true, DryRun: true}
+	if arn, err := different.TaskDefinitionArnForRun(ctx, opt); err != nil {
+		t.Fatal(err)
+	} else if arn != "family katsubushi will be registered" {
+		t.Errorf("unexpected result %s", arn)
+	}
+	opt = ecspresso.RunOption{Revision: aws.Int64(0), NoRegister: true, DryRun: true}
+	if _, err := different.TaskDefinitionArnForRun(ctx, opt); err == nil {
+		t.Error("expected an error when no identical revision is found")
+	}
+}
+
+func TestRunTaskInputServiceTagsOfOtherFamily(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	// the service runs katsubushi:39 and has Team=service-team in the mock
+	for _, tc := range []struct {
+		tdArn      string
+		force      bool
+		propagated bool
+	}{
+		{tdArn: "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:40", propagated: true},
+		{tdArn: "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/batch:1", propagated: false},
+		{tdArn: "batch:1", force: true, propagated: true},
+	} {
+		in, err := app.RunTaskInputWithTaskDefinition(ctx, tc.tdArn, ecspresso.RunOption{
+			PropagateTags:             "SERVICE",
+			ForcePropagateServiceTags: tc.force,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := ecspresso.TagsToString(in.Tags)
+		if propagated := strings.Contains(s, "Team=service-team"); propagated != tc.propagated {
+			t.Errorf("%s force=%t: expected propagated=%t, got tags %s", tc.tdArn, tc.force, tc.propagated, s)
+		}
+	}
+}
+
+func TestStopTasksOnCancel(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	tasks := []string{
+		"arn:aws:ecs:ap-northeast-1:123456789012:task/default2/0001",
+		"arn:aws:ecs:ap-northeast-1:123456789012:task/default2/0002",
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	timedOut, cancel := context.WithTimeout(ctx, 0)
+	defer cancel()
+	for name, tc := range map[string]struct {
+		ctx   context.Context
+		calls int
+	}{
+		"cancelled": {ctx: cancelled, calls: 2},
+		"timed out": {ctx: timedOut, calls: 0},
+		"running":   {ctx: ctx, calls: 0},
+	} {
+		stopTaskCalls = 0
+		app.StopTasksOnCancel(tc.ctx, tasks...)
+		if stopTaskCalls != tc.calls {
+			t.Errorf("%s: expected %d calls of StopTask, got %d", name, tc.calls, stopTaskCalls)
+		}
+	}
+}
+
+func TestRunTaskInputTagsFile(t *testing.T) {
+	ctx := context.TODO()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/default-tags.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	in, err := app.RunTaskInput(ctx, ecspresso.RunOption{
+		TagsFile: "tests/tags.yml",
+		Tags:     "Owner=me",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := ecspresso.TagsToString(in.Tags)
+	if !strings.Contains(s, "Team=file-team") || !strings.Contains(s, "Owner=me") || strings.Contains(s, "file-owner") {
+		t.Errorf("unexpected tags %s", s)
+	}
+
+	if _, err := app.RunTaskInput(ctx, ecspresso.RunOption{TagsFile: "tests/missing-tags.yml"}); err == nil || !strings.Contains(err.Error(), "tests/missing-tags.yml") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidateTags(t *testing.T) {
+	valid := []types.Tag{
+		{Key: aws.String("Team"), Value: aws.String("")},
+		{Key: aws.String(strings.Repeat("k", 128)), Value: aws.String(strings.Repeat("v", 256))},
+	}
+	if err := ecspresso.ValidateTags(valid); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	for _, tag := range []types.Tag{
+		{Key: aws.String(""), Value: aws.String("v")},
+		{Key: aws.String(strings.Repeat("k", 129)), Value: aws.String("v")},
+		{Key: aws.String("AWS:foo"), Value: aws.String("v")},
+		{Key: aws.String("Team"), Value: aws.String(strings.Repeat("v", 257))},
+	} {
+		if err := ecspresso.ValidateTags([]types.Tag{tag}); err == nil {
+			t.Errorf("%s=%s should be invalid", aws.ToString(tag.Key), aws.ToString(tag.Value))
+		}
+	}
+}
+
+func TestWaitTask(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	// all the tasks have stopped in the mock
+	tasks := []types.Task{
+		{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001")},
+		{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0002")},
+	}
+	if err := app.WaitTask(ctx, tasks, false); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := app.WaitTask(ctx, nil, false); err == nil {
+		t.Error("expected error for no tasks")
+	}
+}
+
+func TestWaitRunTaskNoLogTail(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	b := new(bytes.Buffer)
+	logger := ecspresso.NewLogger()
+	logger.SetOutput(ecspresso.NewLogFilter(b, "INFO"))
+	app.SetLogger(logger)
+
+	task := &types.Task{TaskArn: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001")}
+	container := &types.ContainerDefinition{
+		Name: aws.String("app"),
+		LogConfiguration: &types.LogConfiguration{
+			LogDriver: types.LogDriverAwslogs,
+			Options: map[string]string{
+				"awslogs-group":         "/ecs/app",
+				"awslogs-region":        "ap-northeast-1",
+				"awslogs-stream-prefix": "app",
+			},
+		},
+	}
+	opt := ecspresso.RunOption{WaitUntil: "stopped", NoLogTail: true}
+	if err := app.WaitRunTaskWithOption(ctx, task, container, opt); err != nil {
+		t.Fatal(err)
+	}
+	logs := b.String()
+	if !strings.Contains(logs, "Log tailing is disabled") {
+		t.Errorf("unexpected logs: %s", logs)
+	}
+	if strings.Contains(logs, "Watching container") {
+		t.Errorf("logs should not be tailed: %s", logs)
+	}
+}
+
+func TestReportTaskProgress(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	b := new(bytes.Buffer)
+	logger := ecspresso.NewLogger()
+	logger.SetOutput(ecspresso.NewLogFilter(b, "INFO"))
+	app.SetLogger(logger)
+
+	ctx, cancel := context.WithTimeout(ctx, 250*time.Millisecond)
+	defer cancel()
+	app.ReportTaskProgress(ctx, "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001", time.Now().Add(-time.Minute), 100*time.Millisecond)
+	if s := b.String(); !strings.Contains(s, "Task ID 0001 is STOPPED (elapsed 1m0s)") {
+		t.Errorf("unexpected logs: %s", s)
+	}
+}
+
+func TestFindLastGoodTaskDefinitionArn(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	// 0002 (katsubushi:40) is the latest task exited with 0 in the mock
+	for _, name := range []string{"", "app", "0"} {
+		arn, err := app.FindLastGoodTaskDefinitionArn(ctx, "katsubushi", ecspresso.RunOption{WatchContainer: name})
+		if err != nil {
+			t.Errorf("watch container %q: unexpected error %s", name, err)
+			continue
+		}
+		if arn != "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:40" {
+			t.Errorf("watch container %q: unexpected arn %s", name, arn)
+		}
+	}
+	if _, err := app.FindLastGoodTaskDefinitionArn(ctx, "katsubushi", ecspresso.RunOption{WatchContainer: "1"}); err == nil {
+		t.Error("expected error for the index out of range")
+	}
+}
+
+func TestFindLastGoodTaskDefinitionArnStartedBy(t *testing.T) {
+	ctx := context.TODO()
+	t.Setenv("ECSPRESSO_TEST_JOB", "42")
+	app := newRunTestApp(t)
+	// 0001 (katsubushi:38) is started by batch, 0002 (katsubushi:40) by ecspresso-run in the mock
+	for _, tc := range []struct {
+		opt ecspresso.RunOption
+		arn string
+	}{
+		{opt: ecspresso.RunOption{}, arn: "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:40"},
+		{opt: ecspresso.RunOption{StartedBy: aws.String("batch")}, arn: "arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:38"},
+		{opt: ecspresso.RunOption{StartedByTemplate: "job-{{ must_env `ECSPRESSO_TEST_JOB` }}"}},
+	} {
+		arn, err := app.FindLastGoodTaskDefinitionArn(ctx, "katsubushi", tc.opt)
+		if tc.arn == "" {
+			if err == nil {
+				t.Errorf("expected error for no tasks started by job-42, got %s", arn)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if arn != tc.arn {
+			t.Errorf("expected %s, got %s", tc.arn, arn)
+		}
+	}
+}
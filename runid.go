@@ -0,0 +1,54 @@
+package ecspresso
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/uuid"
+)
+
+const (
+	runIDTagKey  = "ecspresso:run-id"
+	runIDEnvName = "ECSPRESSO_RUN_ID"
+)
+
+// runIDRegex is the allowed characters of a tag value in ECS.
+var runIDRegex = regexp.MustCompile(`^[\p{L}\p{Z}\p{N}_.:/=+\-@]{1,256}$`)
+
+func newRunID() string {
+	return uuid.NewString()
+}
+
+func validateRunID(id string) error {
+	if !runIDRegex.MatchString(id) {
+		return fmt.Errorf("invalid run ID %q. it must be 1-256 characters of letters, numbers, spaces and _.:/=+-@", id)
+	}
+	return nil
+}
+
+// withRunID returns a copy of the App which prefixes the logs of ecspresso with the run ID.
+// The logs of the task are not prefixed.
+func (d *App) withRunID(id string) *App {
+	nd := *d
+	// no brackets not to be taken as the log level by the level filter
+	nd.runIDPrefix = "run-id=" + id + " "
+	return &nd
+}
+
+// setRunIDEnv sets the run ID to the environment variable of the container in the overrides.
+func setRunIDEnv(ov *types.TaskOverride, container, id string) {
+	mergeContainerOverride(containerOverrideOf(ov, container), types.ContainerOverride{
+		Environment: []types.KeyValuePair{
+			{Name: aws.String(runIDEnvName), Value: aws.String(id)},
+		},
+	})
+}
+
+func runIDTags(id string) []types.Tag {
+	if id == "" {
+		return nil
+	}
+	return []types.Tag{{Key: aws.String(runIDTagKey), Value: aws.String(id)}}
+}
@@ -0,0 +1,83 @@
+package ecspresso_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestValidateRunID(t *testing.T) {
+	for _, id := range []string{"3f1b6c1e-8a1d-4c6e-9d55-6f1f0b1a2c3d", "build-123", "ci:job/42@main"} {
+		if err := ecspresso.ValidateRunID(id); err != nil {
+			t.Errorf("%q should be valid: %s", id, err)
+		}
+	}
+	for _, id := range []string{"", "a,b", "a\nb", strings.Repeat("x", 257)} {
+		if err := ecspresso.ValidateRunID(id); err == nil {
+			t.Errorf("%q should be invalid", id)
+		}
+	}
+}
+
+func TestSetRunIDEnv(t *testing.T) {
+	ov := types.TaskOverride{
+		ContainerOverrides: []types.ContainerOverride{
+			{
+				Name: aws.String("app"),
+				Environment: []types.KeyValuePair{
+					{Name: aws.String("FOO"), Value: aws.String("foo")},
+					{Name: aws.String("ECSPRESSO_RUN_ID"), Value: aws.String("old")},
+				},
+			},
+		},
+	}
+	ecspresso.SetRunIDEnv(&ov, "app", "run-1")
+	ecspresso.SetRunIDEnv(&ov, "worker", "run-1")
+
+	envs := ov.ContainerOverrides[0].Environment
+	if len(envs) != 2 {
+		t.Fatalf("unexpected environment %#v", envs)
+	}
+	if aws.ToString(envs[1].Value) != "run-1" {
+		t.Errorf("ECSPRESSO_RUN_ID should be replaced: %s", aws.ToString(envs[1].Value))
+	}
+	if len(ov.ContainerOverrides) != 2 || aws.ToString(ov.ContainerOverrides[1].Name) != "worker" {
+		t.Fatalf("unexpected container overrides %#v", ov.ContainerOverrides)
+	}
+	if e := ov.ContainerOverrides[1].Environment; len(e) != 1 || aws.ToString(e[0].Name) != "ECSPRESSO_RUN_ID" {
+		t.Errorf("unexpected environment %#v", e)
+	}
+}
+
+func TestRunIDLogPrefix(t *testing.T) {
+	b := new(bytes.Buffer)
+	logger := ecspresso.NewLogger()
+	logger.SetOutput(ecspresso.NewLogFilter(b, "INFO"))
+	app := &ecspresso.App{}
+	app.SetLogger(logger)
+	app = app.WithRunID("run-1")
+
+	app.Log("[DEBUG] should be filtered")
+	app.Log("should be logged")
+	if s := b.String(); strings.Contains(s, "filtered") || !strings.Contains(s, "run-id=run-1 / should be logged") {
+		t.Errorf("unexpected logs: %s", s)
+	}
+}
+
+func TestRunIDLogPrefixNotForTaskLogs(t *testing.T) {
+	b := new(bytes.Buffer)
+	app := newRunTestApp(t).WithRunID("run-1")
+	app.SetLogWriter(b)
+	if _, err := app.GetLogEvents(context.TODO(), "group", "stream", time.Now(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if s := b.String(); strings.Contains(s, "run-id=") || !strings.Contains(s, "hello") {
+		t.Errorf("unexpected logs of the task: %s", s)
+	}
+}
@@ -0,0 +1,66 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/shogo82148/go-retry"
+)
+
+// retryableRunTaskFailures are the substrings of the reasons of RunTask failures which may succeed on retry.
+// The other failures (e.g. ATTRIBUTE, MemberOf) do not change by retrying.
+var retryableRunTaskFailures = []string{
+	"Capacity is unavailable",
+	"RESOURCE:",
+	"AGENT",
+}
+
+// runTaskRetryMinDelay and runTaskRetryMaxDelay are the range of the backoff for --max-retries.
+var (
+	runTaskRetryMinDelay = 5 * time.Second
+	runTaskRetryMaxDelay = time.Minute
+)
+
+func isRetryableRunTaskFailure(reason string) bool {
+	for _, s := range retryableRunTaskFailures {
+		if strings.Contains(reason, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// submitRunTaskWithRetry calls RunTask and retries with backoff up to maxRetries times on a retryable failure.
+func (d *App) submitRunTaskWithRetry(ctx context.Context, in *ecs.RunTaskInput, rate float64, maxRetries int) ([]types.Task, error) {
+	if maxRetries <= 0 {
+		return d.submitRunTask(ctx, in, rate)
+	}
+	policy := retry.Policy{
+		MinDelay: runTaskRetryMinDelay,
+		MaxDelay: runTaskRetryMaxDelay,
+		MaxCount: maxRetries + 1,
+	}
+	var tasks []types.Task
+	attempt := 0
+	err := policy.Do(ctx, func() error {
+		attempt++
+		var err error
+		tasks, err = d.submitRunTask(ctx, in, rate)
+		if err == nil {
+			return nil
+		}
+		var pf *ErrPlacementFailure
+		if !errors.As(err, &pf) || !isRetryableRunTaskFailure(pf.Reason) {
+			return retry.MarkPermanent(err)
+		}
+		if attempt <= maxRetries {
+			d.Log("[WARNING] RunTask failed by a retryable failure: %s %s. retrying with backoff (%d/%d)", pf.Reason, pf.Detail, attempt, maxRetries)
+		}
+		return err
+	})
+	return tasks, err
+}
@@ -0,0 +1,133 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/scheduler"
+	schedulerTypes "github.com/aws/aws-sdk-go-v2/service/scheduler/types"
+)
+
+const maxScheduleNameLength = 64
+
+var invalidScheduleNameChars = regexp.MustCompile(`[^0-9a-zA-Z_.-]`)
+
+// scheduleRunTask creates a one-time EventBridge Scheduler schedule which runs the task at the time.
+// The schedule is deleted by the scheduler after completion.
+func (d *App) scheduleRunTask(ctx context.Context, in *ecs.RunTaskInput, at time.Time, roleArn string) (string, error) {
+	if !at.After(time.Now()) {
+		return "", fmt.Errorf("the scheduled time %s is in the past", at.Format(time.RFC3339))
+	}
+	out, err := d.ecs.DescribeClusters(ctx, &ecs.DescribeClustersInput{
+		Clusters: []string{d.Cluster},
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to describe cluster %s: %w", d.Cluster, err)
+	}
+	if len(out.Clusters) == 0 {
+		return "", ErrNotFound(fmt.Sprintf("cluster %s is not found", d.Cluster))
+	}
+	clusterArn := aws.ToString(out.Clusters[0].ClusterArn)
+
+	input, err := MarshalJSONForAPI(in.Overrides)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal overrides: %w", err)
+	}
+	name := scheduleName(aws.ToString(in.TaskDefinition), at)
+	schedIn := &scheduler.CreateScheduleInput{
+		Name:                       aws.String(name),
+		Description:                aws.String(fmt.Sprintf("one-time task scheduled by ecspresso %s", Version)),
+		ScheduleExpression:         aws.String("at(" + at.UTC().Format("2006-01-02T15:04:05") + ")"),
+		ScheduleExpressionTimezone: aws.String("UTC"),
+		FlexibleTimeWindow:         &schedulerTypes.FlexibleTimeWindow{Mode: schedulerTypes.FlexibleTimeWindowModeOff},
+		ActionAfterCompletion:      schedulerTypes.ActionAfterCompletionDelete,
+		Target: &schedulerTypes.Target{
+			Arn:           aws.String(clusterArn),
+			RoleArn:       aws.String(roleArn),
+			Input:         aws.String(string(input)),
+			EcsParameters: toSchedulerEcsParameters(in),
+		},
+	}
+	d.Log("[DEBUG] create schedule input")
+	d.LogJSON(schedIn)
+
+	client := scheduler.NewFromConfig(d.config.awsv2Config)
+	res, err := client.CreateSchedule(ctx, schedIn)
+	if err != nil {
+		return "", fmt.Errorf("failed to create schedule: %w", err)
+	}
+	return aws.ToString(res.ScheduleArn), nil
+}
+
+func scheduleName(tdArn string, at time.Time) string {
+	name := fmt.Sprintf("ecspresso-%s-%d", arnToName(tdArn), at.Unix())
+	name = invalidScheduleNameChars.ReplaceAllString(name, "-")
+	if len(name) > maxScheduleNameLength {
+		name = name[len(name)-maxScheduleNameLength:]
+	}
+	return name
+}
+
+// toSchedulerEcsParameters converts the RunTask input to the ECS parameters of EventBridge Scheduler.
+func toSchedulerEcsParameters(in *ecs.RunTaskInput) *schedulerTypes.EcsParameters {
+	p := &schedulerTypes.EcsParameters{
+		TaskDefinitionArn:    in.TaskDefinition,
+		TaskCount:            in.Count,
+		LaunchType:           schedulerTypes.LaunchType(in.LaunchType),
+		PlatformVersion:      in.PlatformVersion,
+		PropagateTags:        schedulerTypes.PropagateTags(in.PropagateTags),
+		EnableECSManagedTags: aws.Bool(in.EnableECSManagedTags),
+		EnableExecuteCommand: aws.Bool(in.EnableExecuteCommand),
+		Group:                in.Group,
+		ReferenceId:          in.ReferenceId,
+	}
+	if nc := in.NetworkConfiguration; nc != nil && nc.AwsvpcConfiguration != nil {
+		p.NetworkConfiguration = &schedulerTypes.NetworkConfiguration{
+			AwsvpcConfiguration: &schedulerTypes.AwsVpcConfiguration{
+				Subnets:        nc.AwsvpcConfiguration.Subnets,
+				SecurityGroups: nc.AwsvpcConfiguration.SecurityGroups,
+				AssignPublicIp: schedulerTypes.AssignPublicIp(nc.AwsvpcConfiguration.AssignPublicIp),
+			},
+		}
+	}
+	for _, s := range in.CapacityProviderStrategy {
+		p.CapacityProviderStrategy = append(p.CapacityProviderStrategy, schedulerTypes.CapacityProviderStrategyItem{
+			CapacityProvider: s.CapacityProvider,
+			Base:             s.Base,
+			Weight:           s.Weight,
+		})
+	}
+	for _, c := range in.PlacementConstraints {
+		p.PlacementConstraints = append(p.PlacementConstraints, schedulerTypes.PlacementConstraint{
+			Type:       schedulerTypes.PlacementConstraintType(c.Type),
+			Expression: c.Expression,
+		})
+	}
+	for _, s := range in.PlacementStrategy {
+		p.PlacementStrategy = append(p.PlacementStrategy, schedulerTypes.PlacementStrategy{
+			Type:  schedulerTypes.PlacementStrategyType(s.Type),
+			Field: s.Field,
+		})
+	}
+	p.Tags = toSchedulerTags(in.Tags)
+	return p
+}
+
+func toSchedulerTags(tags []types.Tag) []map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	res := make([]map[string]string, 0, len(tags))
+	for _, t := range tags {
+		res = append(res, map[string]string{
+			"key":   aws.ToString(t.Key),
+			"value": aws.ToString(t.Value),
+		})
+	}
+	return res
+}
@@ -0,0 +1,48 @@
+package ecspresso_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestScheduleName(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	name := ecspresso.ScheduleName("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:39", at)
+	if name != "ecspresso-katsubushi-39-1704164645" {
+		t.Errorf("unexpected name %s", name)
+	}
+	long := ecspresso.ScheduleName("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/a-very-long-family-name-of-the-task-definition:1", at)
+	if len(long) != 64 {
+		t.Errorf("name must be truncated to 64 characters: %s", long)
+	}
+}
+
+func TestToSchedulerEcsParameters(t *testing.T) {
+	in := &ecs.RunTaskInput{
+		TaskDefinition: aws.String("arn:aws:ecs:ap-northeast-1:123456789012:task-definition/katsubushi:39"),
+		Count:          aws.Int32(1),
+		LaunchType:     types.LaunchTypeFargate,
+		NetworkConfiguration: &types.NetworkConfiguration{
+			AwsvpcConfiguration: &types.AwsVpcConfiguration{
+				Subnets:        []string{"subnet-1"},
+				AssignPublicIp: types.AssignPublicIpEnabled,
+			},
+		},
+		Tags: []types.Tag{{Key: aws.String("env"), Value: aws.String("dev")}},
+	}
+	p := ecspresso.ToSchedulerEcsParameters(in)
+	if aws.ToString(p.TaskDefinitionArn) != aws.ToString(in.TaskDefinition) ||
+		string(p.LaunchType) != "FARGATE" ||
+		p.NetworkConfiguration.AwsvpcConfiguration.Subnets[0] != "subnet-1" ||
+		string(p.NetworkConfiguration.AwsvpcConfiguration.AssignPublicIp) != "ENABLED" {
+		t.Errorf("unexpected parameters %#v", p)
+	}
+	if len(p.Tags) != 1 || p.Tags[0]["key"] != "env" || p.Tags[0]["value"] != "dev" {
+		t.Errorf("unexpected tags %#v", p.Tags)
+	}
+}
@@ -0,0 +1,61 @@
+package ecspresso
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+)
+
+// validateJSONSchema validates the JSON src against the JSON Schema file.
+// The returned error lists the violating paths of src.
+func validateJSONSchema(schemaPath string, src []byte) error {
+	abs, err := filepath.Abs(schemaPath)
+	if err != nil {
+		return err
+	}
+	schema, err := jsonschema.Compile(abs)
+	if err != nil {
+		return fmt.Errorf("failed to compile JSON schema %s: %w", schemaPath, err)
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(src))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		// retry with the relaxed JSON (comments and trailing commas)
+		dec = json.NewDecoder(bytes.NewReader(relaxJSON(src)))
+		dec.UseNumber()
+		if err := dec.Decode(&v); err != nil {
+			return fmt.Errorf("failed to decode JSON: %w", err)
+		}
+	}
+	err = schema.Validate(v)
+	if err == nil {
+		return nil
+	}
+	var ve *jsonschema.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+	violations := schemaViolations(ve, nil)
+	return fmt.Errorf("does not conform to JSON schema %s:\n%s", schemaPath, strings.Join(violations, "\n"))
+}
+
+// schemaViolations flattens the validation error into the leaf violations.
+func schemaViolations(ve *jsonschema.ValidationError, violations []string) []string {
+	if len(ve.Causes) == 0 {
+		path := ve.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		return append(violations, fmt.Sprintf("  %s: %s", path, ve.Message))
+	}
+	for _, c := range ve.Causes {
+		violations = schemaViolations(c, violations)
+	}
+	return violations
+}
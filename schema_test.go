@@ -0,0 +1,32 @@
+package ecspresso_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestValidateJSONSchema(t *testing.T) {
+	schema := "tests/overrides-schema.json"
+	valid := []string{
+		`{"containerOverrides":[{"name":"app","command":["echo","hello"]}]}`,
+		`{"containerOverrides":[{"name":"app",}]} // relaxed JSON`,
+	}
+	for _, src := range valid {
+		if err := ecspresso.ValidateJSONSchema(schema, []byte(src)); err != nil {
+			t.Errorf("%s: unexpected error %s", src, err)
+		}
+	}
+
+	src := `{"containerOverrides":[{"name":"web","command":[1]}]}`
+	err := ecspresso.ValidateJSONSchema(schema, []byte(src))
+	if err == nil {
+		t.Fatalf("%s: expected error, got nil", src)
+	}
+	for _, path := range []string{"/containerOverrides/0/name", "/containerOverrides/0/command/0"} {
+		if !strings.Contains(err.Error(), path) {
+			t.Errorf("error should contain the violating path %s: %s", path, err)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// maxSemverRevisions limits the number of the latest revisions to read the version tags.
+var maxSemverRevisions = 100
+
+// semverRevision represents a revision of the task definition with the semantic version in the tag.
+type semverRevision struct {
+	arn     string
+	version *semver.Version
+}
+
+// selectSemverRevision returns the revision of the highest version which satisfies the constraint.
+func selectSemverRevision(revs []semverRevision, c *semver.Constraints) (semverRevision, bool) {
+	sorted := append([]semverRevision{}, revs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].version.GreaterThan(sorted[j].version)
+	})
+	for _, r := range sorted {
+		if c.Check(r.version) {
+			return r, true
+		}
+	}
+	return semverRevision{}, false
+}
+
+// findTaskDefinitionArnBySemver finds the revision of the highest semantic version in the tag which satisfies the constraint.
+func (d *App) findTaskDefinitionArnBySemver(ctx context.Context, family, constraint, tagKey string) (string, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid semver constraint %q: %w", constraint, err)
+	}
+	var arns []string
+	p := ecs.NewListTaskDefinitionsPaginator(d.ecs, &ecs.ListTaskDefinitionsInput{
+		FamilyPrefix: aws.String(family),
+		Sort:         types.SortOrderDesc,
+	})
+	for p.HasMorePages() && len(arns) < maxSemverRevisions {
+		out, err := p.NextPage(ctx)
+		if err != nil {
+			return "", fmt.Errorf("failed to list task definitions family %s: %w", family, err)
+		}
+		arns = append(arns, out.TaskDefinitionArns...)
+	}
+	if len(arns) > maxSemverRevisions {
+		d.Log("[WARNING] only the latest %d revisions of family %s are searched by semver", maxSemverRevisions, family)
+		arns = arns[:maxSemverRevisions]
+	}
+
+	var revs []semverRevision
+	for _, arn := range arns {
+		out, err := d.ecs.DescribeTaskDefinition(ctx, &ecs.DescribeTaskDefinitionInput{
+			TaskDefinition: aws.String(arn),
+			Include:        []types.TaskDefinitionField{types.TaskDefinitionFieldTags},
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to describe task definition %s: %w", arnToName(arn), err)
+		}
+		for _, t := range out.Tags {
+			if aws.ToString(t.Key) != tagKey {
+				continue
+			}
+			v, err := semver.NewVersion(aws.ToString(t.Value))
+			if err != nil {
+				d.Log("[DEBUG] %s=%s of %s is not a semantic version: %s", tagKey, aws.ToString(t.Value), arnToName(arn), err)
+				break
+			}
+			revs = append(revs, semverRevision{arn: arn, version: v})
+			break
+		}
+	}
+	r, ok := selectSemverRevision(revs, c)
+	if !ok {
+		found := make([]string, 0, len(revs))
+		for _, r := range revs {
+			found = append(found, r.version.Original())
+		}
+		if len(found) == 0 {
+			return "", ErrNotFound(fmt.Sprintf("no revisions of family %s have a semantic version in the tag %s", family, tagKey))
+		}
+		return "", ErrNotFound(fmt.Sprintf("no revisions of family %s satisfy %q. versions found: %s", family, constraint, strings.Join(found, ", ")))
+	}
+	d.Log("Use the task definition %s of version %s (%s)", arnToName(r.arn), r.version.Original(), constraint)
+	return r.arn, nil
+}
@@ -0,0 +1,38 @@
+package ecspresso_test
+
+import (
+	"testing"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestSelectSemverRevision(t *testing.T) {
+	versions := map[string]string{
+		"app:1": "v1.2.3",
+		"app:2": "v1.10.0", // greater than v1.9.0 in semver, not in lexical order
+		"app:3": "v1.9.0",
+		"app:4": "v2.0.0",
+		"app:5": "v2.1.0-rc.1",
+	}
+	for _, c := range []struct {
+		constraint string
+		expected   string
+	}{
+		{constraint: ">=1.2.0 <2.0.0", expected: "app:2"},
+		{constraint: "~1.9", expected: "app:3"},
+		{constraint: "1.2.3", expected: "app:1"},
+		{constraint: ">=2.0.0", expected: "app:4"}, // pre-releases are excluded
+		{constraint: ">=2.1.0-0", expected: "app:5"},
+		{constraint: ">=3.0.0", expected: ""},
+	} {
+		t.Run(c.constraint, func(t *testing.T) {
+			arn, err := ecspresso.SelectSemverRevision(versions, c.constraint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if arn != c.expected {
+				t.Errorf("expected %q, got %q", c.expected, arn)
+			}
+		})
+	}
+}
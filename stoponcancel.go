@@ -0,0 +1,38 @@
+package ecspresso
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// stopOnCancelTimeout is the timeout for stopping the tasks after ecspresso is interrupted.
+var stopOnCancelTimeout = 30 * time.Second
+
+// stopTasksOnCancel stops the tasks when ctx is cancelled by a signal (--stop-on-cancel).
+// The tasks are left running on the timeout of the config, as well as without the option.
+// Stopping is best-effort, and the failures are only logged.
+func (d *App) stopTasksOnCancel(ctx context.Context, tasks []types.Task) {
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		return
+	}
+	stopCtx, cancel := context.WithTimeout(context.Background(), stopOnCancelTimeout)
+	defer cancel()
+	for _, task := range tasks {
+		id := arnToName(aws.ToString(task.TaskArn))
+		d.Log("Stopping the task %s because ecspresso is interrupted", id)
+		if _, err := d.ecs.StopTask(stopCtx, &ecs.StopTaskInput{
+			Cluster: aws.String(d.Cluster),
+			Task:    task.TaskArn,
+			Reason:  aws.String("stopped by ecspresso: interrupted while waiting for the task"),
+		}); err != nil {
+			d.Log("[WARNING] failed to stop the task %s: %s", id, err)
+			continue
+		}
+		d.Log("Task %s is stopping", id)
+	}
+}
@@ -0,0 +1,64 @@
+package ecspresso
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/goccy/go-yaml"
+)
+
+const (
+	maxTagKeyLength   = 128
+	maxTagValueLength = 256
+)
+
+// tagsForRun returns the tags of --tags-file merged with --tags. --tags take precedence.
+func (d *App) tagsForRun(opt *RunOption) ([]types.Tag, error) {
+	var fileTags []types.Tag
+	if path := opt.TagsFile; path != "" {
+		b, err := d.readDefinitionFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tags-file %s: %w", path, err)
+		}
+		m := map[string]string{}
+		if err := yaml.Unmarshal(b, &m); err != nil {
+			return nil, fmt.Errorf("failed to parse tags-file %s. a map of the key to the value is required: %w", path, err)
+		}
+		fileTags = mapToTags(m)
+	}
+	tags, err := parseTags(opt.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("invalid tags: %w", err)
+	}
+	tags = mergeTags(fileTags, tags)
+	if err := validateTags(tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// validateTags validates the tags against the constraints of ECS not to fail in RunTask.
+func validateTags(tags []types.Tag) error {
+	var errs []string
+	for _, t := range tags {
+		k, v := aws.ToString(t.Key), aws.ToString(t.Value)
+		switch {
+		case k == "":
+			errs = append(errs, "tag key is empty")
+		case utf8.RuneCountInString(k) > maxTagKeyLength:
+			errs = append(errs, fmt.Sprintf("tag key %s is longer than %d characters", k, maxTagKeyLength))
+		case strings.HasPrefix(strings.ToLower(k), "aws:"):
+			errs = append(errs, fmt.Sprintf("tag key %s has the reserved prefix aws:", k))
+		}
+		if utf8.RuneCountInString(v) > maxTagValueLength {
+			errs = append(errs, fmt.Sprintf("value of tag %s is longer than %d characters", k, maxTagValueLength))
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid tags: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package ecspresso
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2"
+	elbv2Types "github.com/aws/aws-sdk-go-v2/service/elasticloadbalancingv2/types"
+)
+
+var targetHealthPollInterval = 5 * time.Second
+
+// waitTaskTargetHealthy waits until the task is registered and healthy in the target group.
+func (d *App) waitTaskTargetHealthy(ctx context.Context, task *types.Task, tgArn string, timeout time.Duration) error {
+	running := RunOption{WaitUntil: "running"}
+	if err := d.waitTask(ctx, task, running); err != nil {
+		return err
+	}
+	ip, err := d.taskPrivateIPv4Address(ctx, task)
+	if err != nil {
+		return err
+	}
+	d.Log("Waiting for the task %s (%s) to be healthy in target group %s", arnToName(*task.TaskArn), ip, arnToName(tgArn))
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(targetHealthPollInterval)
+	defer ticker.Stop()
+	var lastState elbv2Types.TargetHealthStateEnum
+	for {
+		out, err := d.elbv2.DescribeTargetHealth(ctx, &elasticloadbalancingv2.DescribeTargetHealthInput{
+			TargetGroupArn: aws.String(tgArn),
+			Targets: []elbv2Types.TargetDescription{
+				{Id: aws.String(ip)},
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("failed to describe target health: %w", err)
+		}
+		for _, desc := range out.TargetHealthDescriptions {
+			th := desc.TargetHealth
+			if th == nil {
+				continue
+			}
+			if th.State != lastState {
+				d.Log("Target %s is %s %s", ip, th.State, aws.ToString(th.Description))
+				lastState = th.State
+			}
+			if th.State == elbv2Types.TargetHealthStateEnumHealthy {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("target %s is not healthy in target group %s within %s. last state: %s", ip, arnToName(tgArn), timeout, lastState)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *App) taskPrivateIPv4Address(ctx context.Context, task *types.Task) (string, error) {
+	out, err := d.ecs.DescribeTasks(ctx, d.DescribeTasksInput(task))
+	if err != nil {
+		return "", fmt.Errorf("failed to describe tasks: %w", err)
+	}
+	if len(out.Tasks) == 0 {
+		return "", fmt.Errorf("task %s is not found", arnToName(*task.TaskArn))
+	}
+	for _, a := range out.Tasks[0].Attachments {
+		if aws.ToString(a.Type) != "ElasticNetworkInterface" {
+			continue
+		}
+		for _, kv := range a.Details {
+			if aws.ToString(kv.Name) == "privateIPv4Address" {
+				return aws.ToString(kv.Value), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("private IPv4 address of task %s is not found. only awsvpc network mode is supported", arnToName(*task.TaskArn))
+}
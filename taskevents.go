@@ -0,0 +1,109 @@
+package ecspresso
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// taskEvent represents a lifecycle event of the task derived from the fields of DescribeTasks.
+type taskEvent struct {
+	key  string
+	name string
+	at   time.Time
+}
+
+// taskLifecycleEvents returns the lifecycle events which have occurred in the order of time.
+func taskLifecycleEvents(t types.Task) []taskEvent {
+	var events []taskEvent
+	add := func(key, name string, at *time.Time) {
+		if at != nil {
+			events = append(events, taskEvent{key: key, name: name, at: *at})
+		}
+	}
+	add("created", "task created", t.CreatedAt)
+	add("pull-started", "image pull started", t.PullStartedAt)
+	add("pull-stopped", "image pull stopped", t.PullStoppedAt)
+	add("connectivity", "task connected", t.ConnectivityAt)
+	add("started", "task started", t.StartedAt)
+	add("stopping", "task stopping", t.StoppingAt)
+	add("execution-stopped", "execution stopped", t.ExecutionStoppedAt)
+	add("stopped", "task stopped", t.StoppedAt)
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].at.Before(events[j].at)
+	})
+	return events
+}
+
+// taskEventReporter logs each lifecycle event of the task once.
+type taskEventReporter struct {
+	mu      sync.Mutex
+	seen    map[string]bool
+	created time.Time
+}
+
+func newTaskEventReporter() *taskEventReporter {
+	return &taskEventReporter{seen: map[string]bool{}}
+}
+
+func (r *taskEventReporter) report(d *App, t types.Task) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.created.IsZero() && t.CreatedAt != nil {
+		r.created = *t.CreatedAt
+	}
+	for _, ev := range taskLifecycleEvents(t) {
+		if r.seen[ev.key] {
+			continue
+		}
+		r.seen[ev.key] = true
+		if r.created.IsZero() {
+			d.Log("Task event: %s at %s", ev.name, ev.at.In(time.Local).Format(time.RFC3339))
+		} else {
+			d.Log("Task event: %s at %s (+%s)", ev.name, ev.at.In(time.Local).Format(time.RFC3339), ev.at.Sub(r.created).Round(time.Second))
+		}
+	}
+	for _, c := range t.Containers {
+		key := "container:" + aws.ToString(c.Name) + ":" + aws.ToString(c.LastStatus)
+		if r.seen[key] {
+			continue
+		}
+		r.seen[key] = true
+		d.Log("Task event: container %s is %s", aws.ToString(c.Name), aws.ToString(c.LastStatus))
+	}
+}
+
+// describeTaskEvents describes the task and reports the events.
+func (d *App) describeTaskEvents(ctx context.Context, task *types.Task, r *taskEventReporter) {
+	out, err := d.ecs.DescribeTasks(ctx, d.DescribeTasksInput(task))
+	if err != nil {
+		if ctx.Err() == nil {
+			d.Log("[DEBUG] failed to describe task for events: %s", err)
+		}
+		return
+	}
+	for _, t := range out.Tasks {
+		r.report(d, t)
+	}
+}
+
+// watchTaskEvents reports the events of the task at interval until ctx is done.
+func (d *App) watchTaskEvents(ctx context.Context, task *types.Task, interval time.Duration, r *taskEventReporter) {
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		d.describeTaskEvents(ctx, task, r)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
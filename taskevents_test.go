@@ -0,0 +1,28 @@
+package ecspresso_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestTaskLifecycleEvents(t *testing.T) {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	task := types.Task{
+		CreatedAt:     aws.Time(created),
+		StartedAt:     aws.Time(created.Add(40 * time.Second)),
+		PullStoppedAt: aws.Time(created.Add(30 * time.Second)),
+		PullStartedAt: aws.Time(created.Add(10 * time.Second)),
+	}
+	expected := []string{"task created", "image pull started", "image pull stopped", "task started"}
+	if diff := cmp.Diff(expected, ecspresso.TaskLifecycleEventNames(task)); diff != "" {
+		t.Errorf("unexpected events %s", diff)
+	}
+	if names := ecspresso.TaskLifecycleEventNames(types.Task{}); len(names) != 0 {
+		t.Errorf("no events expected, got %v", names)
+	}
+}
@@ -2,21 +2,32 @@ package ecspresso
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/ecs"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
 	"github.com/fujiwara/ecsta"
+	"github.com/olekukonko/tablewriter"
+	"github.com/samber/lo"
 )
 
 type TasksOption struct {
-	ID     string `help:"task ID" default:""`
-	Output string `help:"output format" enum:"table,json,tsv" default:"table"`
-	Find   bool   `help:"find a task from tasks list and dump it as JSON" default:"false"`
-	Stop   bool   `help:"stop the task" default:"false"`
-	Force  bool   `help:"stop the task without confirmation" default:"false"`
-	Trace  bool   `help:"trace the task" default:"false"`
+	ID        string `help:"task ID" default:""`
+	Output    string `help:"output format" enum:"table,json,tsv" default:"table"`
+	Find      bool   `help:"find a task from tasks list and dump it as JSON" default:"false"`
+	Stop      bool   `help:"stop the task" default:"false"`
+	Force     bool   `help:"stop the task without confirmation" default:"false"`
+	Trace     bool   `help:"trace the task" default:"false"`
+	StartedBy string `help:"list the tasks of the family started by the value (e.g. ecspresso-run for the tasks run by ecspresso)" default:""`
+	Running   bool   `help:"list the running tasks of the family only" default:"false"`
+	Stopped   bool   `help:"list the stopped tasks of the family only" default:"false"`
 }
 
 func (o TasksOption) taskID() string {
@@ -42,6 +53,14 @@ func (d *App) Tasks(ctx context.Context, opt TasksOption) error {
 		service = &d.config.Service
 	}
 
+	if opt.StartedBy != "" || opt.Running || opt.Stopped {
+		tasks, err := d.listFamilyTasks(ctx, family, opt)
+		if err != nil {
+			return err
+		}
+		return tasks.output(os.Stdout, opt.Output)
+	}
+
 	if opt.Find {
 		return ecstaApp.RunDescribe(ctx, &ecsta.DescribeOption{
 			ID:      opt.taskID(),
@@ -129,3 +148,111 @@ func (d *App) listTasks(ctx context.Context) ([]types.Task, error) {
 	}
 	return tasks, nil
 }
+
+// familyTask is a row of the tasks listed by --started-by, --running or --stopped.
+type familyTask struct {
+	TaskArn       string `json:"task_arn"`
+	LastStatus    string `json:"last_status"`
+	StartedBy     string `json:"started_by"`
+	StartedAt     string `json:"started_at"`
+	StoppedReason string `json:"stopped_reason"`
+}
+
+func (t familyTask) Cols() []string {
+	return []string{t.TaskArn, t.LastStatus, t.StartedBy, t.StartedAt, t.StoppedReason}
+}
+
+type familyTasks []familyTask
+
+func (ts familyTasks) Header() []string {
+	return []string{"Task ARN", "Last Status", "Started By", "Started At", "Stopped Reason"}
+}
+
+func (ts familyTasks) output(w io.Writer, format string) error {
+	switch format {
+	case "json":
+		// an array, not to be concatenated objects, for jq and JSON decoders
+		b, err := json.MarshalIndent(ts, "", "  ")
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(append(b, '\n')); err != nil {
+			return err
+		}
+	case "tsv":
+		for _, t := range ts {
+			if _, err := fmt.Fprintln(w, strings.Join(t.Cols(), "\t")); err != nil {
+				return err
+			}
+		}
+	default:
+		t := tablewriter.NewWriter(w)
+		t.SetHeader(ts.Header())
+		t.SetBorders(tablewriter.Border{Left: true, Top: false, Right: true, Bottom: false})
+		for _, task := range ts {
+			t.Append(task.Cols())
+		}
+		t.Render()
+	}
+	return nil
+}
+
+// listFamilyTasks lists the tasks of the family filtered by startedBy and the status, the latest first.
+// Unlike the list of ecsta, the tasks not in the service (e.g. run by ecspresso) are also listed.
+func (d *App) listFamilyTasks(ctx context.Context, family string, opt TasksOption) (familyTasks, error) {
+	statuses := []types.DesiredStatus{types.DesiredStatusRunning, types.DesiredStatusStopped}
+	if opt.Running && !opt.Stopped {
+		statuses = []types.DesiredStatus{types.DesiredStatusRunning}
+	} else if opt.Stopped && !opt.Running {
+		statuses = []types.DesiredStatus{types.DesiredStatusStopped}
+	}
+	var tasks []types.Task
+	for _, status := range statuses {
+		p := ecs.NewListTasksPaginator(d.ecs, &ecs.ListTasksInput{
+			Cluster:       aws.String(d.Cluster),
+			Family:        aws.String(family),
+			DesiredStatus: status,
+		})
+		for p.HasMorePages() {
+			out, err := p.NextPage(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to list tasks: %w", err)
+			}
+			if len(out.TaskArns) == 0 {
+				continue
+			}
+			dt, err := d.ecs.DescribeTasks(ctx, &ecs.DescribeTasksInput{
+				Cluster: aws.String(d.Cluster),
+				Tasks:   out.TaskArns,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to describe tasks: %w", err)
+			}
+			tasks = append(tasks, dt.Tasks...)
+		}
+	}
+	if opt.StartedBy != "" {
+		// ListTasks API does not accept startedBy with the other filters
+		tasks = lo.Filter(tasks, func(t types.Task, _ int) bool {
+			return aws.ToString(t.StartedBy) == opt.StartedBy
+		})
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		return aws.ToTime(tasks[i].CreatedAt).After(aws.ToTime(tasks[j].CreatedAt))
+	})
+	rows := make(familyTasks, 0, len(tasks))
+	for _, t := range tasks {
+		var startedAt string
+		if t.StartedAt != nil {
+			startedAt = t.StartedAt.Local().Format(time.RFC3339)
+		}
+		rows = append(rows, familyTask{
+			TaskArn:       aws.ToString(t.TaskArn),
+			LastStatus:    aws.ToString(t.LastStatus),
+			StartedBy:     aws.ToString(t.StartedBy),
+			StartedAt:     startedAt,
+			StoppedReason: aws.ToString(t.StoppedReason),
+		})
+	}
+	return rows, nil
+}
@@ -0,0 +1,45 @@
+package ecspresso_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestListFamilyTasks(t *testing.T) {
+	ctx := context.TODO()
+	app := newRunTestApp(t)
+	out, err := app.ListFamilyTasks(ctx, "katsubushi", ecspresso.TasksOption{
+		StartedBy: "ecspresso-run",
+		Stopped:   true,
+		Output:    "tsv",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	// 0001 is started by batch in the mock
+	if len(lines) != 2 {
+		t.Fatalf("unexpected output: %s", out)
+	}
+	for i, id := range []string{"0002", "0003"} {
+		if !strings.HasPrefix(lines[i], "arn:aws:ecs:ap-northeast-1:123456789012:task/default/"+id+"\tSTOPPED\t") {
+			t.Errorf("unexpected line %s", lines[i])
+		}
+	}
+
+	out, err = app.ListFamilyTasks(ctx, "katsubushi", ecspresso.TasksOption{Stopped: true, Output: "json"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]string
+	if err := json.Unmarshal([]byte(out), &rows); err != nil {
+		t.Fatalf("output must be a JSON array: %s %s", err, out)
+	}
+	if len(rows) != 3 || rows[0]["task_arn"] != "arn:aws:ecs:ap-northeast-1:123456789012:task/default/0001" {
+		t.Errorf("unexpected output: %s", out)
+	}
+}
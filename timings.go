@@ -0,0 +1,68 @@
+package ecspresso
+
+import (
+	"time"
+)
+
+const (
+	phaseRegister      = "register"
+	phaseDescribe      = "describe"
+	phaseRunSubmit     = "run-submit"
+	phaseWaitRunning   = "wait-to-running"
+	phaseWaitStopped   = "wait-to-stopped"
+	phaseWait          = "wait"
+	timingsPrecision   = time.Millisecond
+	timingsPhaseFormat = "  %-16s %s"
+)
+
+// RunPhaseTiming represents the time spent in a phase of the run.
+type RunPhaseTiming struct {
+	Phase    string        `json:"phase"`
+	Duration time.Duration `json:"-"`
+	Seconds  float64       `json:"seconds"`
+}
+
+// runTimings records the time spent in each phase of the run.
+type runTimings struct {
+	phases []RunPhaseTiming
+}
+
+// add records the duration since start for the phase. The durations of the same phase are summed up.
+func (t *runTimings) add(phase string, start time.Time) {
+	t.addDuration(phase, time.Since(start))
+}
+
+func (t *runTimings) addDuration(phase string, d time.Duration) {
+	if d < 0 {
+		d = 0
+	}
+	for i := range t.phases {
+		if t.phases[i].Phase == phase {
+			t.phases[i].Duration += d
+			t.phases[i].Seconds = t.phases[i].Duration.Seconds()
+			return
+		}
+	}
+	t.phases = append(t.phases, RunPhaseTiming{Phase: phase, Duration: d, Seconds: d.Seconds()})
+}
+
+// addWait records the wait phase since submitted until stopped.
+// The wait is split into wait-to-running and wait-to-stopped when the task has started.
+func (t *runTimings) addWait(submitted time.Time, taskStartedAt *time.Time) {
+	if taskStartedAt == nil || taskStartedAt.Before(submitted) {
+		t.add(phaseWait, submitted)
+		return
+	}
+	t.addDuration(phaseWaitRunning, taskStartedAt.Sub(submitted))
+	t.add(phaseWaitStopped, *taskStartedAt)
+}
+
+func (d *App) printTimings(t *runTimings) {
+	var total time.Duration
+	d.Log("Timings:")
+	for _, p := range t.phases {
+		d.Log(timingsPhaseFormat, p.Phase, p.Duration.Round(timingsPrecision))
+		total += p.Duration
+	}
+	d.Log(timingsPhaseFormat, "total", total.Round(timingsPrecision))
+}
@@ -0,0 +1,33 @@
+package ecspresso_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kayac/ecspresso/v2"
+)
+
+func TestRunTimings(t *testing.T) {
+	submitted := time.Now().Add(-time.Minute)
+	started := submitted.Add(20 * time.Second)
+
+	phases := ecspresso.RunTimingsForTest(submitted, &started)
+	if len(phases) != 3 {
+		t.Fatalf("unexpected phases %#v", phases)
+	}
+	if p := phases[0]; p.Phase != "register" || p.Duration != 3*time.Second || p.Seconds != 3 {
+		t.Errorf("unexpected register phase %#v", p)
+	}
+	if p := phases[1]; p.Phase != "wait-to-running" || p.Duration != 20*time.Second {
+		t.Errorf("unexpected wait-to-running phase %#v", p)
+	}
+	if p := phases[2]; p.Phase != "wait-to-stopped" || p.Duration < 40*time.Second {
+		t.Errorf("unexpected wait-to-stopped phase %#v", p)
+	}
+
+	// not started
+	phases = ecspresso.RunTimingsForTest(submitted, nil)
+	if len(phases) != 2 || phases[1].Phase != "wait" || phases[1].Duration < time.Minute {
+		t.Errorf("unexpected phases %#v", phases)
+	}
+}
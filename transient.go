@@ -0,0 +1,336 @@
+package ecspresso
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/samber/lo"
+)
+
+const debugSidecarName = "ecspresso-debug"
+
+// taskDefinitionModifier modifies a task definition for a single run.
+type taskDefinitionModifier func(td *TaskDefinitionInput) error
+
+// transientModifiers returns modifiers which require a transient task definition revision.
+func (d *App) transientModifiers(opt RunOption) ([]taskDefinitionModifier, error) {
+	var mods []taskDefinitionModifier
+	if image := aws.ToString(opt.DebugSidecar); image != "" {
+		mods = append(mods, addDebugSidecar(image))
+	}
+	for _, s := range opt.Ulimit {
+		u, err := parseUlimit(s)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, d.setUlimit(u, opt.WatchContainer))
+	}
+	for _, s := range opt.DockerLabel {
+		l, err := parseDockerLabel(s)
+		if err != nil {
+			return nil, err
+		}
+		mods = append(mods, d.setDockerLabel(l, opt.WatchContainer))
+	}
+	if s := aws.ToString(opt.Image); s != "" {
+		container, image, err := parseImageOverride(s)
+		if err != nil {
+			return nil, err
+		}
+		if container == "" {
+			container = opt.WatchContainer
+		}
+		mods = append(mods, d.setImage(container, image))
+	}
+	if len(opt.EntryPoint) > 0 {
+		container := opt.EntryPointContainer
+		if container == "" {
+			container = opt.WatchContainer
+		}
+		mods = append(mods, d.setEntryPoint(container, opt.EntryPoint))
+	}
+	if opt.ForceAwslogs {
+		d.Log("[WARNING] --force-awslogs modifies the log configuration of the watch container in a transient task definition")
+		mods = append(mods, d.forceAwslogs(opt.WatchContainer, d.config.Region))
+	}
+	return mods, nil
+}
+
+// registerTransientTaskDefinition registers a new revision of a copy of td modified by mods.
+// It returns the ARN and the modified copy. td is not modified.
+// The returned function deregisters the transient revision.
+func (d *App) registerTransientTaskDefinition(ctx context.Context, td *TaskDefinitionInput, mods []taskDefinitionModifier) (string, *TaskDefinitionInput, func(), error) {
+	transientTd := *td
+	containers, err := copyContainerDefinitions(td.ContainerDefinitions)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	transientTd.ContainerDefinitions = containers
+	for _, mod := range mods {
+		if err := mod(&transientTd); err != nil {
+			return "", nil, nil, fmt.Errorf("failed to modify task definition: %w", err)
+		}
+	}
+	d.Log("Registering a transient task definition for this run")
+	newTd, err := d.RegisterTaskDefinition(ctx, &transientTd)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	name := taskDefinitionName(newTd)
+	d.Log("Transient task definition %s is registered", name)
+	deregister := func() {
+		// ctx may be already canceled at this point
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		d.Log("Deregistering the transient task definition %s", name)
+		if _, err := d.ecs.DeregisterTaskDefinition(ctx, &ecs.DeregisterTaskDefinitionInput{
+			TaskDefinition: newTd.TaskDefinitionArn,
+		}); err != nil {
+			d.Log("[WARNING] failed to deregister the transient task definition %s: %s", name, err)
+			return
+		}
+		d.Log("%s was deregistered successfully", name)
+	}
+	return aws.ToString(newTd.TaskDefinitionArn), &transientTd, deregister, nil
+}
+
+// copyContainerDefinitions returns a deep copy of the container definitions.
+func copyContainerDefinitions(cds []types.ContainerDefinition) ([]types.ContainerDefinition, error) {
+	b, err := json.Marshal(cds)
+	if err != nil {
+		return nil, fmt.Errorf("failed to copy container definitions: %w", err)
+	}
+	var copied []types.ContainerDefinition
+	if err := json.Unmarshal(b, &copied); err != nil {
+		return nil, fmt.Errorf("failed to copy container definitions: %w", err)
+	}
+	return copied, nil
+}
+
+func addDebugSidecar(image string) taskDefinitionModifier {
+	return func(td *TaskDefinitionInput) error {
+		for _, c := range td.ContainerDefinitions {
+			if aws.ToString(c.Name) == debugSidecarName {
+				return fmt.Errorf("container %s already exists", debugSidecarName)
+			}
+		}
+		c := types.ContainerDefinition{
+			Name:      aws.String(debugSidecarName),
+			Image:     aws.String(image),
+			Essential: aws.Bool(false), // must not affect the result of the task
+			Command:   []string{"sleep", "infinity"},
+			LinuxParameters: &types.LinuxParameters{
+				InitProcessEnabled: aws.Bool(true), // recommended for ECS Exec
+			},
+		}
+		if td.Memory == nil {
+			// EC2 tasks require container level memory without task level memory
+			c.MemoryReservation = aws.Int32(64)
+		}
+		td.ContainerDefinitions = append(td.ContainerDefinitions, c)
+		return nil
+	}
+}
+
+// forceAwslogs configures the awslogs log driver for the container to tail its logs.
+func (d *App) forceAwslogs(name string, region string) taskDefinitionModifier {
+	return func(td *TaskDefinitionInput) error {
+		i := containerIndexOf(td, name)
+		if i < 0 {
+			return fmt.Errorf("container %s is not found in task definition", name)
+		}
+		c := &td.ContainerDefinitions[i]
+		if lc := c.LogConfiguration; lc != nil && lc.LogDriver == types.LogDriverAwslogs && lc.Options["awslogs-stream-prefix"] != "" {
+			return nil // already configured
+		}
+		group := "/ecspresso/" + aws.ToString(td.Family)
+		d.Log("[INFO] container %s logs to awslogs group %s", aws.ToString(c.Name), group)
+		if td.ExecutionRoleArn == nil {
+			d.Log("[WARNING] awslogs requires the task execution role to be allowed logs:CreateLogGroup, logs:CreateLogStream and logs:PutLogEvents")
+		}
+		c.LogConfiguration = &types.LogConfiguration{
+			LogDriver: types.LogDriverAwslogs,
+			Options: map[string]string{
+				"awslogs-group":         group,
+				"awslogs-region":        region,
+				"awslogs-stream-prefix": "ecspresso",
+				"awslogs-create-group":  "true",
+			},
+		}
+		return nil
+	}
+}
+
+// containerIndexOf returns the index of the container named name in td.
+// An empty name means the first container. It returns -1 if not found.
+func containerIndexOf(td *TaskDefinitionInput, name string) int {
+	if name == "" {
+		if len(td.ContainerDefinitions) == 0 {
+			return -1
+		}
+		return 0
+	}
+	for i, c := range td.ContainerDefinitions {
+		if aws.ToString(c.Name) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// ulimitOverride represents a ulimit of a container to override in a transient task definition.
+type ulimitOverride struct {
+	container string
+	ulimit    types.Ulimit
+}
+
+// parseUlimit parses a ulimit in the format [container:]name=soft:hard.
+func parseUlimit(s string) (ulimitOverride, error) {
+	var u ulimitOverride
+	spec := s
+	if i := strings.Index(s, ":"); i >= 0 && i < strings.Index(s, "=") {
+		u.container, spec = s[:i], s[i+1:]
+	}
+	name, values, ok := strings.Cut(spec, "=")
+	if !ok {
+		return u, fmt.Errorf("invalid ulimit format. [container:]name=soft:hard is required: %s", s)
+	}
+	if !lo.Contains(types.UlimitName("").Values(), types.UlimitName(name)) {
+		return u, fmt.Errorf("invalid ulimit name: %s", name)
+	}
+	softStr, hardStr, ok := strings.Cut(values, ":")
+	if !ok {
+		return u, fmt.Errorf("invalid ulimit format. [container:]name=soft:hard is required: %s", s)
+	}
+	soft, err := strconv.ParseInt(softStr, 10, 32)
+	if err != nil || soft < 0 {
+		return u, fmt.Errorf("invalid soft limit of ulimit %s: %s", name, softStr)
+	}
+	hard, err := strconv.ParseInt(hardStr, 10, 32)
+	if err != nil || hard < 0 {
+		return u, fmt.Errorf("invalid hard limit of ulimit %s: %s", name, hardStr)
+	}
+	if soft > hard {
+		return u, fmt.Errorf("soft limit %d must not exceed hard limit %d of ulimit %s", soft, hard, name)
+	}
+	u.ulimit = types.Ulimit{
+		Name:      types.UlimitName(name),
+		SoftLimit: int32(soft),
+		HardLimit: int32(hard),
+	}
+	return u, nil
+}
+
+func (d *App) setUlimit(u ulimitOverride, watchContainer string) taskDefinitionModifier {
+	return func(td *TaskDefinitionInput) error {
+		name := u.container
+		if name == "" {
+			name = watchContainer
+		}
+		i := containerIndexOf(td, name)
+		if i < 0 {
+			return fmt.Errorf("container %s is not found in task definition", name)
+		}
+		c := &td.ContainerDefinitions[i]
+		d.Log("[INFO] set ulimit %s=%d:%d to container %s", u.ulimit.Name, u.ulimit.SoftLimit, u.ulimit.HardLimit, aws.ToString(c.Name))
+		ulimits := lo.Filter(c.Ulimits, func(ul types.Ulimit, _ int) bool {
+			return ul.Name != u.ulimit.Name
+		})
+		c.Ulimits = append(ulimits, u.ulimit)
+		return nil
+	}
+}
+
+// dockerLabelOverride represents a docker label of a container to override in a transient task definition.
+type dockerLabelOverride struct {
+	container string
+	key       string
+	value     string
+}
+
+// parseDockerLabel parses a docker label in the format [container:]key=value.
+func parseDockerLabel(s string) (dockerLabelOverride, error) {
+	var l dockerLabelOverride
+	spec := s
+	if i := strings.Index(s, ":"); i >= 0 && i < strings.Index(s, "=") {
+		l.container, spec = s[:i], s[i+1:]
+	}
+	key, value, ok := strings.Cut(spec, "=")
+	if !ok || key == "" {
+		return l, fmt.Errorf("invalid docker label format. [container:]key=value is required: %s", s)
+	}
+	l.key, l.value = key, value
+	return l, nil
+}
+
+func (d *App) setDockerLabel(l dockerLabelOverride, watchContainer string) taskDefinitionModifier {
+	return func(td *TaskDefinitionInput) error {
+		name := l.container
+		if name == "" {
+			name = watchContainer
+		}
+		i := containerIndexOf(td, name)
+		if i < 0 {
+			return fmt.Errorf("container %s is not found in task definition", name)
+		}
+		c := &td.ContainerDefinitions[i]
+		d.Log("[INFO] set docker label %s=%s to container %s", l.key, l.value, aws.ToString(c.Name))
+		// copy the labels not to modify the original map
+		labels := make(map[string]string, len(c.DockerLabels)+1)
+		for k, v := range c.DockerLabels {
+			labels[k] = v
+		}
+		labels[l.key] = l.value
+		c.DockerLabels = labels
+		return nil
+	}
+}
+
+func (d *App) setEntryPoint(name string, entryPoint []string) taskDefinitionModifier {
+	return func(td *TaskDefinitionInput) error {
+		i := containerIndexOf(td, name)
+		if i < 0 {
+			return fmt.Errorf("container %s is not found in task definition", name)
+		}
+		c := &td.ContainerDefinitions[i]
+		d.Log("[INFO] set entryPoint %q to container %s", entryPoint, aws.ToString(c.Name))
+		c.EntryPoint = append([]string{}, entryPoint...)
+		return nil
+	}
+}
+
+// imageReferenceRegex matches a docker image reference: [registry[:port]/]name[:tag][@digest].
+var imageReferenceRegex = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*(?:/[a-z0-9]+(?:(?:[._]|__|-+)[a-z0-9]+)*)*(?::[a-zA-Z0-9_][a-zA-Z0-9_.-]{0,127})?(?:@sha256:[a-f0-9]{64})?$`)
+
+// parseImageOverride parses an image in the format [container=]image.
+func parseImageOverride(s string) (string, string, error) {
+	container, image, ok := strings.Cut(s, "=")
+	if !ok {
+		container, image = "", s
+	}
+	if !imageReferenceRegex.MatchString(image) {
+		return "", "", fmt.Errorf("invalid image reference: %s", image)
+	}
+	return container, image, nil
+}
+
+func (d *App) setImage(name string, image string) taskDefinitionModifier {
+	return func(td *TaskDefinitionInput) error {
+		i := containerIndexOf(td, name)
+		if i < 0 {
+			return fmt.Errorf("container %s is not found in task definition", name)
+		}
+		c := &td.ContainerDefinitions[i]
+		d.Log("[INFO] set image %s to container %s (was %s)", image, aws.ToString(c.Name), aws.ToString(c.Image))
+		c.Image = aws.String(image)
+		return nil
+	}
+}
@@ -0,0 +1,215 @@
+package ecspresso_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+	"github.com/kayac/ecspresso/v2"
+)
+
+var testParseUlimitSuite = []struct {
+	src       string
+	container string
+	ulimit    types.Ulimit
+	ok        bool
+}{
+	{
+		src:    "nofile=1024:4096",
+		ulimit: types.Ulimit{Name: types.UlimitNameNofile, SoftLimit: 1024, HardLimit: 4096},
+		ok:     true,
+	},
+	{
+		src:       "app:nofile=65535:65535",
+		container: "app",
+		ulimit:    types.Ulimit{Name: types.UlimitNameNofile, SoftLimit: 65535, HardLimit: 65535},
+		ok:        true,
+	},
+	{src: "nofile=4096:1024"},  // soft > hard
+	{src: "nofiles=1024:4096"}, // invalid name
+	{src: "nofile=1024"},       // no hard limit
+	{src: "app:nofile"},        // no values
+}
+
+func TestParseUlimit(t *testing.T) {
+	for _, s := range testParseUlimitSuite {
+		container, ulimit, err := ecspresso.ParseUlimit(s.src)
+		if !s.ok {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", s.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", s.src, err)
+			continue
+		}
+		if container != s.container {
+			t.Errorf("%s: expected container %s, got %s", s.src, s.container, container)
+		}
+		if diff := cmp.Diff(s.ulimit, ulimit, cmp.AllowUnexported(types.Ulimit{})); diff != "" {
+			t.Errorf("%s: unexpected ulimit %s", s.src, diff)
+		}
+	}
+}
+
+func TestParseDockerLabel(t *testing.T) {
+	for _, s := range []struct {
+		src                   string
+		container, key, value string
+		ok                    bool
+	}{
+		{src: "team=backend", key: "team", value: "backend", ok: true},
+		{src: "app:com.example.route=debug", container: "app", key: "com.example.route", value: "debug", ok: true},
+		{src: "empty=", key: "empty", value: "", ok: true},
+		{src: "url=http://example.com", key: "url", value: "http://example.com", ok: true},
+		{src: "novalue"},
+		{src: "app:=value"},
+	} {
+		container, key, value, err := ecspresso.ParseDockerLabel(s.src)
+		if !s.ok {
+			if err == nil {
+				t.Errorf("%s: expected error, got nil", s.src)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %s", s.src, err)
+			continue
+		}
+		if container != s.container || key != s.key || value != s.value {
+			t.Errorf("%s: unexpected result %s %s %s", s.src, container, key, value)
+		}
+	}
+}
+
+func TestSetEntryPoint(t *testing.T) {
+	_, cliopts, _, err := ecspresso.ParseCLIv2([]string{
+		"run", "--entry-point=sh", "--entry-point=-c", "--entry-point=echo a,b", "--entry-point-container=web",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	opt := cliopts.Run
+	expected := []string{"sh", "-c", "echo a,b"}
+	if diff := cmp.Diff(expected, opt.EntryPoint); diff != "" {
+		t.Errorf("unexpected entry point %s", diff)
+	}
+
+	td := &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("app"), EntryPoint: []string{"/entrypoint.sh"}},
+			{Name: aws.String("web")},
+		},
+	}
+	if err := ecspresso.SetEntryPoint(td, opt.EntryPointContainer, opt.EntryPoint); err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(expected, td.ContainerDefinitions[1].EntryPoint); diff != "" {
+		t.Errorf("unexpected entry point %s", diff)
+	}
+	if diff := cmp.Diff([]string{"/entrypoint.sh"}, td.ContainerDefinitions[0].EntryPoint); diff != "" {
+		t.Errorf("entry point of the other container must not be modified %s", diff)
+	}
+	if err := ecspresso.SetEntryPoint(td, "notfound", opt.EntryPoint); err == nil {
+		t.Error("expected error for the container not found")
+	}
+}
+
+func TestParseImageOverride(t *testing.T) {
+	for _, c := range []struct {
+		s         string
+		container string
+		image     string
+		isErr     bool
+	}{
+		{s: "nginx", image: "nginx"},
+		{s: "nginx:1.25-alpine", image: "nginx:1.25-alpine"},
+		{s: "web=nginx:latest", container: "web", image: "nginx:latest"},
+		{s: "app=123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:v1.2.3", container: "app", image: "123456789012.dkr.ecr.ap-northeast-1.amazonaws.com/app:v1.2.3"},
+		{s: "localhost:5000/my_app/worker@sha256:" + strings.Repeat("a", 64), image: "localhost:5000/my_app/worker@sha256:" + strings.Repeat("a", 64)},
+		{s: "Nginx", isErr: true},
+		{s: "web=", isErr: true},
+		{s: "nginx:", isErr: true},
+		{s: "nginx latest", isErr: true},
+	} {
+		t.Run(c.s, func(t *testing.T) {
+			container, image, err := ecspresso.ParseImageOverride(c.s)
+			if c.isErr {
+				if err == nil {
+					t.Errorf("expected error, got %s=%s", container, image)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if container != c.container || image != c.image {
+				t.Errorf("expected %s=%s, got %s=%s", c.container, c.image, container, image)
+			}
+		})
+	}
+
+	td := &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1")},
+			{Name: aws.String("web"), Image: aws.String("nginx:latest")},
+		},
+	}
+	if err := ecspresso.SetImage(td, "app", "app:v2"); err != nil {
+		t.Fatal(err)
+	}
+	if image := aws.ToString(td.ContainerDefinitions[0].Image); image != "app:v2" {
+		t.Errorf("unexpected image %s", image)
+	}
+	if image := aws.ToString(td.ContainerDefinitions[1].Image); image != "nginx:latest" {
+		t.Errorf("image of the other container must not be modified: %s", image)
+	}
+	if err := ecspresso.SetImage(td, "notfound", "app:v2"); err == nil {
+		t.Error("expected error for the container not found")
+	}
+}
+
+func TestRegisterTransientTaskDefinition(t *testing.T) {
+	app := newRunTestApp(t)
+	td := &ecspresso.TaskDefinitionInput{
+		Family: aws.String("katsubushi"),
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("app"), Image: aws.String("app:v1"), DockerLabels: map[string]string{"foo": "bar"}},
+		},
+	}
+	opt := ecspresso.RunOption{
+		WatchContainer: "app",
+		Image:          aws.String("app:v2"),
+		DockerLabel:    []string{"foo=baz"},
+		Ulimit:         []string{"nofile=1024:4096"},
+		ForceAwslogs:   true,
+		DebugSidecar:   aws.String("busybox"),
+	}
+	arn, transientTd, err := app.RegisterTransientTaskDefinition(context.TODO(), td, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(arn, "/katsubushi:46") {
+		t.Errorf("unexpected arn %s", arn)
+	}
+	if n := len(transientTd.ContainerDefinitions); n != 2 {
+		t.Errorf("debug sidecar must be added to the transient task definition: %d containers", n)
+	}
+	c := transientTd.ContainerDefinitions[0]
+	if aws.ToString(c.Image) != "app:v2" || c.DockerLabels["foo"] != "baz" || len(c.Ulimits) != 1 || c.LogConfiguration == nil {
+		t.Errorf("unexpected transient container %#v", c)
+	}
+
+	// the original task definition must not be modified
+	expected := []types.ContainerDefinition{
+		{Name: aws.String("app"), Image: aws.String("app:v1"), DockerLabels: map[string]string{"foo": "bar"}},
+	}
+	if diff := cmp.Diff(expected, td.ContainerDefinitions, cmpopts.IgnoreUnexported(types.ContainerDefinition{})); diff != "" {
+		t.Errorf("the original task definition is modified %s", diff)
+	}
+}
@@ -2,13 +2,17 @@ package ecspresso
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
+	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/aws/arn"
 	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	goConfig "github.com/kayac/go-config"
 	"github.com/samber/lo"
 )
 
@@ -32,15 +36,84 @@ func isLongArnFormat(a string) (bool, error) {
 }
 
 func (d *App) readDefinitionFile(path string) ([]byte, error) {
+	var src []byte
 	switch filepath.Ext(path) {
 	case jsonnetExt:
-		jsonStr, err := d.loader.VM.EvaluateFile(path)
+		jsonStr, err := d.loader.evaluateFile(path)
 		if err != nil {
 			return nil, err
 		}
-		return d.loader.ReadWithEnvBytes([]byte(jsonStr))
+		src = []byte(jsonStr)
+	default:
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src = b
+	}
+	return d.renderTemplate(src)
+}
+
+// renderTemplate renders src with the template functions and describes the error.
+func (d *App) renderTemplate(src []byte) (b []byte, err error) {
+	defer func() {
+		// go-config panics when must_env fails
+		if r := recover(); r != nil {
+			perr, ok := r.(error)
+			if !ok {
+				panic(r)
+			}
+			b, err = nil, d.describeTemplateError(src, perr)
+		}
+	}()
+	b, err = d.loader.ReadWithEnvBytes(src)
+	if err != nil {
+		return nil, d.describeTemplateError(src, err)
+	}
+	return b, nil
+}
+
+var (
+	templateErrorLineRegexp = regexp.MustCompile(`template: conf:(\d+)`)
+	templateEnvRegexp       = regexp.MustCompile("\\b(must_env|env)\\s+[\"`]([^\"`]+)[\"`]")
+)
+
+// describeTemplateError annotates the error of template execution with the failed line,
+// the unset environment variables referred by env and must_env, and the available template functions.
+func (d *App) describeTemplateError(src []byte, err error) error {
+	funcs := lo.Keys(goConfig.DefaultFuncMap)
+	for _, fm := range d.config.templateFuncs {
+		funcs = append(funcs, lo.Keys(fm)...)
+	}
+	sort.Strings(funcs)
+	msg := fmt.Sprintf("available template functions: %s", strings.Join(funcs, ", "))
+
+	if unset := unsetTemplateEnvs(src); len(unset) > 0 {
+		msg = fmt.Sprintf("unset environment variables: %s\n%s", strings.Join(unset, ", "), msg)
+	}
+
+	if m := templateErrorLineRegexp.FindStringSubmatch(err.Error()); len(m) == 2 {
+		n, _ := strconv.Atoi(m[1])
+		lines := strings.Split(string(src), "\n")
+		if 0 < n && n <= len(lines) {
+			msg = fmt.Sprintf("at line %d: %s\n%s", n, strings.TrimSpace(lines[n-1]), msg)
+		}
 	}
-	return d.loader.ReadWithEnv(path)
+	return fmt.Errorf("%w\n%s", err, msg)
+}
+
+// unsetTemplateEnvs returns the environment variables referred by env and must_env in src
+// which are not set, with the function name (e.g. "IMAGE (must_env)").
+func unsetTemplateEnvs(src []byte) []string {
+	var unset []string
+	for _, m := range templateEnvRegexp.FindAllStringSubmatch(string(src), -1) {
+		fn, name := m[1], m[2]
+		if _, ok := os.LookupEnv(name); ok {
+			continue
+		}
+		unset = append(unset, fmt.Sprintf("%s (%s)", name, fn))
+	}
+	return lo.Uniq(unset)
 }
 
 func parseTags(s string) ([]types.Tag, error) {
@@ -69,6 +142,36 @@ func parseTags(s string) ([]types.Tag, error) {
 	return tags, nil
 }
 
+// mapToTags converts the map to the tags sorted by the key.
+func mapToTags(m map[string]string) []types.Tag {
+	keys := lo.Keys(m)
+	sort.Strings(keys)
+	tags := make([]types.Tag, 0, len(keys))
+	for _, k := range keys {
+		tags = append(tags, types.Tag{Key: aws.String(k), Value: aws.String(m[k])})
+	}
+	return tags
+}
+
+// mergeTags merges tags by key. Tags in later layers take precedence.
+// Duplicated keys in a layer are also merged, because RunTask rejects duplicated keys.
+func mergeTags(layers ...[]types.Tag) []types.Tag {
+	merged := make([]types.Tag, 0)
+	index := make(map[string]int)
+	for _, tags := range layers {
+		for _, tag := range tags {
+			k := aws.ToString(tag.Key)
+			if i, ok := index[k]; ok {
+				merged[i] = tag
+				continue
+			}
+			index[k] = len(merged)
+			merged = append(merged, tag)
+		}
+	}
+	return merged
+}
+
 func map2str(m map[string]string) string {
 	var p []string
 	keys := lo.Keys(m)
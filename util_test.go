@@ -207,3 +207,28 @@ func TestCompareTags(t *testing.T) {
 		})
 	}
 }
+
+func TestMergeTags(t *testing.T) {
+	tdTags := []types.Tag{
+		{Key: ptr("Env"), Value: ptr("td")},
+		{Key: ptr("Team"), Value: ptr("td")},
+		{Key: ptr("Family"), Value: ptr("app")},
+	}
+	svTags := []types.Tag{
+		{Key: ptr("Env"), Value: ptr("service")},
+		{Key: ptr("Service"), Value: ptr("web")},
+	}
+	cliTags := []types.Tag{
+		{Key: ptr("Team"), Value: ptr("cli")},
+	}
+	expected := []types.Tag{
+		{Key: ptr("Env"), Value: ptr("service")},
+		{Key: ptr("Team"), Value: ptr("cli")},
+		{Key: ptr("Family"), Value: ptr("app")},
+		{Key: ptr("Service"), Value: ptr("web")},
+	}
+	got := ecspresso.MergeTags(tdTags, svTags, cliTags)
+	if diff := cmp.Diff(expected, got, cmp.AllowUnexported(types.Tag{})); diff != "" {
+		t.Errorf("unexpected merged tags (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,171 @@
+package ecspresso
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+)
+
+// fargateMemoryRange represents the range of memory (MiB) available for a Fargate cpu value.
+type fargateMemoryRange struct {
+	min, max, step int
+}
+
+// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-cpu-memory-error.html
+var fargateMemoryRanges = map[int][]fargateMemoryRange{
+	256:   {{512, 512, 1}, {1024, 2048, 1024}},
+	512:   {{1024, 4096, 1024}},
+	1024:  {{2048, 8192, 1024}},
+	2048:  {{4096, 16384, 1024}},
+	4096:  {{8192, 30720, 1024}},
+	8192:  {{16384, 61440, 4096}},
+	16384: {{32768, 122880, 8192}},
+}
+
+// isFargate reports whether the task runs on Fargate with the launch type or the capacity provider strategy.
+func isFargate(launchType types.LaunchType, strategy []types.CapacityProviderStrategyItem) bool {
+	if launchType == types.LaunchTypeFargate {
+		return true
+	}
+	for _, s := range strategy {
+		switch aws.ToString(s.CapacityProvider) {
+		case "FARGATE", "FARGATE_SPOT":
+			return true
+		}
+	}
+	return false
+}
+
+// validateTaskResources validates that cpu and memory of the task definition fit the launch type.
+func validateTaskResources(td *TaskDefinitionInput, fargate bool) error {
+	if fargate {
+		return validateFargateResources(td)
+	}
+	if td.Memory != nil {
+		return nil
+	}
+	for _, c := range td.ContainerDefinitions {
+		if aws.ToInt32(c.Memory) == 0 && aws.ToInt32(c.MemoryReservation) == 0 {
+			return fmt.Errorf("container %s requires memory or memoryReservation when the task level memory is not defined", aws.ToString(c.Name))
+		}
+	}
+	return nil
+}
+
+func validateFargateResources(td *TaskDefinitionInput) error {
+	if td.Cpu == nil || td.Memory == nil {
+		return fmt.Errorf("task level cpu and memory are required for Fargate")
+	}
+	cpu, err := strconv.Atoi(aws.ToString(toNumberCPU(*td.Cpu)))
+	if err != nil {
+		return fmt.Errorf("invalid cpu %s: %w", *td.Cpu, err)
+	}
+	memory, err := strconv.Atoi(aws.ToString(toNumberMemory(*td.Memory)))
+	if err != nil {
+		return fmt.Errorf("invalid memory %s: %w", *td.Memory, err)
+	}
+	ranges, ok := fargateMemoryRanges[cpu]
+	if !ok {
+		return fmt.Errorf("cpu %d is not supported by Fargate", cpu)
+	}
+	for _, r := range ranges {
+		if r.min <= memory && memory <= r.max && (memory-r.min)%r.step == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("memory %d is not supported with cpu %d by Fargate", memory, cpu)
+}
+
+// validateResourceOverridesForRun validates the cpu and memory of td overridden by --cpu and --memory
+// are one of the combinations allowed by Fargate.
+func (d *App) validateResourceOverridesForRun(td *TaskDefinitionInput, ov *types.TaskOverride, opt *RunOption) error {
+	sv, err := d.LoadServiceDefinition(d.config.ServiceDefinitionPath)
+	if err != nil {
+		return err
+	}
+	launchType, strategy, err := launchSettingsForRun(sv, opt)
+	if err != nil {
+		return err
+	}
+	if !isFargate(launchType, strategy) {
+		return nil
+	}
+	overridden := *td
+	if ov.Cpu != nil {
+		overridden.Cpu = ov.Cpu
+	}
+	if ov.Memory != nil {
+		overridden.Memory = ov.Memory
+	}
+	if err := validateFargateResources(&overridden); err != nil {
+		return fmt.Errorf("invalid --cpu or --memory: %w. valid combinations are %s", err, fargateResourceCombinations())
+	}
+	return nil
+}
+
+// fargateResourceCombinations returns the combinations of cpu and memory supported by Fargate.
+func fargateResourceCombinations() string {
+	cpus := make([]int, 0, len(fargateMemoryRanges))
+	for cpu := range fargateMemoryRanges {
+		cpus = append(cpus, cpu)
+	}
+	sort.Ints(cpus)
+	combos := make([]string, 0, len(cpus))
+	for _, cpu := range cpus {
+		var memories []string
+		for _, r := range fargateMemoryRanges[cpu] {
+			if r.min == r.max {
+				memories = append(memories, strconv.Itoa(r.min))
+			} else {
+				memories = append(memories, fmt.Sprintf("%d-%d in %d increments", r.min, r.max, r.step))
+			}
+		}
+		combos = append(combos, fmt.Sprintf("cpu %d: memory %s", cpu, strings.Join(memories, ", ")))
+	}
+	return strings.Join(combos, "; ")
+}
+
+// validateCluster validates that the cluster is able to run tasks.
+// requireInstances means the task requires registered container instances (EC2 launch type).
+func validateCluster(c types.Cluster, requireInstances bool) error {
+	name := aws.ToString(c.ClusterName)
+	if status := aws.ToString(c.Status); status != "ACTIVE" {
+		return fmt.Errorf("cluster %s is %s", name, status)
+	}
+	if requireInstances && c.RegisteredContainerInstancesCount == 0 {
+		return fmt.Errorf("cluster %s has no registered container instances for EC2 launch type", name)
+	}
+	return nil
+}
+
+// validateLogging validates that the containers have a log configuration.
+// It validates the watch container, or all the essential containers when essential is true.
+func validateLogging(td *TaskDefinitionInput, watchContainer string, essential bool) error {
+	var noncompliant []string
+	found := false
+	for i, c := range td.ContainerDefinitions {
+		name := aws.ToString(c.Name)
+		if essential {
+			if c.Essential != nil && !*c.Essential { // essential is true by default
+				continue
+			}
+		} else if name != watchContainer && !(watchContainer == "" && i == 0) {
+			continue
+		}
+		found = true
+		if c.LogConfiguration == nil || c.LogConfiguration.LogDriver == "" {
+			noncompliant = append(noncompliant, name)
+		}
+	}
+	if !essential && !found {
+		return ErrNotFound(fmt.Sprintf("watch container %s is not found in the task definition", watchContainer))
+	}
+	if len(noncompliant) > 0 {
+		return fmt.Errorf("log configuration is required for containers: %s", strings.Join(noncompliant, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package ecspresso_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ecs/types"
+	"github.com/kayac/ecspresso/v2"
+)
+
+var testValidateTaskResourcesSuite = []struct {
+	name    string
+	td      ecspresso.TaskDefinitionInput
+	fargate bool
+	ok      bool
+}{
+	{
+		name:    "fargate valid",
+		td:      ecspresso.TaskDefinitionInput{Cpu: aws.String("256"), Memory: aws.String("512")},
+		fargate: true,
+		ok:      true,
+	},
+	{
+		name:    "fargate valid with units",
+		td:      ecspresso.TaskDefinitionInput{Cpu: aws.String("1 vCPU"), Memory: aws.String("3 GB")},
+		fargate: true,
+		ok:      true,
+	},
+	{
+		name:    "fargate invalid pair",
+		td:      ecspresso.TaskDefinitionInput{Cpu: aws.String("256"), Memory: aws.String("4096")},
+		fargate: true,
+	},
+	{
+		name:    "fargate without memory",
+		td:      ecspresso.TaskDefinitionInput{Cpu: aws.String("256")},
+		fargate: true,
+	},
+	{
+		name: "ec2 with container memory",
+		td: ecspresso.TaskDefinitionInput{
+			ContainerDefinitions: []types.ContainerDefinition{
+				{Name: aws.String("app"), MemoryReservation: aws.Int32(128)},
+			},
+		},
+		ok: true,
+	},
+	{
+		name: "ec2 without memory",
+		td: ecspresso.TaskDefinitionInput{
+			ContainerDefinitions: []types.ContainerDefinition{
+				{Name: aws.String("app")},
+			},
+		},
+	},
+}
+
+func TestValidateTaskResources(t *testing.T) {
+	for _, s := range testValidateTaskResourcesSuite {
+		err := ecspresso.ValidateTaskResources(&s.td, s.fargate)
+		if s.ok && err != nil {
+			t.Errorf("%s: unexpected error %s", s.name, err)
+		} else if !s.ok && err == nil {
+			t.Errorf("%s: expected error, got nil", s.name)
+		}
+	}
+}
+
+func TestValidateCluster(t *testing.T) {
+	active := types.Cluster{ClusterName: aws.String("default"), Status: aws.String("ACTIVE")}
+	if err := ecspresso.ValidateCluster(active, false); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	if err := ecspresso.ValidateCluster(active, true); err == nil {
+		t.Error("expected error for no container instances, got nil")
+	}
+	active.RegisteredContainerInstancesCount = 1
+	if err := ecspresso.ValidateCluster(active, true); err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+	inactive := types.Cluster{ClusterName: aws.String("default"), Status: aws.String("INACTIVE")}
+	if err := ecspresso.ValidateCluster(inactive, false); err == nil {
+		t.Error("expected error for INACTIVE cluster, got nil")
+	}
+}
+
+func TestValidateLogging(t *testing.T) {
+	awslogs := &types.LogConfiguration{LogDriver: types.LogDriverAwslogs}
+	td := &ecspresso.TaskDefinitionInput{
+		ContainerDefinitions: []types.ContainerDefinition{
+			{Name: aws.String("app"), LogConfiguration: awslogs},
+			{Name: aws.String("proxy")},
+			{Name: aws.String("sidecar"), Essential: aws.Bool(false)},
+		},
+	}
+	for _, s := range []struct {
+		watch     string
+		essential bool
+		errMsg    string
+	}{
+		{watch: "", essential: false},
+		{watch: "app", essential: false},
+		{watch: "proxy", essential: false, errMsg: "containers: proxy"},
+		{watch: "sidecar", essential: false, errMsg: "containers: sidecar"},
+		{watch: "app", essential: true, errMsg: "containers: proxy"},
+		{watch: "0", essential: false, errMsg: "watch container 0 is not found in the task definition"},
+	} {
+		err := ecspresso.ValidateLogging(td, s.watch, s.essential)
+		if s.errMsg == "" {
+			if err != nil {
+				t.Errorf("watch:%s essential:%t unexpected error %s", s.watch, s.essential, err)
+			}
+			continue
+		}
+		if err == nil || !strings.HasSuffix(err.Error(), s.errMsg) {
+			t.Errorf("watch:%s essential:%t expected error %q, got %v", s.watch, s.essential, s.errMsg, err)
+		}
+	}
+}
+
+func TestValidateResourceOverridesForRun(t *testing.T) {
+	ctx := context.Background()
+	app, err := ecspresso.New(ctx, &ecspresso.CLIOptions{ConfigFilePath: "tests/default-tags.yml"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	td := &ecspresso.TaskDefinitionInput{Cpu: aws.String("256"), Memory: aws.String("512")}
+	for _, tc := range []struct {
+		opt   ecspresso.RunOption
+		valid bool
+	}{
+		{opt: ecspresso.RunOption{LaunchType: "FARGATE", Cpu: "1024", Memory: "2048"}, valid: true},
+		{opt: ecspresso.RunOption{LaunchType: "FARGATE", Cpu: "1024", Memory: "1024"}, valid: false},
+		{opt: ecspresso.RunOption{LaunchType: "FARGATE", Cpu: "3000", Memory: "8192"}, valid: false},
+		// the service runs on EC2
+		{opt: ecspresso.RunOption{Cpu: "3000", Memory: "1024"}, valid: true},
+	} {
+		err := app.ValidateResourceOverridesForRun(td, tc.opt)
+		if tc.valid && err != nil {
+			t.Errorf("%s cpu=%s memory=%s: unexpected error: %s", tc.opt.LaunchType, tc.opt.Cpu, tc.opt.Memory, err)
+		}
+		if !tc.valid && (err == nil || !strings.Contains(err.Error(), "cpu 1024: memory 2048-8192 in 1024 increments")) {
+			t.Errorf("%s cpu=%s memory=%s: unexpected error: %v", tc.opt.LaunchType, tc.opt.Cpu, tc.opt.Memory, err)
+		}
+	}
+}